@@ -7,5 +7,5 @@ import (
 )
 
 func main() {
-	cli.Run(os.Stdin, os.Stdout)
+	os.Exit(cli.Main(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,213 @@
+// Package analysis computes properties of decision trees, such as expected
+// values.
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// probabilityTolerance is how far chance probabilities may sum from 1.
+const probabilityTolerance = 1e-6
+
+// NodeKind classifies how a node's expected value is rolled back.
+type NodeKind int
+
+const (
+	Terminal NodeKind = iota // No outgoing edges: value is the node's payoff
+	Step                     // Single unweighted branch: passes its child's value up
+	Choice                   // Decision node: takes the best branch
+	Chance                   // Probability-weighted branches
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case Terminal:
+		return "terminal"
+	case Step:
+		return "step"
+	case Choice:
+		return "choice"
+	case Chance:
+		return "chance"
+	default:
+		return "unknown"
+	}
+}
+
+// NodeValue is the rolled-back expected value at one node.
+type NodeValue struct {
+	NodeID string
+	Kind   NodeKind
+	Value  float64
+	Best   string // for Choice nodes, the target of the optimal branch
+}
+
+// EVResult holds the expected value of every node reachable from the root.
+type EVResult struct {
+	RootID string
+	Values map[string]*NodeValue
+}
+
+// ExpectedValue rolls the tree back from its root in the style of classic
+// decision analysis. A node's own payoff (zero if unset) is added to the
+// value of what follows it. Edges carrying probabilities make a chance node
+// whose value is the probability-weighted sum of its branches; otherwise a
+// Decision node takes its best branch. Other nodes may have at most one
+// unweighted branch.
+func ExpectedValue(t *model.Tree) (*EVResult, error) {
+	if t.RootID == "" {
+		return nil, errors.New("no root set")
+	}
+	if t.GetNode(t.RootID) == nil {
+		return nil, fmt.Errorf("root node %q not found", t.RootID)
+	}
+	if errs := CheckProbabilities(t); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	res := &EVResult{RootID: t.RootID, Values: make(map[string]*NodeValue)}
+	onPath := make(map[string]bool)
+	var roll func(id string) (float64, error)
+	roll = func(id string) (float64, error) {
+		if v, ok := res.Values[id]; ok {
+			return v.Value, nil
+		}
+		if onPath[id] {
+			return 0, fmt.Errorf("cycle through node %s: expected value is undefined", id)
+		}
+		onPath[id] = true
+		defer delete(onPath, id)
+
+		n := t.GetNode(id)
+		nv := &NodeValue{NodeID: id}
+		if n.Payoff != nil {
+			nv.Value = *n.Payoff
+		}
+		children := t.Children(id)
+		switch {
+		case len(children) == 0:
+			nv.Kind = Terminal
+		case children[0].Probability != nil:
+			nv.Kind = Chance
+			for _, e := range children {
+				v, err := roll(e.ToID)
+				if err != nil {
+					return 0, err
+				}
+				nv.Value += *e.Probability * v
+			}
+		case n.Type == model.Decision:
+			nv.Kind = Choice
+			best := math.Inf(-1)
+			for _, e := range children {
+				v, err := roll(e.ToID)
+				if err != nil {
+					return 0, err
+				}
+				if v > best {
+					best = v
+					nv.Best = e.ToID
+				}
+			}
+			nv.Value += best
+		case len(children) == 1:
+			nv.Kind = Step
+			v, err := roll(children[0].ToID)
+			if err != nil {
+				return 0, err
+			}
+			nv.Value += v
+		default:
+			return 0, fmt.Errorf("node %s is a %s with %d branches but no probabilities", id, n.Type, len(children))
+		}
+		res.Values[id] = nv
+		return nv.Value, nil
+	}
+	if _, err := roll(t.RootID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CheckProbabilities reports every node whose outgoing edges mix weighted
+// and unweighted branches, or whose probabilities do not sum to 1.
+func CheckProbabilities(t *model.Tree) []error {
+	var errs []error
+	for _, id := range t.NodeIDs() {
+		children := t.Children(id)
+		weighted := 0
+		sum := 0.0
+		for _, e := range children {
+			if e.Probability != nil {
+				weighted++
+				sum += *e.Probability
+			}
+		}
+		switch {
+		case weighted == 0:
+		case weighted < len(children):
+			errs = append(errs, fmt.Errorf("node %s: %d of %d branches have no probability", id, len(children)-weighted, len(children)))
+		case math.Abs(sum-1) > probabilityTolerance:
+			errs = append(errs, fmt.Errorf("node %s: branch probabilities sum to %.6g, not 1", id, sum))
+		}
+	}
+	return errs
+}
+
+// FormatEV renders the result as an indented tree in the style of the ASCII
+// preview, with the expected value of each node and the best branch of each
+// choice marked with "*".
+func FormatEV(t *model.Tree, res *EVResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Expected value: %.2f\n", res.Values[res.RootID].Value)
+	var walk func(id, edgePart, prefix, connector, childPrefix string)
+	walk = func(id, edgePart, prefix, connector, childPrefix string) {
+		n := t.GetNode(id)
+		nv := res.Values[id]
+		line := prefix + connector + edgePart + n.Label + fmt.Sprintf("  EV=%.2f", nv.Value)
+		if nv.Kind == Choice {
+			line += "  best: " + branchName(t, id, nv.Best)
+		}
+		b.WriteString(line + "\n")
+
+		children := t.Children(id)
+		for i, e := range children {
+			conn, next := "├── ", "│   "
+			if i == len(children)-1 {
+				conn, next = "└── ", "    "
+			}
+			mark := ""
+			if nv.Kind == Choice && e.ToID == nv.Best {
+				mark = "* "
+			}
+			walk(e.ToID, mark+edgeText(e), prefix+childPrefix, conn, next)
+		}
+	}
+	walk(res.RootID, "", "", "", "")
+	return b.String()
+}
+
+func edgeText(e model.Edge) string {
+	switch {
+	case e.Probability != nil && e.Label != "":
+		return fmt.Sprintf("[%s p=%g] ", e.Label, *e.Probability)
+	case e.Probability != nil:
+		return fmt.Sprintf("[p=%g] ", *e.Probability)
+	case e.Label != "":
+		return "[" + e.Label + "] "
+	default:
+		return ""
+	}
+}
+
+func branchName(t *model.Tree, fromID, toID string) string {
+	if e := t.GetEdge(fromID, toID); e != nil && e.Label != "" {
+		return e.Label
+	}
+	return toID
+}
@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func f(v float64) *float64 { return &v }
+
+// buildLaunchTree models launching a product (a gamble) versus doing nothing.
+func buildLaunchTree() *model.Tree {
+	t := model.NewTree("launch")
+	t.RootID = "n1"
+	t.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Decision, Label: "Launch?"}
+	t.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Market response", Payoff: f(-20)}
+	t.Nodes["n3"] = &model.Node{ID: "n3", Type: model.StartEnd, Label: "Strong", Payoff: f(100)}
+	t.Nodes["n4"] = &model.Node{ID: "n4", Type: model.StartEnd, Label: "Weak", Payoff: f(-10)}
+	t.Nodes["n5"] = &model.Node{ID: "n5", Type: model.StartEnd, Label: "Status quo"}
+	t.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2", Label: "yes"},
+		{FromID: "n2", ToID: "n3", Label: "good", Probability: f(0.6)},
+		{FromID: "n2", ToID: "n4", Label: "bad", Probability: f(0.4)},
+		{FromID: "n1", ToID: "n5", Label: "no"},
+	}
+	return t
+}
+
+func TestExpectedValue(t *testing.T) {
+	res, err := ExpectedValue(buildLaunchTree())
+	if err != nil {
+		t.Fatalf("ExpectedValue: %v", err)
+	}
+	// Chance: -20 + 0.6*100 + 0.4*(-10) = 36
+	if v := res.Values["n2"]; v.Kind != Chance || math.Abs(v.Value-36) > 1e-9 {
+		t.Errorf("n2 = %+v, want chance with EV 36", v)
+	}
+	root := res.Values["n1"]
+	if root.Kind != Choice || root.Best != "n2" || math.Abs(root.Value-36) > 1e-9 {
+		t.Errorf("root = %+v, want choice of n2 with EV 36", root)
+	}
+	if v := res.Values["n5"]; v.Kind != Terminal || v.Value != 0 {
+		t.Errorf("n5 = %+v, want terminal with EV 0", v)
+	}
+}
+
+func TestExpectedValueStep(t *testing.T) {
+	tr := model.NewTree("steps")
+	tr.RootID = "n1"
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "Prepare", Payoff: f(-5)}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.StartEnd, Label: "Done", Payoff: f(15)}
+	tr.Edges = []model.Edge{{FromID: "n1", ToID: "n2"}}
+
+	res, err := ExpectedValue(tr)
+	if err != nil {
+		t.Fatalf("ExpectedValue: %v", err)
+	}
+	if v := res.Values["n1"]; v.Kind != Step || v.Value != 10 {
+		t.Errorf("n1 = %+v, want step with EV 10", v)
+	}
+}
+
+func TestExpectedValueErrors(t *testing.T) {
+	noRoot := model.NewTree("x")
+	if _, err := ExpectedValue(noRoot); err == nil {
+		t.Error("expected error without root")
+	}
+
+	badSum := buildLaunchTree()
+	badSum.GetEdge("n2", "n4").Probability = f(0.3)
+	if _, err := ExpectedValue(badSum); err == nil || !strings.Contains(err.Error(), "sum to 0.9") {
+		t.Errorf("err = %v, want probability sum error", err)
+	}
+
+	mixed := buildLaunchTree()
+	mixed.GetEdge("n2", "n4").Probability = nil
+	if _, err := ExpectedValue(mixed); err == nil || !strings.Contains(err.Error(), "have no probability") {
+		t.Errorf("err = %v, want mixed branches error", err)
+	}
+
+	action := buildLaunchTree()
+	action.Nodes["n1"].Type = model.Action
+	if _, err := ExpectedValue(action); err == nil || !strings.Contains(err.Error(), "no probabilities") {
+		t.Errorf("err = %v, want ambiguous branches error", err)
+	}
+
+	loop := buildLaunchTree()
+	loop.Graph = true
+	loop.Edges = append(loop.Edges, model.Edge{FromID: "n5", ToID: "n1"})
+	if _, err := ExpectedValue(loop); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("err = %v, want cycle error", err)
+	}
+}
+
+func TestExpectedValueSharedNode(t *testing.T) {
+	tr := model.NewTree("dag")
+	tr.Graph = true
+	tr.RootID = "n1"
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Decision, Label: "Pick"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "A", Payoff: f(1)}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "B", Payoff: f(2)}
+	tr.Nodes["n4"] = &model.Node{ID: "n4", Type: model.StartEnd, Label: "End", Payoff: f(10)}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n1", ToID: "n3"},
+		{FromID: "n2", ToID: "n4"},
+		{FromID: "n3", ToID: "n4"},
+	}
+	res, err := ExpectedValue(tr)
+	if err != nil {
+		t.Fatalf("ExpectedValue: %v", err)
+	}
+	if v := res.Values["n1"]; v.Best != "n3" || v.Value != 12 {
+		t.Errorf("n1 = %+v, want best n3 with EV 12", v)
+	}
+}
+
+func TestCheckProbabilities(t *testing.T) {
+	if errs := CheckProbabilities(buildLaunchTree()); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestFormatEV(t *testing.T) {
+	tr := buildLaunchTree()
+	res, err := ExpectedValue(tr)
+	if err != nil {
+		t.Fatalf("ExpectedValue: %v", err)
+	}
+	want := "Expected value: 36.00\n" +
+		"Launch?  EV=36.00  best: yes\n" +
+		"├── * [yes] Market response  EV=36.00\n" +
+		"│   ├── [good p=0.6] Strong  EV=100.00\n" +
+		"│   └── [bad p=0.4] Weak  EV=-10.00\n" +
+		"└── [no] Status quo  EV=0.00\n"
+	if got := FormatEV(tr, res); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
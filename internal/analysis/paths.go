@@ -0,0 +1,276 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// MaxPaths caps how many paths Paths lists. Shared nodes in graph mode
+// multiply paths, and a list longer than this is no use as a test plan.
+const MaxPaths = 10000
+
+// PathStep is one node on a path.
+type PathStep struct {
+	NodeID string `json:"node"`
+	Type   string `json:"type"`
+	Label  string `json:"label"`
+	// Edge is the label of the edge from the previous step, if any.
+	Edge string `json:"edge,omitempty"`
+}
+
+// Path is a route from the root to a leaf. In graph mode a route can
+// instead return to a node already on it; the path then stops, with LoopsTo
+// naming that node, LoopEdge the loop-back edge's label and the last
+// step the node that edge leaves.
+type Path struct {
+	Steps    []PathStep `json:"steps"`
+	LoopsTo  string     `json:"loops_to,omitempty"`
+	LoopEdge string     `json:"loop_edge,omitempty"`
+}
+
+// Depth is the number of edges on the path, counting a loop-back edge.
+func (p Path) Depth() int {
+	d := len(p.Steps) - 1
+	if p.LoopsTo != "" {
+		d++
+	}
+	return d
+}
+
+// Paths lists every path from the root to a leaf, depth first in edge
+// order, so the paths come out in the order the ASCII preview shows their
+// leaves.
+func Paths(t *model.Tree) ([]Path, error) {
+	if t.RootID == "" {
+		return nil, errors.New("no root set")
+	}
+	if t.GetNode(t.RootID) == nil {
+		return nil, fmt.Errorf("root node %q not found", t.RootID)
+	}
+	var paths []Path
+	var steps []PathStep
+	onPath := make(map[string]bool)
+	var walk func(id, edge string) error
+	walk = func(id, edge string) error {
+		n := t.GetNode(id)
+		steps = append(steps, PathStep{NodeID: id, Type: n.Type.String(), Label: n.Label, Edge: edge})
+		onPath[id] = true
+		defer func() {
+			steps = steps[:len(steps)-1]
+			delete(onPath, id)
+		}()
+
+		emit := func(p Path) error {
+			if len(paths) == MaxPaths {
+				return fmt.Errorf("more than %d paths", MaxPaths)
+			}
+			p.Steps = append([]PathStep(nil), steps...)
+			paths = append(paths, p)
+			return nil
+		}
+		children := t.Children(id)
+		if len(children) == 0 {
+			return emit(Path{})
+		}
+		for _, e := range children {
+			var err error
+			if onPath[e.ToID] {
+				err = emit(Path{LoopsTo: e.ToID, LoopEdge: e.Label})
+			} else {
+				err = walk(e.ToID, e.Label)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(t.RootID, ""); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// MaxDepth returns the depth of the deepest path.
+func MaxDepth(paths []Path) int {
+	max := 0
+	for _, p := range paths {
+		if d := p.Depth(); d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// PathFormats lists the formats WritePaths accepts.
+func PathFormats() []string {
+	return []string{"text", "csv", "json", "gherkin"}
+}
+
+// WritePaths writes the paths of t in one of PathFormats. Gherkin output
+// is a feature named after the tree.
+func WritePaths(w io.Writer, t *model.Tree, paths []Path, format string) error {
+	switch format {
+	case "text":
+		_, err := io.WriteString(w, FormatPaths(t, paths))
+		return err
+	case "csv":
+		return writePathsCSV(w, t, paths)
+	case "json":
+		return writePathsJSON(w, paths)
+	case "gherkin":
+		_, err := io.WriteString(w, formatPathsGherkin(t, paths))
+		return err
+	default:
+		return fmt.Errorf("unknown paths format %q (use %s)", format, strings.Join(PathFormats(), ", "))
+	}
+}
+
+// FormatPaths writes one numbered line per path, such as
+// "2. Start -> Auth? -[no]-> Show login", then the count and maximum depth.
+func FormatPaths(t *model.Tree, paths []Path) string {
+	var b strings.Builder
+	for i, p := range paths {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, pathText(t, p))
+	}
+	noun := "paths"
+	if len(paths) == 1 {
+		noun = "path"
+	}
+	fmt.Fprintf(&b, "%d %s, max depth %d\n", len(paths), noun, MaxDepth(paths))
+	return b.String()
+}
+
+func pathText(t *model.Tree, p Path) string {
+	var b strings.Builder
+	for i, s := range p.Steps {
+		if i > 0 {
+			b.WriteString(arrow(s.Edge))
+		}
+		b.WriteString(s.Label)
+	}
+	if p.LoopsTo != "" {
+		fmt.Fprintf(&b, "%s(back to %s)", arrow(p.LoopEdge), t.GetNode(p.LoopsTo).Label)
+	}
+	return b.String()
+}
+
+// outcome names where a path ends: its leaf, or the node it loops back to.
+func outcome(t *model.Tree, p Path) string {
+	if p.LoopsTo != "" {
+		return "back to " + t.GetNode(p.LoopsTo).Label
+	}
+	return p.Steps[len(p.Steps)-1].Label
+}
+
+// scenarioName names a path by the branches it takes, as in
+// "Auth? no, Retry? yes", or by its outcome if no branch is labeled.
+func scenarioName(t *model.Tree, p Path) string {
+	var choices []string
+	for i, s := range p.Steps[1:] {
+		if s.Edge != "" {
+			choices = append(choices, p.Steps[i].Label+" "+s.Edge)
+		}
+	}
+	if p.LoopEdge != "" {
+		choices = append(choices, p.Steps[len(p.Steps)-1].Label+" "+p.LoopEdge)
+	}
+	if len(choices) == 0 {
+		return outcome(t, p)
+	}
+	return strings.Join(choices, ", ")
+}
+
+func arrow(edge string) string {
+	if edge == "" {
+		return " -> "
+	}
+	return " -[" + edge + "]-> "
+}
+
+// writePathsCSV writes one row per path: its number, depth, outcome, the
+// route as text and the node IDs along it.
+func writePathsCSV(w io.Writer, t *model.Tree, paths []Path) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"path", "depth", "outcome", "steps", "nodes"})
+	for i, p := range paths {
+		ids := make([]string, len(p.Steps))
+		for j, s := range p.Steps {
+			ids[j] = s.NodeID
+		}
+		if p.LoopsTo != "" {
+			ids = append(ids, p.LoopsTo)
+		}
+		cw.Write([]string{strconv.Itoa(i + 1), strconv.Itoa(p.Depth()), outcome(t, p), pathText(t, p), strings.Join(ids, " ")})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writePathsJSON(w io.Writer, paths []Path) error {
+	type jsonPath struct {
+		Number int `json:"path"`
+		Depth  int `json:"depth"`
+		Path
+	}
+	out := struct {
+		Count    int        `json:"count"`
+		MaxDepth int        `json:"max_depth"`
+		Paths    []jsonPath `json:"paths"`
+	}{Count: len(paths), MaxDepth: MaxDepth(paths), Paths: []jsonPath{}}
+	for i, p := range paths {
+		out.Paths = append(out.Paths, jsonPath{i + 1, p.Depth(), p})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// formatPathsGherkin writes each path as a scenario. The root is the
+// Given step and the last node the Then step; every node between is a
+// When or And step, with the branch taken from it.
+func formatPathsGherkin(t *model.Tree, paths []Path) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Feature: %s\n", t.Name)
+	for i, p := range paths {
+		fmt.Fprintf(&b, "\n  Scenario: Path %d: %s\n", i+1, scenarioName(t, p))
+		last := len(p.Steps) - 1
+		if p.LoopsTo != "" {
+			last++ // the final step is the loop back
+		}
+		for j, s := range p.Steps {
+			keyword := "And"
+			switch {
+			case j == 0:
+				keyword = "Given"
+			case j == 1:
+				keyword = "When"
+			}
+			if j == last && j > 0 {
+				fmt.Fprintf(&b, "    Then %s\n", s.Label)
+				continue
+			}
+			branch := p.LoopEdge
+			if j+1 < len(p.Steps) {
+				branch = p.Steps[j+1].Edge
+			}
+			if branch != "" {
+				fmt.Fprintf(&b, "    %s %s is %q\n", keyword, s.Label, branch)
+			} else {
+				fmt.Fprintf(&b, "    %s %s\n", keyword, s.Label)
+			}
+		}
+		if p.LoopsTo != "" {
+			fmt.Fprintf(&b, "    Then it goes back to %s\n", t.GetNode(p.LoopsTo).Label)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func TestPaths(t *testing.T) {
+	paths, err := Paths(buildLaunchTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"n1 n2 n3", "n1 n2 n4", "n1 n5"}
+	if len(paths) != len(want) {
+		t.Fatalf("got %d paths, want %d", len(paths), len(want))
+	}
+	for i, p := range paths {
+		var ids []string
+		for _, s := range p.Steps {
+			ids = append(ids, s.NodeID)
+		}
+		if got := strings.Join(ids, " "); got != want[i] {
+			t.Errorf("path %d = %s, want %s", i+1, got, want[i])
+		}
+	}
+	if s := paths[0].Steps[2]; s.Edge != "good" || s.Type != "startend" || s.Label != "Strong" {
+		t.Errorf("step = %+v", s)
+	}
+	if d := MaxDepth(paths); d != 2 {
+		t.Errorf("MaxDepth = %d, want 2", d)
+	}
+}
+
+func TestPathsLoop(t *testing.T) {
+	tr := buildLaunchTree()
+	tr.Graph = true
+	tr.Edges = append(tr.Edges, model.Edge{FromID: "n4", ToID: "n1", Label: "retry"})
+	paths, err := Paths(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 || paths[1].LoopsTo != "n1" || paths[1].LoopEdge != "retry" || paths[1].Depth() != 3 {
+		t.Fatalf("paths = %+v", paths)
+	}
+	want := "2. Launch? -[yes]-> Market response -[bad]-> Weak -[retry]-> (back to Launch?)\n"
+	if out := FormatPaths(tr, paths); !strings.Contains(out, want) || !strings.HasSuffix(out, "3 paths, max depth 3\n") {
+		t.Errorf("FormatPaths =\n%s", out)
+	}
+}
+
+func TestPathsNoRoot(t *testing.T) {
+	tr := buildLaunchTree()
+	tr.RootID = ""
+	if _, err := Paths(tr); err == nil {
+		t.Error("expected error without a root")
+	}
+}
+
+func TestWritePaths(t *testing.T) {
+	tr := buildLaunchTree()
+	paths, _ := Paths(tr)
+
+	var buf bytes.Buffer
+	if err := WritePaths(&buf, tr, paths, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if lines[0] != "path,depth,outcome,steps,nodes" || lines[3] != "3,1,Status quo,Launch? -[no]-> Status quo,n1 n5" {
+		t.Errorf("csv =\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WritePaths(&buf, tr, paths, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Count    int `json:"count"`
+		MaxDepth int `json:"max_depth"`
+		Paths    []struct {
+			Path  int
+			Depth int
+			Steps []PathStep
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Count != 3 || doc.MaxDepth != 2 || doc.Paths[2].Path != 3 || doc.Paths[2].Steps[1].Edge != "no" {
+		t.Errorf("json = %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WritePaths(&buf, tr, paths, "gherkin"); err != nil {
+		t.Fatal(err)
+	}
+	want := `Feature: launch
+
+  Scenario: Path 1: Launch? yes, Market response good
+    Given Launch? is "yes"
+    When Market response is "good"
+    Then Strong
+`
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("gherkin =\n%s", buf.String())
+	}
+
+	if err := WritePaths(&buf, tr, paths, "xml"); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
@@ -0,0 +1,210 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// Stats summarizes the size and shape of a tree. The path figures cover
+// the part of the tree reachable from the root and are zero without one.
+type Stats struct {
+	Nodes     int            `json:"nodes"`
+	NodeTypes map[string]int `json:"node_types"`
+	Edges     int            `json:"edges"`
+	Leaves    int            `json:"leaves"`
+	// Orphans are the nodes that cannot be reached from the root; without
+	// a root, that is every node.
+	Orphans  int     `json:"orphans"`
+	Paths    int     `json:"paths"`
+	MaxDepth int     `json:"max_depth"`
+	AvgDepth float64 `json:"avg_depth"`
+	// Branching maps a number of outgoing edges to how many nodes have it.
+	Branching map[int]int `json:"branching"`
+	// AvgBranching is the average number of outgoing edges of the nodes
+	// that have any.
+	AvgBranching float64 `json:"avg_branching"`
+	// Complexity is the cyclomatic complexity of the tree as a flow graph
+	// whose leaves all lead to one exit: edges - nodes + 2 x connected
+	// components. It is 1 for a chain, and each extra branch or loop adds
+	// one, so for a plain tree it equals the number of paths.
+	Complexity int `json:"complexity"`
+}
+
+// ComputeStats measures a tree. It fails only for a graph with loops that
+// has more than MaxPaths paths.
+func ComputeStats(t *model.Tree) (*Stats, error) {
+	st := &Stats{
+		Nodes:     len(t.Nodes),
+		NodeTypes: make(map[string]int),
+		Edges:     len(t.Edges),
+		Branching: make(map[int]int),
+	}
+	for _, nt := range []model.NodeType{model.Decision, model.Action, model.StartEnd, model.IO} {
+		st.NodeTypes[nt.String()] = 0
+	}
+	branches := 0
+	for _, id := range t.NodeIDs() {
+		st.NodeTypes[t.Nodes[id].Type.String()]++
+		k := len(t.Children(id))
+		st.Branching[k]++
+		if k == 0 {
+			st.Leaves++
+		}
+		branches += k
+	}
+	if branching := st.Nodes - st.Leaves; branching > 0 {
+		st.AvgBranching = float64(branches) / float64(branching)
+	}
+
+	st.Orphans = st.Nodes
+	if t.GetNode(t.RootID) != nil {
+		reached, err := pathStats(t, st)
+		if err != nil {
+			return nil, err
+		}
+		st.Orphans -= reached
+	}
+
+	st.Complexity = complexity(t)
+	return st, nil
+}
+
+// pathStats fills in the path count and depths, and returns how many
+// nodes the root reaches. Paths are counted from the bottom up, so a
+// shared subtree is visited once however many paths run through it; only
+// a graph with loops needs its paths listed.
+func pathStats(t *model.Tree, st *Stats) (int, error) {
+	type sub struct{ paths, depthSum, maxDepth int }
+	memo := make(map[string]sub)
+	onPath := make(map[string]bool)
+	cyclic := false
+	var visit func(id string) sub
+	visit = func(id string) sub {
+		if s, ok := memo[id]; ok {
+			return s
+		}
+		onPath[id] = true
+		defer delete(onPath, id)
+		s := sub{}
+		children := t.Children(id)
+		if len(children) == 0 {
+			s.paths = 1
+		}
+		for _, e := range children {
+			if onPath[e.ToID] {
+				cyclic = true
+				continue
+			}
+			c := visit(e.ToID)
+			s.paths += c.paths
+			s.depthSum += c.depthSum + c.paths
+			s.maxDepth = max(s.maxDepth, c.maxDepth+1)
+		}
+		memo[id] = s
+		return s
+	}
+	root := visit(t.RootID)
+	reached := len(memo)
+	if cyclic {
+		paths, err := Paths(t)
+		if err != nil {
+			return 0, err
+		}
+		root = sub{paths: len(paths), maxDepth: MaxDepth(paths)}
+		for _, p := range paths {
+			root.depthSum += p.Depth()
+		}
+	}
+	st.Paths = root.paths
+	st.MaxDepth = root.maxDepth
+	if root.paths > 0 {
+		st.AvgDepth = float64(root.depthSum) / float64(root.paths)
+	}
+	return reached, nil
+}
+
+// complexity computes edges - nodes + 2 x components after joining every
+// leaf to an extra exit node, ignoring edge direction for the components.
+func complexity(t *model.Tree) int {
+	if len(t.Nodes) == 0 {
+		return 0
+	}
+	const exit = "" // no node has an empty ID
+	parent := make(map[string]string, len(t.Nodes)+1)
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] == id {
+			return id
+		}
+		parent[id] = find(parent[id])
+		return parent[id]
+	}
+	components := 0
+	join := func(from, to string) {
+		if a, b := find(from), find(to); a != b {
+			parent[a] = b
+			components--
+		}
+	}
+	add := func(id string) {
+		parent[id] = id
+		components++
+	}
+	for id := range t.Nodes {
+		add(id)
+	}
+	edges, nodes := len(t.Edges), len(t.Nodes)
+	for _, e := range t.Edges {
+		join(e.FromID, e.ToID)
+	}
+	for _, id := range t.NodeIDs() {
+		if len(t.Children(id)) > 0 {
+			continue
+		}
+		if _, ok := parent[exit]; !ok {
+			add(exit)
+			nodes++
+		}
+		join(id, exit)
+		edges++
+	}
+	return edges - nodes + 2*components
+}
+
+// FormatStats writes the statistics one per line.
+func FormatStats(st *Stats) string {
+	var b strings.Builder
+	var types []string
+	for _, nt := range []model.NodeType{model.Decision, model.Action, model.StartEnd, model.IO} {
+		types = append(types, fmt.Sprintf("%s %d", nt, st.NodeTypes[nt.String()]))
+	}
+	fmt.Fprintf(&b, "Nodes:       %d (%s)\n", st.Nodes, strings.Join(types, ", "))
+	fmt.Fprintf(&b, "Edges:       %d\n", st.Edges)
+	fmt.Fprintf(&b, "Leaves:      %d\n", st.Leaves)
+	fmt.Fprintf(&b, "Orphans:     %d\n", st.Orphans)
+	fmt.Fprintf(&b, "Paths:       %d\n", st.Paths)
+	fmt.Fprintf(&b, "Depth:       max %d, average %.2f\n", st.MaxDepth, st.AvgDepth)
+
+	degrees := make([]int, 0, len(st.Branching))
+	for k := range st.Branching {
+		degrees = append(degrees, k)
+	}
+	sort.Ints(degrees)
+	var dist []string
+	for _, k := range degrees {
+		noun := "nodes"
+		if st.Branching[k] == 1 {
+			noun = "node"
+		}
+		dist = append(dist, fmt.Sprintf("%d: %d %s", k, st.Branching[k], noun))
+	}
+	if len(dist) == 0 {
+		dist = append(dist, "none")
+	}
+	fmt.Fprintf(&b, "Branching:   %s (average %.2f)\n", strings.Join(dist, ", "), st.AvgBranching)
+	fmt.Fprintf(&b, "Complexity:  %d\n", st.Complexity)
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func TestComputeStats(t *testing.T) {
+	st, err := ComputeStats(buildLaunchTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Nodes != 5 || st.NodeTypes["decision"] != 2 || st.NodeTypes["startend"] != 3 || st.NodeTypes["io"] != 0 {
+		t.Errorf("nodes = %d, types %v", st.Nodes, st.NodeTypes)
+	}
+	if st.Edges != 4 || st.Leaves != 3 || st.Orphans != 0 || st.Paths != 3 {
+		t.Errorf("edges %d, leaves %d, orphans %d, paths %d", st.Edges, st.Leaves, st.Orphans, st.Paths)
+	}
+	if st.MaxDepth != 2 || math.Abs(st.AvgDepth-5.0/3) > 1e-9 {
+		t.Errorf("depth max %d, average %g", st.MaxDepth, st.AvgDepth)
+	}
+	if len(st.Branching) != 2 || st.Branching[0] != 3 || st.Branching[2] != 2 || st.AvgBranching != 2 {
+		t.Errorf("branching %v, average %g", st.Branching, st.AvgBranching)
+	}
+	if st.Complexity != 3 {
+		t.Errorf("complexity = %d, want 3 (one per path)", st.Complexity)
+	}
+}
+
+func TestComputeStatsSharedAndLoops(t *testing.T) {
+	// A diamond: n1 branches to n2 and n3, which both lead to n4.
+	tr := model.NewTree("diamond")
+	tr.Graph = true
+	for _, id := range []string{"n1", "n2", "n3", "n4"} {
+		tr.Nodes[id] = &model.Node{ID: id, Type: model.Action, Label: id}
+	}
+	tr.Edges = []model.Edge{{FromID: "n1", ToID: "n2"}, {FromID: "n1", ToID: "n3"}, {FromID: "n2", ToID: "n4"}, {FromID: "n3", ToID: "n4"}}
+	tr.RootID = "n1"
+	st, err := ComputeStats(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Paths != 2 || st.MaxDepth != 2 || st.AvgDepth != 2 || st.Complexity != 2 {
+		t.Errorf("diamond: %+v", st)
+	}
+
+	// Looping from n4 back to n1 replaces the leaf with a loop.
+	tr.Edges = append(tr.Edges, model.Edge{FromID: "n4", ToID: "n1"})
+	st, err = ComputeStats(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Paths != 2 || st.Leaves != 0 || st.MaxDepth != 3 || st.Complexity != 3 {
+		t.Errorf("loop: %+v", st)
+	}
+}
+
+func TestComputeStatsOrphans(t *testing.T) {
+	tr := buildLaunchTree()
+	tr.Nodes["n6"] = &model.Node{ID: "n6", Type: model.IO, Label: "Lost"}
+	st, _ := ComputeStats(tr)
+	if st.Orphans != 1 || st.Paths != 3 {
+		t.Errorf("orphans %d, paths %d", st.Orphans, st.Paths)
+	}
+	tr.RootID = ""
+	st, _ = ComputeStats(tr)
+	if st.Orphans != 6 || st.Paths != 0 || st.MaxDepth != 0 {
+		t.Errorf("no root: orphans %d, paths %d", st.Orphans, st.Paths)
+	}
+	if st, _ := ComputeStats(model.NewTree("empty")); st.Complexity != 0 || len(st.Branching) != 0 {
+		t.Errorf("empty: %+v", st)
+	}
+}
+
+func TestFormatStats(t *testing.T) {
+	st, _ := ComputeStats(buildLaunchTree())
+	out := FormatStats(st)
+	for _, want := range []string{
+		"Nodes:       5 (decision 2, action 0, startend 3, io 0)\n",
+		"Depth:       max 2, average 1.67\n",
+		"Branching:   0: 3 nodes, 2: 2 nodes (average 2.00)\n",
+		"Complexity:  3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
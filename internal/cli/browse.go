@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/jllovet/decision-tree-cli/internal/model"
 	"github.com/jllovet/decision-tree-cli/internal/terminal"
@@ -15,11 +16,23 @@ import (
 type flatRow struct {
 	nodeID string
 	text   string
+	ref    bool // back-reference to a node already shown above
+	parent int  // index of the parent row, or -1 for the root
+	folded bool // the node's children are hidden
+
+	// labelAt and labelEnd are the byte offsets of the node label in text.
+	labelAt, labelEnd int
 }
 
 // flattenTree produces a flat list of rows by DFS-walking the tree,
-// mirroring the ASCII preview rendering.
+// mirroring the ASCII preview rendering, including its back-references.
 func flattenTree(t *model.Tree) []flatRow {
+	return flattenFolded(t, nil)
+}
+
+// flattenFolded is flattenTree with the children of the nodes in folded
+// left out.
+func flattenFolded(t *model.Tree, folded map[string]bool) []flatRow {
 	if t.RootID == "" {
 		return nil
 	}
@@ -27,32 +40,51 @@ func flattenTree(t *model.Tree) []flatRow {
 		return nil
 	}
 	var rows []flatRow
-	flattenNode(&rows, t, t.RootID, "", "", true, true)
+	flattenNode(&rows, t, make(map[string]bool), folded, t.RootID, "", "", -1, true, true)
 	return rows
 }
 
-func flattenNode(rows *[]flatRow, t *model.Tree, nodeID, edgeLabel, prefix string, isLast, isRoot bool) {
+func flattenNode(rows *[]flatRow, t *model.Tree, seen, folded map[string]bool, nodeID, edgeLabel, prefix string, parent int, isLast, isRoot bool) {
 	n := t.GetNode(nodeID)
 	if n == nil {
 		return
 	}
+	ref := seen[nodeID]
+	seen[nodeID] = true
+	children := t.Children(nodeID)
+	fold := !ref && folded[nodeID] && len(children) > 0
 
 	edgePart := ""
 	if edgeLabel != "" {
 		edgePart = "[" + edgeLabel + "] "
 	}
 
+	nodePart := nodeDecorator(n)
+	if ref {
+		nodePart = fmt.Sprintf("↪ %s (%s)", nodePart, n.ID)
+	}
+
 	var text string
 	if isRoot {
-		text = edgePart + nodeDecorator(n)
+		text = edgePart + nodePart
 	} else {
 		connector := "├── "
 		if isLast {
 			connector = "└── "
 		}
-		text = prefix + connector + edgePart + nodeDecorator(n)
+		text = prefix + connector + edgePart + nodePart
+	}
+	labelAt := len(text) - len(nodePart) + strings.Index(nodePart, n.Label)
+	row := flatRow{nodeID: nodeID, text: text, ref: ref, parent: parent, folded: fold,
+		labelAt: labelAt, labelEnd: labelAt + len(n.Label)}
+	if fold {
+		row.text += fmt.Sprintf(" … (+%d)", len(children))
 	}
-	*rows = append(*rows, flatRow{nodeID: nodeID, text: text})
+	*rows = append(*rows, row)
+	if ref || fold {
+		return
+	}
+	self := len(*rows) - 1
 
 	var childPrefix string
 	if isRoot {
@@ -63,10 +95,9 @@ func flattenNode(rows *[]flatRow, t *model.Tree, nodeID, edgeLabel, prefix strin
 		childPrefix = prefix + "│   "
 	}
 
-	children := t.Children(nodeID)
 	for i, e := range children {
 		last := i == len(children)-1
-		flattenNode(rows, t, e.ToID, e.Label, childPrefix, last, false)
+		flattenNode(rows, t, seen, folded, e.ToID, e.Label, childPrefix, self, last, false)
 	}
 }
 
@@ -98,7 +129,10 @@ type browser struct {
 	height      int // visible rows (terminal rows minus status/message)
 	width       int // terminal columns
 	message     string
-	connectFrom string // when non-empty, browser is in connect mode
+	connectFrom string          // when non-empty, browser is in connect mode
+	folded      map[string]bool // nodes whose children are hidden
+	search      string          // last search query, highlighted in the rows
+	cut         string          // node marked with x, moved by P
 }
 
 func newBrowser(s *Session, in io.Reader, out io.Writer) *browser {
@@ -182,6 +216,10 @@ func (b *browser) run() error {
 			b.opCopy()
 		case keyPaste:
 			b.opPaste()
+		case keyCut:
+			b.opCut()
+		case keyMovePaste:
+			b.opMovePaste()
 		case keyConnect:
 			b.opConnect()
 		case keyDisconnect:
@@ -192,6 +230,22 @@ func (b *browser) run() error {
 			b.opUndo()
 		case keyRedo:
 			b.opRedo()
+		case keyFold:
+			b.opFold()
+		case keyUnfold:
+			b.opUnfold()
+		case keyToggleFold:
+			b.opToggleFold()
+		case keyFoldAll:
+			b.opFoldAll()
+		case keyUnfoldAll:
+			b.opUnfoldAll()
+		case keySearch:
+			b.opSearch()
+		case keyNextMatch:
+			b.opNextMatch(1)
+		case keyPrevMatch:
+			b.opNextMatch(-1)
 		default:
 			continue
 		}
@@ -200,7 +254,7 @@ func (b *browser) run() error {
 }
 
 func (b *browser) refresh() {
-	b.rows = flattenTree(b.session.Tree)
+	b.rows = flattenFolded(b.session.Tree, b.folded)
 	if b.cursor >= len(b.rows) {
 		b.cursor = len(b.rows) - 1
 	}
@@ -249,13 +303,17 @@ func (b *browser) render() {
 			}
 			if b.connectFrom != "" && b.rows[i].nodeID == b.connectFrom {
 				marker = "+ "
+			} else if b.cut != "" && b.rows[i].nodeID == b.cut {
+				marker = "x "
 			}
 			if i == b.cursor {
-				fmt.Fprintf(b.out, "\x1b[7m%s%s\x1b[0m\x1b[K\r\n", marker, b.rows[i].text)
+				fmt.Fprintf(b.out, "\x1b[7m%s%s\x1b[0m\x1b[K\r\n", marker, b.rowText(i, "\x1b[7m"))
 			} else if b.connectFrom != "" && b.rows[i].nodeID == b.connectFrom {
-				fmt.Fprintf(b.out, "\x1b[33m%s%s\x1b[0m\x1b[K\r\n", marker, b.rows[i].text)
+				fmt.Fprintf(b.out, "\x1b[33m%s%s\x1b[0m\x1b[K\r\n", marker, b.rowText(i, "\x1b[33m"))
+			} else if b.cut != "" && b.rows[i].nodeID == b.cut {
+				fmt.Fprintf(b.out, "\x1b[2m%s%s\x1b[0m\x1b[K\r\n", marker, b.rowText(i, "\x1b[2m"))
 			} else {
-				fmt.Fprintf(b.out, "%s%s\x1b[K\r\n", marker, b.rows[i].text)
+				fmt.Fprintf(b.out, "%s%s\x1b[K\r\n", marker, b.rowText(i, ""))
 			}
 		}
 		// Fill remaining lines if tree is shorter than viewport
@@ -277,7 +335,7 @@ func (b *browser) render() {
 	if b.connectFrom != "" {
 		status = fmt.Sprintf(" Connect %s \u2192 ? | \u2191\u2193 Navigate  Enter Confirm  Esc Cancel", b.connectFrom)
 	} else {
-		status = " \u2191\u2193/jk Navigate  e Edit  t Type  r Root  d Delete  a Add  y Copy  p Paste  c Connect  D Detach  u Undo  ^R Redo  q Quit"
+		status = " \u2191\u2193/jk Navigate  h/l Fold  H/L Fold all  / Search  n/N Next  e Edit  t Type  r Root  d Delete  a Add  y Copy  p Paste  x Cut  P Move  c Connect  D Detach  u Undo  ^R Redo  q Quit"
 	}
 	if runeLen := len([]rune(status)); runeLen > b.width {
 		status = string([]rune(status)[:b.width])
@@ -287,6 +345,48 @@ func (b *browser) render() {
 	fmt.Fprintf(b.out, "\x1b[7m%s\x1b[0m", status)
 }
 
+// rowText returns a row's text with the search matches in its label
+// highlighted. style is the row's own color, restored after each match.
+func (b *browser) rowText(i int, style string) string {
+	row := b.rows[i]
+	if b.search == "" || row.ref {
+		return row.text
+	}
+	spans := matchSpans(row.text[row.labelAt:row.labelEnd], b.search)
+	if len(spans) == 0 {
+		return row.text
+	}
+	var s strings.Builder
+	last := 0
+	for _, sp := range spans {
+		start, end := row.labelAt+sp[0], row.labelAt+sp[1]
+		s.WriteString(row.text[last:start])
+		s.WriteString("\x1b[0m\x1b[30;43m" + row.text[start:end] + "\x1b[0m" + style)
+		last = end
+	}
+	s.WriteString(row.text[last:])
+	return s.String()
+}
+
+// matchSpans returns the byte ranges of the case-insensitive,
+// non-overlapping occurrences of query in s.
+func matchSpans(s, query string) [][2]int {
+	if query == "" {
+		return nil
+	}
+	var spans [][2]int
+	for i := 0; i+len(query) <= len(s); {
+		if strings.EqualFold(s[i:i+len(query)], query) {
+			spans = append(spans, [2]int{i, i + len(query)})
+			i += len(query)
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return spans
+}
+
 // Key constants
 const (
 	keyUp         = iota + 256
@@ -306,6 +406,16 @@ const (
 	keyUndo
 	keyRedo
 	keyInit
+	keyFold
+	keyUnfold
+	keyToggleFold
+	keyFoldAll
+	keyUnfoldAll
+	keySearch
+	keyNextMatch
+	keyPrevMatch
+	keyCut
+	keyMovePaste
 )
 
 func (b *browser) readKey() int {
@@ -327,6 +437,10 @@ func (b *browser) readKey() int {
 				return keyUp
 			case 'B':
 				return keyDown
+			case 'C':
+				return keyUnfold
+			case 'D':
+				return keyFold
 			}
 		}
 		// Bare Esc (seq[0] was not '[' or unrecognized)
@@ -352,6 +466,10 @@ func (b *browser) readKey() int {
 		return keyCopy
 	case 'p':
 		return keyPaste
+	case 'x':
+		return keyCut
+	case 'P':
+		return keyMovePaste
 	case 'c':
 		return keyConnect
 	case 'D':
@@ -362,6 +480,22 @@ func (b *browser) readKey() int {
 		return keyUndo
 	case 0x12: // Ctrl+R
 		return keyRedo
+	case 'h':
+		return keyFold
+	case 'l':
+		return keyUnfold
+	case ' ':
+		return keyToggleFold
+	case 'H':
+		return keyFoldAll
+	case 'L':
+		return keyUnfoldAll
+	case '/':
+		return keySearch
+	case 'n':
+		return keyNextMatch
+	case 'N':
+		return keyPrevMatch
 	}
 	return -1
 }
@@ -369,12 +503,23 @@ func (b *browser) readKey() int {
 // prompt displays a mini-prompt on the message line and reads text input.
 // Returns the entered text and true, or empty string and false if cancelled (Esc).
 func (b *browser) prompt(label string) (string, bool) {
+	return b.promptWith(label, nil)
+}
+
+// promptWith is prompt, calling onChange with the text after every edit.
+func (b *browser) promptWith(label string, onChange func(string)) (string, bool) {
 	buf := make([]byte, 0, 128)
 
 	redraw := func() {
 		// Move to the message line (height + 1 from top)
 		fmt.Fprintf(b.out, "\x1b[%d;1H\x1b[K%s%s", b.height+1, label, string(buf))
 	}
+	changed := func() {
+		if onChange != nil {
+			onChange(string(buf))
+		}
+		redraw()
+	}
 	redraw()
 
 	raw := make([]byte, 1)
@@ -390,14 +535,14 @@ func (b *browser) prompt(label string) (string, bool) {
 		case raw[0] == 0x7f || raw[0] == 0x08:
 			if len(buf) > 0 {
 				buf = buf[:len(buf)-1]
-				redraw()
+				changed()
 			}
 		case raw[0] == 0x15: // Ctrl+U: clear input
 			buf = buf[:0]
-			redraw()
+			changed()
 		case raw[0] >= 0x20:
 			buf = append(buf, raw[0])
-			redraw()
+			changed()
 		}
 	}
 }
@@ -513,27 +658,27 @@ func (b *browser) opAddChild() {
 		b.message = "Add cancelled"
 		return
 	}
-	// Add the node
-	addCmd := tree.NewAddNodeCmd(nodeType, label)
-	if err := b.session.History.Execute(b.session.Tree, addCmd); err != nil {
-		b.message = "Error: " + err.Error()
-		return
-	}
-	// Get the new node's ID
-	type idGetter interface{ ID() string }
-	childID := ""
-	if ig, ok := addCmd.(idGetter); ok {
-		childID = ig.ID()
-	}
 	// Prompt for edge label
 	edgeLabel, ok := b.prompt("Edge label (Enter for none): ")
 	if !ok {
 		edgeLabel = ""
 	}
-	// Connect parent to child
-	connCmd := tree.NewConnectCmd(parentID, childID, edgeLabel)
-	if err := b.session.History.Execute(b.session.Tree, connCmd); err != nil {
-		b.message = "Error connecting: " + err.Error()
+	// Add the node and connect it as one undoable step
+	h, t := b.session.History, b.session.Tree
+	childID := ""
+	err = h.Atomic(t, func() error {
+		addCmd := tree.NewAddNodeCmd(nodeType, label)
+		if err := h.Execute(t, addCmd); err != nil {
+			return err
+		}
+		type idGetter interface{ ID() string }
+		if ig, ok := addCmd.(idGetter); ok {
+			childID = ig.ID()
+		}
+		return h.Execute(t, tree.NewConnectCmd(parentID, childID, edgeLabel))
+	})
+	if err != nil {
+		b.message = "Error: " + err.Error()
 		return
 	}
 	b.message = fmt.Sprintf("Added %s as child of %s", childID, parentID)
@@ -563,25 +708,65 @@ func (b *browser) opPaste() {
 		b.message = "Clipboard is empty"
 		return
 	}
-	cmd := tree.NewPasteSubtreeCmd(b.session.Clipboard)
-	if err := b.session.History.Execute(b.session.Tree, cmd); err != nil {
+	// Paste and connect the pasted root to the parent as one undoable step
+	h, t := b.session.History, b.session.Tree
+	var idMap map[string]string
+	err := h.Atomic(t, func() error {
+		cmd := tree.NewPasteSubtreeCmd(b.session.Clipboard)
+		if err := h.Execute(t, cmd); err != nil {
+			return err
+		}
+		type pastedIDsGetter interface{ PastedIDs() map[string]string }
+		if pg, ok := cmd.(pastedIDsGetter); ok {
+			idMap = pg.PastedIDs()
+		}
+		return h.Execute(t, tree.NewConnectCmd(parentID, idMap[b.session.Clipboard.Root], ""))
+	})
+	if err != nil {
 		b.message = "Error: " + err.Error()
 		return
 	}
-	// Get pasted root and connect to parent
-	type pastedIDsGetter interface{ PastedIDs() map[string]string }
-	if pg, ok := cmd.(pastedIDsGetter); ok {
-		idMap := pg.PastedIDs()
-		newRoot := idMap[b.session.Clipboard.Root]
-		connCmd := tree.NewConnectCmd(parentID, newRoot, "")
-		if err := b.session.History.Execute(b.session.Tree, connCmd); err != nil {
-			b.message = "Pasted but could not connect: " + err.Error()
-			b.refresh()
-			return
-		}
-		b.message = fmt.Sprintf("Pasted %d nodes under %s", len(idMap), parentID)
+	b.message = fmt.Sprintf("Pasted %d nodes under %s", len(idMap), parentID)
+	b.refresh()
+}
+
+// opCut marks the selected node to be moved by opMovePaste. Cutting the
+// marked node again clears the mark. Nothing changes until the paste.
+func (b *browser) opCut() {
+	id := b.selectedNodeID()
+	if id == "" {
+		return
+	}
+	if b.cut == id {
+		b.cut = ""
+		b.message = "Cut cleared"
+		return
+	}
+	b.cut = id
+	b.message = fmt.Sprintf("Cut %s (press P on the new parent to move it)", id)
+}
+
+// opMovePaste moves the cut node, keeping its ID, edge label and subtree,
+// under the selected node as a single undoable step.
+func (b *browser) opMovePaste() {
+	parentID := b.selectedNodeID()
+	if parentID == "" {
+		return
+	}
+	if b.cut == "" {
+		b.message = "Nothing cut (press x on a node first)"
+		return
+	}
+	id := b.cut
+	if err := b.session.History.Execute(b.session.Tree, tree.NewMoveCmd(id, parentID, nil)); err != nil {
+		b.message = "Error: " + err.Error()
+		return
 	}
+	b.cut = ""
+	delete(b.folded, parentID)
+	b.message = fmt.Sprintf("Moved %s under %s", id, parentID)
 	b.refresh()
+	b.selectNode(id)
 }
 
 func (b *browser) addRoot() {
@@ -600,19 +785,22 @@ func (b *browser) addRoot() {
 		b.message = "Add cancelled"
 		return
 	}
-	addCmd := tree.NewAddNodeCmd(nodeType, label)
-	if err := b.session.History.Execute(b.session.Tree, addCmd); err != nil {
-		b.message = "Error: " + err.Error()
-		return
-	}
-	type idGetter interface{ ID() string }
+	// Add the node and make it the root as one undoable step
+	h, t := b.session.History, b.session.Tree
 	newID := ""
-	if ig, ok := addCmd.(idGetter); ok {
-		newID = ig.ID()
-	}
-	setRootCmd := tree.NewSetRootCmd(newID)
-	if err := b.session.History.Execute(b.session.Tree, setRootCmd); err != nil {
-		b.message = "Error setting root: " + err.Error()
+	err = h.Atomic(t, func() error {
+		addCmd := tree.NewAddNodeCmd(nodeType, label)
+		if err := h.Execute(t, addCmd); err != nil {
+			return err
+		}
+		type idGetter interface{ ID() string }
+		if ig, ok := addCmd.(idGetter); ok {
+			newID = ig.ID()
+		}
+		return h.Execute(t, tree.NewSetRootCmd(newID))
+	})
+	if err != nil {
+		b.message = "Error: " + err.Error()
 		return
 	}
 	b.message = fmt.Sprintf("Created root node %s", newID)
@@ -697,6 +885,9 @@ func (b *browser) opInit() {
 	b.session.Tree = tmpl.Build()
 	b.session.History = tree.NewHistory()
 	b.session.Clipboard = nil
+	b.folded = nil
+	b.search = ""
+	b.cut = ""
 	b.message = fmt.Sprintf("Initialized from %q (%d nodes)", tmpl.Name, len(b.session.Tree.Nodes))
 	b.refresh()
 }
@@ -718,3 +909,230 @@ func (b *browser) opRedo() {
 	b.message = "Redone"
 	b.refresh()
 }
+
+// opFold hides the selected node's children. On a leaf, a folded node or
+// a back-reference it moves to the parent row instead, so repeated h walks
+// up the tree.
+func (b *browser) opFold() {
+	if b.cursor < 0 || b.cursor >= len(b.rows) {
+		return
+	}
+	row := b.rows[b.cursor]
+	if row.ref || row.folded || len(b.session.Tree.Children(row.nodeID)) == 0 {
+		if row.parent >= 0 {
+			b.cursor = row.parent
+			b.scrollToCursor()
+		}
+		return
+	}
+	b.setFolded(row.nodeID, true)
+}
+
+// opUnfold shows the selected node's children, or moves to the first
+// child if they are already shown.
+func (b *browser) opUnfold() {
+	if b.cursor < 0 || b.cursor >= len(b.rows) {
+		return
+	}
+	row := b.rows[b.cursor]
+	if row.folded {
+		b.setFolded(row.nodeID, false)
+		return
+	}
+	if b.cursor+1 < len(b.rows) && b.rows[b.cursor+1].parent == b.cursor {
+		b.cursor++
+		b.scrollToCursor()
+	}
+}
+
+func (b *browser) opToggleFold() {
+	if b.cursor < 0 || b.cursor >= len(b.rows) {
+		return
+	}
+	row := b.rows[b.cursor]
+	switch {
+	case row.folded:
+		b.setFolded(row.nodeID, false)
+	case !row.ref && len(b.session.Tree.Children(row.nodeID)) > 0:
+		b.setFolded(row.nodeID, true)
+	}
+}
+
+func (b *browser) setFolded(id string, fold bool) {
+	if b.folded == nil {
+		b.folded = make(map[string]bool)
+	}
+	if fold {
+		b.folded[id] = true
+	} else {
+		delete(b.folded, id)
+	}
+	b.refresh()
+	b.selectNode(id)
+}
+
+// opFoldAll folds every node below the root, leaving the root and its
+// children visible.
+func (b *browser) opFoldAll() {
+	chain := b.ancestry()
+	b.folded = make(map[string]bool)
+	t := b.session.Tree
+	for id := range t.Nodes {
+		if id != t.RootID && len(t.Children(id)) > 0 {
+			b.folded[id] = true
+		}
+	}
+	b.refresh()
+	b.selectNode(chain...)
+}
+
+func (b *browser) opUnfoldAll() {
+	chain := b.ancestry()
+	b.folded = nil
+	b.refresh()
+	b.selectNode(chain...)
+}
+
+// ancestry returns the selected node's ID followed by its ancestors' as
+// shown in the rows, nearest first.
+func (b *browser) ancestry() []string {
+	var ids []string
+	for i := b.cursor; i >= 0 && i < len(b.rows); i = b.rows[i].parent {
+		ids = append(ids, b.rows[i].nodeID)
+	}
+	return ids
+}
+
+// selectNode moves the cursor to the first of ids that has a row,
+// preferring a node's own row to a back-reference.
+func (b *browser) selectNode(ids ...string) {
+	for _, id := range ids {
+		ref := -1
+		for i, row := range b.rows {
+			if row.nodeID != id {
+				continue
+			}
+			if !row.ref {
+				b.cursor = i
+				b.scrollToCursor()
+				return
+			}
+			if ref < 0 {
+				ref = i
+			}
+		}
+		if ref >= 0 {
+			b.cursor = ref
+			b.scrollToCursor()
+			return
+		}
+	}
+}
+
+// opSearch reads a query and moves to the first label that contains it,
+// case-insensitively, as it is typed. Matches in folded subtrees are
+// unfolded. Esc restores the view; an empty query clears the search.
+func (b *browser) opSearch() {
+	from := b.selectedNodeID()
+	cursor, offset, search := b.cursor, b.offset, b.search
+	folded := make(map[string]bool, len(b.folded))
+	for id := range b.folded {
+		folded[id] = true
+	}
+	restore := func() {
+		b.folded = make(map[string]bool, len(folded))
+		for id := range folded {
+			b.folded[id] = true
+		}
+		b.refresh()
+		b.cursor, b.offset = cursor, offset
+	}
+
+	query, ok := b.promptWith("/", func(q string) {
+		restore()
+		b.search = q
+		if q != "" && !b.jumpToMatch(from, 0) {
+			b.message = "No match for " + q
+		}
+		b.render()
+	})
+	if !ok {
+		restore()
+		b.search = search
+		b.message = "Search cancelled"
+		return
+	}
+	b.search = query
+	if query == "" {
+		b.message = "Search cleared"
+		return
+	}
+	if !b.jumpToMatch(from, 0) {
+		b.message = "No match for " + query
+	}
+}
+
+// opNextMatch moves to the next (dir 1) or previous (dir -1) match of
+// the last search, wrapping around.
+func (b *browser) opNextMatch(dir int) {
+	if b.search == "" {
+		b.message = "No search (press / to search)"
+		return
+	}
+	if !b.jumpToMatch(b.selectedNodeID(), dir) {
+		b.message = "No match for " + b.search
+	}
+}
+
+// jumpToMatch selects the first node matching the search in tree order
+// after from (dir 1), before it (dir -1), or at or after it (dir 0),
+// wrapping around. It searches the unfolded tree and unfolds the match's
+// ancestors. It reports whether there was a match.
+func (b *browser) jumpToMatch(from string, dir int) bool {
+	full := flattenTree(b.session.Tree)
+	var matches []int
+	cur := -1
+	for i, row := range full {
+		if row.ref {
+			continue
+		}
+		if row.nodeID == from {
+			cur = i
+		}
+		if len(matchSpans(row.text[row.labelAt:row.labelEnd], b.search)) > 0 {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return false
+	}
+
+	k := -1
+	switch dir {
+	case -1:
+		k = len(matches) - 1
+		for j := len(matches) - 1; j >= 0; j-- {
+			if matches[j] < cur {
+				k = j
+				break
+			}
+		}
+	default:
+		k = 0
+		for j, m := range matches {
+			if m > cur || (dir == 0 && m == cur) {
+				k = j
+				break
+			}
+		}
+	}
+
+	m := matches[k]
+	for p := full[m].parent; p >= 0; p = full[p].parent {
+		delete(b.folded, full[p].nodeID)
+	}
+	b.refresh()
+	b.selectNode(full[m].nodeID)
+	b.message = fmt.Sprintf("/%s: match %d of %d", b.search, k+1, len(matches))
+	return true
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/jllovet/decision-tree-cli/internal/model"
@@ -48,6 +49,24 @@ func TestFlattenTree(t *testing.T) {
 	}
 }
 
+func TestFlattenTreeGraphBackReference(t *testing.T) {
+	tr := buildSampleTree()
+	tr.Graph = true
+	tree.ConnectNodes(tr, "n4", "n2", "retry")
+	rows := flattenTree(tr)
+
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, want 5", len(rows))
+	}
+	last := rows[4]
+	if last.nodeID != "n2" || !last.ref {
+		t.Errorf("last row = %+v, want back-reference to n2", last)
+	}
+	if last.text != "        └── [retry] ↪ <Auth?> (n2)" {
+		t.Errorf("last row text = %q", last.text)
+	}
+}
+
 func TestFlattenTreeEmpty(t *testing.T) {
 	tr := model.NewTree("empty")
 	rows := flattenTree(tr)
@@ -343,3 +362,226 @@ func TestViewportOffset(t *testing.T) {
 		t.Errorf("offset = %d, want 0", b.offset)
 	}
 }
+
+func newTestBrowser(tr *model.Tree, input string) *browser {
+	b := &browser{
+		session: &Session{
+			Tree:    tr,
+			History: tree.NewHistory(),
+		},
+		in:     bytes.NewReader([]byte(input)),
+		out:    &bytes.Buffer{},
+		height: 20,
+		width:  80,
+	}
+	b.refresh()
+	return b
+}
+
+func TestFlattenFolded(t *testing.T) {
+	rows := flattenFolded(buildSampleTree(), map[string]bool{"n2": true, "n3": true})
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[1].text != "└── <Auth?> … (+2)" || !rows[1].folded || rows[1].parent != 0 {
+		t.Errorf("row 1 = %+v", rows[1])
+	}
+	if rows[0].folded || rows[0].parent != -1 {
+		t.Errorf("row 0 = %+v", rows[0])
+	}
+	if got := rows[1].text[rows[1].labelAt:rows[1].labelEnd]; got != "Auth?" {
+		t.Errorf("label span = %q", got)
+	}
+}
+
+func TestFoldAndUnfold(t *testing.T) {
+	b := newTestBrowser(buildSampleTree(), "")
+
+	b.cursor = 1 // n2
+	b.opFold()
+	if len(b.rows) != 2 || !b.rows[1].folded || b.cursor != 1 {
+		t.Fatalf("after fold: %d rows, cursor %d", len(b.rows), b.cursor)
+	}
+	b.opFold() // already folded: move to the parent
+	if b.cursor != 0 {
+		t.Errorf("fold on folded node: cursor %d, want 0", b.cursor)
+	}
+	b.opUnfold() // n1 is expanded: move to its first child
+	if b.cursor != 1 {
+		t.Errorf("unfold on expanded node: cursor %d, want 1", b.cursor)
+	}
+	b.opUnfold()
+	if len(b.rows) != 4 || b.selectedNodeID() != "n2" {
+		t.Errorf("after unfold: %d rows, selected %s", len(b.rows), b.selectedNodeID())
+	}
+	b.opToggleFold()
+	if len(b.rows) != 2 {
+		t.Errorf("after toggle: %d rows, want 2", len(b.rows))
+	}
+	b.opToggleFold()
+	if len(b.rows) != 4 {
+		t.Errorf("after second toggle: %d rows, want 4", len(b.rows))
+	}
+
+	b.cursor = 3 // n4, a leaf
+	b.opFold()
+	if b.selectedNodeID() != "n2" {
+		t.Errorf("fold on leaf selected %s, want n2", b.selectedNodeID())
+	}
+}
+
+func TestFoldAll(t *testing.T) {
+	b := newTestBrowser(buildSampleTree(), "")
+	b.cursor = 2 // n3
+	b.opFoldAll()
+	if len(b.rows) != 2 || !b.rows[1].folded {
+		t.Fatalf("after fold all: %+v", b.rows)
+	}
+	if b.selectedNodeID() != "n2" {
+		t.Errorf("fold all selected %s, want the visible ancestor n2", b.selectedNodeID())
+	}
+	b.opUnfoldAll()
+	if len(b.rows) != 4 || b.selectedNodeID() != "n2" {
+		t.Errorf("after unfold all: %d rows, selected %s", len(b.rows), b.selectedNodeID())
+	}
+}
+
+func TestSearchUnfoldsAndCycles(t *testing.T) {
+	b := newTestBrowser(buildSampleTree(), "GRA\r")
+	b.cursor = 1
+	b.opFold() // hide n3 under n2
+	b.opSearch()
+	if b.search != "GRA" || b.selectedNodeID() != "n3" {
+		t.Fatalf("search = %q, selected %s", b.search, b.selectedNodeID())
+	}
+	if b.folded["n2"] {
+		t.Error("search should unfold the match's ancestors")
+	}
+	if b.message != "/GRA: match 1 of 1" {
+		t.Errorf("message = %q", b.message)
+	}
+
+	// "t" is in Start, Auth? and Grant.
+	b.search = "t"
+	b.opNextMatch(1)
+	if b.selectedNodeID() != "n1" {
+		t.Errorf("next from n3 wrapped to %s, want n1", b.selectedNodeID())
+	}
+	b.opNextMatch(1)
+	if b.selectedNodeID() != "n2" {
+		t.Errorf("next from n1 = %s, want n2", b.selectedNodeID())
+	}
+	b.opNextMatch(-1)
+	b.opNextMatch(-1)
+	if b.selectedNodeID() != "n3" {
+		t.Errorf("previous twice from n2 = %s, want n3", b.selectedNodeID())
+	}
+
+	b.search = "zzz"
+	b.opNextMatch(1)
+	if b.message != "No match for zzz" {
+		t.Errorf("message = %q", b.message)
+	}
+}
+
+func TestSearchCancelRestoresView(t *testing.T) {
+	b := newTestBrowser(buildSampleTree(), "gra\x1b")
+	b.cursor = 1
+	b.opFold()
+	b.opSearch()
+	if !b.folded["n2"] || b.cursor != 1 || b.search != "" {
+		t.Errorf("after cancel: folded %v, cursor %d, search %q", b.folded, b.cursor, b.search)
+	}
+}
+
+func TestRowTextHighlightsMatches(t *testing.T) {
+	b := newTestBrowser(buildSampleTree(), "")
+	b.search = "a"
+	want := "└── <\x1b[0m\x1b[30;43mA\x1b[0m\x1b[7muth?>"
+	if got := b.rowText(1, "\x1b[7m"); got != want {
+		t.Errorf("rowText = %q, want %q", got, want)
+	}
+	// Only the label is searched, not the edge label.
+	b.search = "yes"
+	if got := b.rowText(2, ""); got != b.rows[2].text {
+		t.Errorf("rowText = %q, want the plain text", got)
+	}
+}
+
+func TestCutAndMovePaste(t *testing.T) {
+	tr := buildSampleTree()
+	b := newTestBrowser(tr, "")
+
+	b.opMovePaste()
+	if b.message != "Nothing cut (press x on a node first)" {
+		t.Errorf("message = %q", b.message)
+	}
+
+	b.cursor = 3 // n4
+	b.opCut()
+	if b.cut != "n4" {
+		t.Fatalf("cut = %q", b.cut)
+	}
+	b.cursor = 2 // n3
+	b.opMovePaste()
+	if b.cut != "" || b.selectedNodeID() != "n4" {
+		t.Errorf("after paste: cut %q, selected %s", b.cut, b.selectedNodeID())
+	}
+	e := tr.GetEdge("n3", "n4")
+	if e == nil || e.Label != "no" || tr.HasEdge("n2", "n4") || len(tr.Nodes) != 4 {
+		t.Fatalf("edges after move: %+v", tr.Edges)
+	}
+
+	b.opUndo()
+	if !tr.HasEdge("n2", "n4") || tr.HasEdge("n3", "n4") {
+		t.Errorf("undo should restore n2 -> n4: %+v", tr.Edges)
+	}
+
+	// Moving a node under its own subtree fails and keeps the cut.
+	b.cursor = 1 // n2
+	b.opCut()
+	b.cursor = 2 // n3
+	b.opMovePaste()
+	if b.cut != "n2" || !strings.HasPrefix(b.message, "Error: cannot move n2 under n3") {
+		t.Errorf("cut %q, message %q", b.cut, b.message)
+	}
+	b.cursor = 1
+	b.opCut()
+	if b.cut != "" || b.message != "Cut cleared" {
+		t.Errorf("second cut: cut %q, message %q", b.cut, b.message)
+	}
+}
+
+func TestOpAddChildUndoesInOneStep(t *testing.T) {
+	tr := buildSampleTree()
+	b := newTestBrowser(tr, "action\rRetry\rlater\r")
+	b.cursor = 3 // n4
+	b.opAddChild()
+	if e := tr.GetEdge("n4", "n5"); e == nil || e.Label != "later" {
+		t.Fatalf("child not connected: %+v", tr.Edges)
+	}
+	b.opUndo()
+	if tr.GetNode("n5") != nil || tr.HasEdge("n4", "n5") {
+		t.Errorf("one undo should remove the child and its edge, message %q", b.message)
+	}
+	b.opRedo()
+	if !tr.HasEdge("n4", "n5") {
+		t.Error("redo should restore the child and its edge")
+	}
+}
+
+func TestOpPasteUndoesInOneStep(t *testing.T) {
+	tr := buildSampleTree()
+	b := newTestBrowser(tr, "")
+	b.cursor = 1 // n2
+	b.opCopy()
+	b.cursor = 3 // n4
+	b.opPaste()
+	if len(tr.Nodes) != 7 || !tr.HasEdge("n4", "n5") {
+		t.Fatalf("paste: %d nodes, edges %+v", len(tr.Nodes), tr.Edges)
+	}
+	b.opUndo()
+	if len(tr.Nodes) != 4 || len(tr.Edges) != 3 {
+		t.Errorf("one undo should remove the pasted nodes: %d nodes, %d edges", len(tr.Nodes), len(tr.Edges))
+	}
+}
@@ -1,15 +1,26 @@
 package cli
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/jllovet/decision-tree-cli/internal/analysis"
+	"github.com/jllovet/decision-tree-cli/internal/diff"
+	"github.com/jllovet/decision-tree-cli/internal/importer"
+	"github.com/jllovet/decision-tree-cli/internal/learn"
 	"github.com/jllovet/decision-tree-cli/internal/model"
 	"github.com/jllovet/decision-tree-cli/internal/preview"
 	"github.com/jllovet/decision-tree-cli/internal/render"
 	"github.com/jllovet/decision-tree-cli/internal/storage"
+	"github.com/jllovet/decision-tree-cli/internal/terminal"
 	"github.com/jllovet/decision-tree-cli/internal/tree"
 )
 
@@ -20,6 +31,10 @@ type Session struct {
 	Clipboard *tree.Clipboard
 	In        io.Reader
 	Out       io.Writer
+	Err       io.Writer // where Execute reports failed commands; nil means Out
+
+	lines       *terminal.LineReader // shared with the REPL so buffered input isn't lost
+	sourceDepth int                  // nesting of scripts being run by source
 }
 
 // NewSession creates a new CLI session with an empty tree.
@@ -31,84 +46,165 @@ func NewSession(w io.Writer) *Session {
 	}
 }
 
-// Execute dispatches a parsed command to the appropriate handler.
+// ErrQuit is returned by Run for the quit and exit commands.
+var ErrQuit = errors.New("quit")
+
+// UsageError reports a command that was invoked incorrectly, such as with
+// missing arguments or an unknown name. Its message is shown as-is, without
+// the "Error: " prefix.
+type UsageError struct {
+	Msg string
+}
+
+func (e *UsageError) Error() string {
+	return e.Msg
+}
+
+// usage returns a UsageError whose message is the given lines.
+func usage(lines ...string) error {
+	return &UsageError{Msg: strings.Join(lines, "\n")}
+}
+
+// usagef returns a UsageError with a formatted message.
+func usagef(format string, args ...any) error {
+	return &UsageError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// formatError renders a command error the way the REPL shows it.
+func formatError(err error) string {
+	var ue *UsageError
+	if errors.As(err, &ue) {
+		return ue.Msg
+	}
+	return "Error: " + err.Error()
+}
+
+// Execute runs a command and reports any failure to s.Err (or s.Out).
 // Returns true if the session should continue, false to quit.
 func (s *Session) Execute(cmd ParsedCommand) bool {
+	err := s.Run(cmd)
+	if errors.Is(err, ErrQuit) {
+		return false
+	}
+	if err != nil {
+		fmt.Fprintln(s.errOut(), formatError(err))
+	}
+	return true
+}
+
+func (s *Session) errOut() io.Writer {
+	if s.Err != nil {
+		return s.Err
+	}
+	return s.Out
+}
+
+// Run dispatches a parsed command to the appropriate handler. Output is
+// written to s.Out; a failed command returns an error instead of printing
+// it. quit and exit return ErrQuit.
+func (s *Session) Run(cmd ParsedCommand) error {
 	switch cmd.Name {
 	case "":
-		return true
+		return nil
 	case "add":
-		s.cmdAdd(cmd.Args)
+		return s.cmdAdd(cmd.Args)
 	case "connect":
-		s.cmdConnect(cmd.Args)
+		return s.cmdConnect(cmd.Args)
 	case "disconnect":
-		s.cmdDisconnect(cmd.Args)
+		return s.cmdDisconnect(cmd.Args)
+	case "move":
+		return s.cmdMove(cmd.Args)
 	case "remove":
-		s.cmdRemove(cmd.Args)
+		return s.cmdRemove(cmd.Args)
 	case "edit":
-		s.cmdEdit(cmd.Args)
+		return s.cmdEdit(cmd.Args)
 	case "set-root":
-		s.cmdSetRoot(cmd.Args)
+		return s.cmdSetRoot(cmd.Args)
+	case "mode":
+		return s.cmdMode(cmd.Args)
+	case "meta":
+		return s.cmdMeta(cmd.Args)
+	case "analyze":
+		return s.cmdAnalyze(cmd.Args)
+	case "lint":
+		return s.cmdLint(cmd.Args)
+	case "paths":
+		return s.cmdPaths(cmd.Args)
+	case "stats":
+		return s.cmdStats(cmd.Args)
+	case "diff":
+		return s.cmdDiff(cmd.Args)
 	case "list":
-		s.cmdList()
+		return s.cmdList()
 	case "preview":
-		s.cmdPreview()
+		return s.cmdPreview()
 	case "render":
-		s.cmdRender(cmd.Args)
+		return s.cmdRender(cmd.Args)
 	case "copy":
-		s.cmdCopy(cmd.Args)
+		return s.cmdCopy(cmd.Args)
 	case "paste":
-		s.cmdPaste()
+		return s.cmdPaste()
 	case "save":
-		s.cmdSave(cmd.Args)
+		return s.cmdSave(cmd.Args)
 	case "load":
-		s.cmdLoad(cmd.Args)
+		return s.cmdLoad(cmd.Args)
+	case "learn":
+		return s.cmdLearn(cmd.Args)
+	case "eval":
+		return s.cmdEval(cmd.Args)
+	case "source":
+		return s.cmdSource(cmd.Args)
 	case "init":
-		s.cmdInit(cmd.Args)
+		return s.cmdInit(cmd.Args)
 	case "browse":
-		s.cmdBrowse()
+		return s.cmdBrowse()
+	case "walk":
+		return s.cmdWalk()
 	case "undo":
-		s.cmdUndo()
+		return s.cmdUndo()
 	case "redo":
-		s.cmdRedo()
+		return s.cmdRedo()
+	case "begin":
+		return s.cmdBegin()
+	case "commit":
+		return s.cmdCommit()
+	case "rollback":
+		return s.cmdRollback()
+	case "history":
+		return s.cmdHistory()
 	case "help":
-		s.cmdHelp()
+		return s.cmdHelp()
 	case "quit", "exit":
-		return false
+		return ErrQuit
 	default:
-		fmt.Fprintf(s.Out, "Unknown command: %s (type 'help' for commands)\n", cmd.Name)
+		return usagef("Unknown command: %s (type 'help' for commands)", cmd.Name)
 	}
-	return true
 }
 
-func (s *Session) cmdAdd(args []string) {
+func (s *Session) cmdAdd(args []string) error {
 	if len(args) < 2 {
-		fmt.Fprintln(s.Out, "Usage: add <type> <label>")
-		fmt.Fprintln(s.Out, "Types: decision, action, startend, io")
-		return
+		return usage("Usage: add <type> <label>", "Types: decision, action, startend, io")
 	}
 	nodeType, err := model.ParseNodeType(args[0])
 	if err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	label := strings.Join(args[1:], " ")
 	cmd := tree.NewAddNodeCmd(nodeType, label)
 	if err := s.History.Execute(s.Tree, cmd); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	// Get the ID from the command
 	type idGetter interface{ ID() string }
 	if ig, ok := cmd.(idGetter); ok {
 		fmt.Fprintf(s.Out, "Added node %s\n", ig.ID())
 	}
+	return nil
 }
 
-func (s *Session) cmdConnect(args []string) {
+func (s *Session) cmdConnect(args []string) error {
 	if len(args) < 2 {
-		fmt.Fprintln(s.Out, "Usage: connect <from> <to> [label]")
-		return
+		return usage("Usage: connect <from> <to> [label]")
 	}
 	label := ""
 	if len(args) >= 3 {
@@ -116,264 +212,742 @@ func (s *Session) cmdConnect(args []string) {
 	}
 	cmd := tree.NewConnectCmd(args[0], args[1], label)
 	if err := s.History.Execute(s.Tree, cmd); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	fmt.Fprintf(s.Out, "Connected %s -> %s\n", args[0], args[1])
+	return nil
 }
 
-func (s *Session) cmdDisconnect(args []string) {
+func (s *Session) cmdDisconnect(args []string) error {
 	if len(args) < 2 {
-		fmt.Fprintln(s.Out, "Usage: disconnect <from> <to>")
-		return
+		return usage("Usage: disconnect <from> <to>")
 	}
 	cmd := tree.NewDisconnectCmd(args[0], args[1])
 	if err := s.History.Execute(s.Tree, cmd); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	fmt.Fprintf(s.Out, "Disconnected %s -> %s\n", args[0], args[1])
+	return nil
 }
 
-func (s *Session) cmdRemove(args []string) {
+func (s *Session) cmdMove(args []string) error {
+	if len(args) < 2 {
+		return usage("Usage: move <node-id> <new-parent-id> [edge-label]")
+	}
+	var label *string
+	if len(args) >= 3 {
+		l := strings.Join(args[2:], " ")
+		label = &l
+	}
+	cmd := tree.NewMoveCmd(args[0], args[1], label)
+	if err := s.History.Execute(s.Tree, cmd); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Moved %s under %s\n", args[0], args[1])
+	return nil
+}
+
+func (s *Session) cmdRemove(args []string) error {
 	if len(args) < 1 {
-		fmt.Fprintln(s.Out, "Usage: remove <node-id>")
-		return
+		return usage("Usage: remove <node-id>")
 	}
 	cmd := tree.NewRemoveNodeCmd(args[0])
 	if err := s.History.Execute(s.Tree, cmd); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	fmt.Fprintf(s.Out, "Removed node %s\n", args[0])
+	return nil
 }
 
-func (s *Session) cmdEdit(args []string) {
+func (s *Session) cmdEdit(args []string) error {
 	if len(args) < 3 {
-		fmt.Fprintln(s.Out, "Usage: edit <node-id> label <new-label>")
-		fmt.Fprintln(s.Out, "       edit <node-id> type <new-type>")
-		return
+		return usage(
+			"Usage: edit <node-id> label <new-label>",
+			"       edit <node-id> type <new-type>",
+			"       edit <node-id> payoff <number|none>",
+			"       edit <node-id> condition <expression|none>",
+			"       edit <from->to> label <new-label>",
+			"       edit <from->to> prob <probability|none>",
+			"       edit <from->to> outcome <value|none>",
+		)
 	}
 	id := args[0]
 	field := strings.ToLower(args[1])
 	value := strings.Join(args[2:], " ")
 
+	if fromID, toID, ok := parseEdgeRef(id); ok {
+		return s.editEdge(fromID, toID, field, value)
+	}
+
+	var cmd tree.Command
 	switch field {
 	case "label":
-		cmd := tree.NewEditLabelCmd(id, value)
-		if err := s.History.Execute(s.Tree, cmd); err != nil {
-			fmt.Fprintf(s.Out, "Error: %v\n", err)
-			return
-		}
-		fmt.Fprintf(s.Out, "Updated %s label\n", id)
+		cmd = tree.NewEditLabelCmd(id, value)
 	case "type":
 		nt, err := model.ParseNodeType(value)
 		if err != nil {
-			fmt.Fprintf(s.Out, "Error: %v\n", err)
-			return
+			return err
+		}
+		cmd = tree.NewEditTypeCmd(id, nt)
+	case "payoff":
+		payoff, err := parseOptionalFloat(value, false)
+		if err != nil {
+			return err
 		}
-		cmd := tree.NewEditTypeCmd(id, nt)
-		if err := s.History.Execute(s.Tree, cmd); err != nil {
-			fmt.Fprintf(s.Out, "Error: %v\n", err)
-			return
+		cmd = tree.NewSetPayoffCmd(id, payoff)
+	case "condition", "cond":
+		field = "condition"
+		cmd = tree.NewSetConditionCmd(id, noneToEmpty(value))
+	default:
+		return usagef("Unknown field %q (use 'label', 'type', 'payoff' or 'condition')", field)
+	}
+	if err := s.History.Execute(s.Tree, cmd); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Updated %s %s\n", id, field)
+	return nil
+}
+
+func (s *Session) editEdge(fromID, toID, field, value string) error {
+	var cmd tree.Command
+	switch field {
+	case "label":
+		cmd = tree.NewEditEdgeLabelCmd(fromID, toID, value)
+	case "prob", "probability":
+		p, err := parseOptionalFloat(value, true)
+		if err != nil {
+			return err
 		}
-		fmt.Fprintf(s.Out, "Updated %s type\n", id)
+		cmd = tree.NewSetProbabilityCmd(fromID, toID, p)
+	case "outcome":
+		cmd = tree.NewSetOutcomeCmd(fromID, toID, noneToEmpty(value))
 	default:
-		fmt.Fprintf(s.Out, "Unknown field %q (use 'label' or 'type')\n", field)
+		return usagef("Unknown edge field %q (use 'label', 'prob' or 'outcome')", field)
+	}
+	if err := s.History.Execute(s.Tree, cmd); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Updated %s->%s %s\n", fromID, toID, field)
+	return nil
+}
+
+// parseOptionalFloat parses a number, or "none" to clear a value. When
+// percent is true a trailing "%" divides the number by 100. NaN and the
+// infinities are refused: they poison expected values and JSON cannot
+// store them.
+func parseOptionalFloat(value string, percent bool) (*float64, error) {
+	if strings.EqualFold(value, "none") {
+		return nil, nil
+	}
+	scale := 1.0
+	if percent && strings.HasSuffix(value, "%") {
+		value = strings.TrimSuffix(value, "%")
+		scale = 100
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	v /= scale
+	return &v, nil
+}
+
+// plural formats a count with its noun, adding "s" unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// noneToEmpty maps the "none" placeholder used to clear a field to "".
+func noneToEmpty(value string) string {
+	if strings.EqualFold(value, "none") {
+		return ""
 	}
+	return value
 }
 
-func (s *Session) cmdSetRoot(args []string) {
+func (s *Session) cmdSetRoot(args []string) error {
 	if len(args) < 1 {
-		fmt.Fprintln(s.Out, "Usage: set-root <node-id>")
-		return
+		return usage("Usage: set-root <node-id>")
 	}
 	cmd := tree.NewSetRootCmd(args[0])
 	if err := s.History.Execute(s.Tree, cmd); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	fmt.Fprintf(s.Out, "Root set to %s\n", args[0])
+	return nil
 }
 
-func (s *Session) cmdList() {
+func (s *Session) cmdMode(args []string) error {
+	if len(args) < 1 {
+		fmt.Fprintf(s.Out, "Mode: %s\n", modeName(s.Tree.Graph))
+		fmt.Fprintln(s.Out, "Usage: mode <tree|graph>")
+		return nil
+	}
+	var graph bool
+	switch strings.ToLower(args[0]) {
+	case "tree":
+		graph = false
+	case "graph":
+		graph = true
+	default:
+		return usagef("Unknown mode: %s (use 'tree' or 'graph')", args[0])
+	}
+	cmd := tree.NewSetModeCmd(graph)
+	if err := s.History.Execute(s.Tree, cmd); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Mode set to %s\n", modeName(graph))
+	return nil
+}
+
+func modeName(graph bool) string {
+	if graph {
+		return "graph"
+	}
+	return "tree"
+}
+
+func (s *Session) cmdMeta(args []string) error {
+	if len(args) != 1 && !(len(args) >= 4 && args[1] == "set") && !(len(args) == 3 && args[1] == "rm") {
+		return usage(
+			"Usage: meta <id|from->to>",
+			"       meta <id|from->to> set <key> <value>",
+			"       meta <id|from->to> rm <key>",
+		)
+	}
+	ref := args[0]
+	fromID, toID, isEdge := parseEdgeRef(ref)
+
+	if len(args) == 1 {
+		var attrs map[string]string
+		if isEdge {
+			e := s.Tree.GetEdge(fromID, toID)
+			if e == nil {
+				return fmt.Errorf("no edge from %s to %s", fromID, toID)
+			}
+			attrs = e.Attrs
+		} else {
+			n := s.Tree.GetNode(ref)
+			if n == nil {
+				return fmt.Errorf("node %q not found", ref)
+			}
+			attrs = n.Attrs
+		}
+		if len(attrs) == 0 {
+			fmt.Fprintf(s.Out, "(no attributes on %s)\n", ref)
+			return nil
+		}
+		for _, k := range model.SortedAttrKeys(attrs) {
+			fmt.Fprintf(s.Out, "%s = %s\n", k, attrs[k])
+		}
+		return nil
+	}
+
+	key := args[2]
+	var cmd tree.Command
+	switch {
+	case args[1] == "set" && isEdge:
+		cmd = tree.NewSetEdgeAttrCmd(fromID, toID, key, strings.Join(args[3:], " "))
+	case args[1] == "set":
+		cmd = tree.NewSetNodeAttrCmd(ref, key, strings.Join(args[3:], " "))
+	case isEdge:
+		cmd = tree.NewRemoveEdgeAttrCmd(fromID, toID, key)
+	default:
+		cmd = tree.NewRemoveNodeAttrCmd(ref, key)
+	}
+	if err := s.History.Execute(s.Tree, cmd); err != nil {
+		return err
+	}
+	if args[1] == "set" {
+		fmt.Fprintf(s.Out, "Set %s on %s\n", key, ref)
+	} else {
+		fmt.Fprintf(s.Out, "Removed %s from %s\n", key, ref)
+	}
+	return nil
+}
+
+func (s *Session) cmdList() error {
 	lines := tree.ListNodes(s.Tree)
 	if len(lines) == 0 {
 		fmt.Fprintln(s.Out, "(no nodes)")
-		return
+		return nil
 	}
 	for _, line := range lines {
 		fmt.Fprintln(s.Out, line)
 	}
+	return nil
 }
 
-func (s *Session) cmdPreview() {
+func (s *Session) cmdPreview() error {
 	fmt.Fprintln(s.Out, preview.Render(s.Tree))
+	return nil
 }
 
-func (s *Session) cmdRender(args []string) {
+func (s *Session) cmdRender(args []string) error {
+	args, flags := splitFlags(args)
 	if len(args) < 1 {
-		fmt.Fprintln(s.Out, "Usage: render <dot|mermaid> [filename]")
-		return
+		return usage("Usage: render <dot|mermaid|svg|html|plantuml|d2> [filename] [--attrs key,...] [--direction TB|LR|BT|RL]\n" +
+			"       [--theme type=color,...] [--subtree node-id] [--depth n] [--highlight id,id,...]")
 	}
-	var r render.Renderer
-	switch strings.ToLower(args[0]) {
-	case "dot":
-		r = &render.DOTRenderer{}
-	case "mermaid":
-		r = &render.MermaidRenderer{}
-	default:
-		fmt.Fprintf(s.Out, "Unknown format: %s (use 'dot' or 'mermaid')\n", args[0])
-		return
+	var tooltipAttrs []string
+	if flags["attrs"] != "" {
+		tooltipAttrs = strings.Split(flags["attrs"], ",")
+	}
+	opts, err := renderOptions(flags)
+	if err != nil {
+		return usage(err.Error())
+	}
+	r := newRenderer(args[0], tooltipAttrs, opts)
+	if r == nil {
+		return usagef("Unknown format: %s (use 'dot', 'mermaid', 'svg', 'html', 'plantuml' or 'd2')", args[0])
 	}
 	out, err := r.Render(s.Tree)
 	if err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	if len(args) >= 2 {
 		if err := os.WriteFile(args[1], []byte(out), 0644); err != nil {
-			fmt.Fprintf(s.Out, "Error: %v\n", err)
-			return
+			return err
 		}
 		fmt.Fprintf(s.Out, "Wrote %s to %s\n", args[0], args[1])
-		return
+		return nil
 	}
 	fmt.Fprint(s.Out, out)
+	return nil
 }
 
-func (s *Session) cmdCopy(args []string) {
+// newRenderer returns the renderer for a format name, or nil if the format
+// is unknown.
+func newRenderer(format string, tooltipAttrs []string, opts render.Options) render.Renderer {
+	switch strings.ToLower(format) {
+	case "dot":
+		return &render.DOTRenderer{TooltipAttrs: tooltipAttrs, Options: opts}
+	case "mermaid":
+		return &render.MermaidRenderer{TooltipAttrs: tooltipAttrs, Options: opts}
+	case "svg":
+		return &render.SVGRenderer{TooltipAttrs: tooltipAttrs, Options: opts}
+	case "html":
+		return &render.HTMLRenderer{TooltipAttrs: tooltipAttrs, Options: opts}
+	case "plantuml", "puml":
+		return &render.PlantUMLRenderer{TooltipAttrs: tooltipAttrs, Options: opts}
+	case "d2":
+		return &render.D2Renderer{TooltipAttrs: tooltipAttrs, Options: opts}
+	}
+	return nil
+}
+
+// renderOptions reads the layout and styling flags shared by the shell's
+// render command and dt render.
+func renderOptions(flags map[string]string) (render.Options, error) {
+	var opts render.Options
+	var err error
+	if opts.Direction, err = render.ParseDirection(flags["direction"]); err != nil {
+		return opts, err
+	}
+	if flags["theme"] != "" {
+		if opts.Theme, err = render.ParseTheme(flags["theme"]); err != nil {
+			return opts, err
+		}
+	}
+	if flags["depth"] != "" {
+		if opts.Depth, err = render.ParseDepth(flags["depth"]); err != nil {
+			return opts, err
+		}
+	}
+	opts.Subtree = flags["subtree"]
+	if flags["highlight"] != "" {
+		opts.Highlight = strings.Split(flags["highlight"], ",")
+	}
+	return opts, nil
+}
+
+func (s *Session) cmdAnalyze(args []string) error {
+	if len(args) < 1 || strings.ToLower(args[0]) != "ev" {
+		return usage("Usage: analyze ev")
+	}
+	res, err := analysis.ExpectedValue(s.Tree)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(s.Out, analysis.FormatEV(s.Tree, res))
+	return nil
+}
+
+func (s *Session) cmdPaths(args []string) error {
+	args, flags := splitFlags(args)
+	format := strings.ToLower(flags["format"])
+	if format == "" {
+		format = "text"
+	}
+	if len(args) > 1 || !slices.Contains(analysis.PathFormats(), format) {
+		return usage("Usage: paths [--format " + strings.Join(analysis.PathFormats(), "|") + "] [filename]")
+	}
+	paths, err := analysis.Paths(s.Tree)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return analysis.WritePaths(s.Out, s.Tree, paths, format)
+	}
+	var buf bytes.Buffer
+	if err := analysis.WritePaths(&buf, s.Tree, paths, format); err != nil {
+		return err
+	}
+	if err := os.WriteFile(args[0], buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Wrote %d paths to %s\n", len(paths), args[0])
+	return nil
+}
+
+func (s *Session) cmdStats(args []string) error {
+	args, flags := splitFlags(args)
+	format := strings.ToLower(flags["format"])
+	if len(args) > 0 || (format != "" && format != "text" && format != "json") {
+		return usage("Usage: stats [--format text|json]")
+	}
+	st, err := analysis.ComputeStats(s.Tree)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		return writeJSON(s.Out, st)
+	}
+	fmt.Fprint(s.Out, analysis.FormatStats(st))
+	return nil
+}
+
+// cmdDiff shows how the tree has changed since it was saved to a file, or
+// how it differs from any other tree file.
+func (s *Session) cmdDiff(args []string) error {
+	args, flags := splitFlags(args)
+	format := strings.ToLower(flags["format"])
+	if format == "" {
+		format = "text"
+	}
+	if len(args) != 1 || !slices.Contains(diff.Formats(), format) {
+		return usage("Usage: diff <filename> [--format " + strings.Join(diff.Formats(), "|") + "]")
+	}
+	old, err := loadTree(args[0], "")
+	if err != nil {
+		return err
+	}
+	return diff.Write(s.Out, old, s.Tree, diff.Compare(old, s.Tree), format)
+}
+
+func (s *Session) cmdCopy(args []string) error {
 	if len(args) < 1 {
-		fmt.Fprintln(s.Out, "Usage: copy <node-id>")
-		return
+		return usage("Usage: copy <node-id>")
 	}
 	cb, err := tree.CopySubtree(s.Tree, args[0])
 	if err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	s.Clipboard = cb
 	fmt.Fprintf(s.Out, "Copied subtree from %s (%d nodes)\n", args[0], len(cb.Nodes))
+	return nil
 }
 
-func (s *Session) cmdPaste() {
+func (s *Session) cmdPaste() error {
 	if s.Clipboard == nil {
-		fmt.Fprintln(s.Out, "Clipboard is empty")
-		return
+		return usage("Clipboard is empty")
 	}
 	cmd := tree.NewPasteSubtreeCmd(s.Clipboard)
 	if err := s.History.Execute(s.Tree, cmd); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	type pastedIDsGetter interface{ PastedIDs() map[string]string }
 	if pg, ok := cmd.(pastedIDsGetter); ok {
 		idMap := pg.PastedIDs()
 		fmt.Fprintf(s.Out, "Pasted %d nodes (root: %s -> %s)\n", len(idMap), s.Clipboard.Root, idMap[s.Clipboard.Root])
 	}
+	return nil
 }
 
-func (s *Session) cmdSave(args []string) {
+func (s *Session) cmdSave(args []string) error {
 	if len(args) < 1 {
-		fmt.Fprintln(s.Out, "Usage: save <filename>")
-		return
+		return usage("Usage: save <filename>")
+	}
+	if s.History.InTransaction() {
+		return fmt.Errorf("cannot save with a transaction open (commit or rollback it first)")
 	}
 	if err := storage.Save(s.Tree, args[0]); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
+	}
+	if err := storage.SaveHistory(s.History, args[0]); err != nil {
+		return fmt.Errorf("saved %s, but not its undo history: %w", args[0], err)
 	}
 	fmt.Fprintf(s.Out, "Saved to %s\n", args[0])
+	return nil
 }
 
-func (s *Session) cmdLoad(args []string) {
+func (s *Session) cmdLoad(args []string) error {
+	args, flags := splitFlags(args)
 	if len(args) < 1 {
-		fmt.Fprintln(s.Out, "Usage: load <filename>")
-		return
+		return usage("Usage: load <filename> [--format " + strings.Join(importer.Formats(), "|") + "]")
 	}
-	loaded, err := storage.Load(args[0])
+	loaded, err := loadTree(args[0], flags["format"])
 	if err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	s.Tree = loaded
 	s.History = tree.NewHistory()
 	s.Clipboard = nil
 	fmt.Fprintf(s.Out, "Loaded %q (%d nodes)\n", loaded.Name, len(loaded.Nodes))
+	if flags["format"] == "" && importer.FormatForPath(args[0]) == "" {
+		s.loadHistory(args[0])
+	}
+	return nil
 }
 
-func (s *Session) cmdUndo() {
+// loadHistory restores the undo history saved next to a tree file. A
+// missing, stale or unreadable journal only costs the history, so it is
+// reported without failing the load.
+func (s *Session) loadHistory(path string) {
+	h, err := storage.LoadHistory(path)
+	switch {
+	case errors.Is(err, storage.ErrStaleHistory):
+		fmt.Fprintf(s.Out, "Ignoring %s: the file was changed without it\n", storage.HistoryPath(path))
+	case err != nil:
+		fmt.Fprintf(s.Out, "Ignoring %s: %v\n", storage.HistoryPath(path), err)
+	case h != nil:
+		s.History = h
+		entries, undone := h.Entries()
+		fmt.Fprintf(s.Out, "Restored undo history (%s, %d undone)\n", plural(len(entries)-undone, "step"), undone)
+	}
+}
+
+// loadTree reads a tree file. Files in an import format, named by format
+// or recognized by their extension, go through the importer; anything else
+// is a saved tree.
+func loadTree(path, format string) (*model.Tree, error) {
+	if format == "" {
+		format = importer.FormatForPath(path)
+	}
+	if format == "" {
+		return storage.Load(path)
+	}
+	if !slices.Contains(importer.Formats(), format) {
+		return nil, usagef("Unknown format: %s (use %s)", format, strings.Join(importer.Formats(), " or "))
+	}
+	return importer.ImportFile(path, format)
+}
+
+func (s *Session) cmdLearn(args []string) error {
+	args, flags := splitFlags(args)
+	if len(args) < 1 || flags["target"] == "" {
+		return usage("Usage: learn <file.csv> --target <column> [--criterion entropy|gini] [--max-depth n] [--min-samples-leaf n]")
+	}
+	opts := learn.Options{Target: flags["target"]}
+	var err error
+	if c, ok := flags["criterion"]; ok {
+		if opts.Criterion, err = learn.ParseCriterion(c); err != nil {
+			return err
+		}
+	}
+	if opts.MaxDepth, err = intFlag(flags, "max-depth"); err != nil {
+		return err
+	}
+	if opts.MinSamplesLeaf, err = intFlag(flags, "min-samples-leaf"); err != nil {
+		return err
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ds, err := learn.ReadCSV(f)
+	if err != nil {
+		return err
+	}
+	learned, err := learn.Learn(ds, opts)
+	if err != nil {
+		return err
+	}
+	learned.Name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+	s.Tree = learned
+	s.History = tree.NewHistory()
+	s.Clipboard = nil
+	fmt.Fprintf(s.Out, "Learned %q from %d rows (%d nodes, %s)\n", learned.Name, len(ds.Rows), len(learned.Nodes), opts.Criterion)
+	return nil
+}
+
+// intFlag parses a non-negative integer flag, returning 0 when it is absent.
+func intFlag(flags map[string]string, name string) (int, error) {
+	v, ok := flags[name]
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("--%s must be a non-negative integer, got %q", name, v)
+	}
+	return n, nil
+}
+
+func (s *Session) cmdUndo() error {
 	if err := s.History.Undo(s.Tree); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	fmt.Fprintln(s.Out, "Undone")
+	return nil
 }
 
-func (s *Session) cmdRedo() {
+func (s *Session) cmdRedo() error {
 	if err := s.History.Redo(s.Tree); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
-		return
+		return err
 	}
 	fmt.Fprintln(s.Out, "Redone")
+	return nil
 }
 
-func (s *Session) cmdInit(args []string) {
-	if len(args) == 0 {
-		fmt.Fprintln(s.Out, "Available templates:")
-		for i, tmpl := range templates {
-			fmt.Fprintf(s.Out, "  %d. %s — %s\n", i+1, tmpl.Name, tmpl.Description)
+func (s *Session) cmdBegin() error {
+	if err := s.History.Begin(); err != nil {
+		return err
+	}
+	fmt.Fprintln(s.Out, "Transaction started (commit keeps the changes as one undo step, rollback discards them)")
+	return nil
+}
+
+func (s *Session) cmdCommit() error {
+	n, err := s.History.Commit()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Committed %s as one undo step\n", plural(n, "change"))
+	return nil
+}
+
+func (s *Session) cmdRollback() error {
+	n, err := s.History.Rollback(s.Tree)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Rolled back %s\n", plural(n, "change"))
+	return nil
+}
+
+// cmdHistory lists the changes that can be undone, oldest first, then those
+// that have been undone and can be redone.
+func (s *Session) cmdHistory() error {
+	entries, undone := s.History.Entries()
+	if len(entries) == 0 {
+		fmt.Fprintln(s.Out, "No history")
+		return nil
+	}
+	for i, e := range entries {
+		line := fmt.Sprintf("%3d  %s  %s", i+1, e.Time.Local().Format("2006-01-02 15:04:05"), tree.Describe(e.Cmd))
+		if i >= len(entries)-undone {
+			line += "  (undone)"
 		}
+		fmt.Fprintln(s.Out, line)
+	}
+	if s.History.InTransaction() {
+		fmt.Fprintln(s.Out, "     (transaction open: its changes are listed after commit)")
+	}
+	return nil
+}
+
+func (s *Session) cmdInit(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(s.Out, templateList())
 		fmt.Fprintln(s.Out, "Usage: init <template-name>")
-		return
+		return nil
 	}
 	tmpl := findTemplate(args[0])
 	if tmpl == nil {
-		fmt.Fprintf(s.Out, "Unknown template: %s\n", args[0])
-		fmt.Fprintln(s.Out, "Available templates:")
-		for i, t := range templates {
-			fmt.Fprintf(s.Out, "  %d. %s — %s\n", i+1, t.Name, t.Description)
-		}
-		return
+		return usage("Unknown template: "+args[0], templateList())
 	}
 	s.Tree = tmpl.Build()
 	s.History = tree.NewHistory()
 	s.Clipboard = nil
 	fmt.Fprintf(s.Out, "Initialized tree from template %q (%d nodes)\n", tmpl.Name, len(s.Tree.Nodes))
+	return nil
 }
 
-func (s *Session) cmdBrowse() {
+// templateList describes the available templates, one per line.
+func templateList() string {
+	lines := []string{"Available templates:"}
+	for i, t := range templates {
+		lines = append(lines, fmt.Sprintf("  %d. %s — %s", i+1, t.Name, t.Description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *Session) cmdBrowse() error {
 	if s.In == nil {
-		fmt.Fprintln(s.Out, "Error: browse requires an interactive terminal")
-		return
+		return errors.New("browse requires an interactive terminal")
 	}
 	b := newBrowser(s, s.In, s.Out)
-	if err := b.run(); err != nil {
-		fmt.Fprintf(s.Out, "Error: %v\n", err)
+	return b.run()
+}
+
+// readLine reads one line of input for interactive commands, reusing the
+// REPL's line reader when there is one.
+func (s *Session) readLine(prompt string) (string, error) {
+	if s.lines == nil {
+		s.lines = terminal.NewLineReader(s.In, s.Out)
 	}
+	return s.lines.ReadLine(prompt)
 }
 
-func (s *Session) cmdHelp() {
+func (s *Session) cmdHelp() error {
 	help := `Commands:
   add <type> <label>         Add a node (types: decision, action, startend, io)
   connect <from> <to> [label] Connect two nodes with an optional edge label
   disconnect <from> <to>     Remove edge between two nodes
+  move <id> <parent> [label] Move a node and its subtree under a new parent
   remove <node-id>           Remove a node and its edges
   edit <id> label <text>     Edit a node's label
   edit <id> type <type>      Edit a node's type
+  edit <id> payoff <n|none>  Set a node's payoff (negative for a cost)
+  edit <id> condition <expr> Set the expression a node routes records on
+  edit <from->to> label <t>  Edit an edge's label
+  edit <from->to> prob <p>   Set an edge's probability (0.3 or 30%, none clears)
+  edit <from->to> outcome <v> Set the condition value that selects an edge
   set-root <node-id>         Set the root node
+  mode <tree|graph>          Allow multiple parents and loop-back edges (graph)
   list                       List all nodes
+  analyze ev                 Expected value at every node and best choices
+  paths [--format f] [file]  List every root-to-leaf path (text, csv, json or gherkin)
+  stats [--format text|json] Count nodes, paths and branches and measure complexity
+  diff <file> [--format f]   Show changes from a saved tree to this one (text, json, dot, mermaid)
+  lint [--format text|json]  Check for dead-end decisions, unreachable nodes and more
+       [--disable rule,...]    Skip rules (lint --rules lists them)
+  lint --fix [--dry-run]     Repair what lint can as one undo step (--dry-run shows a diff)
+  eval <records.jsonl> [out] Route JSON records through the tree's conditions
   preview                    Show ASCII tree preview
   init [name]                Initialize tree from a template
   browse                     Interactive tree browser
-  render <dot|mermaid> [file] Render as DOT or Mermaid (optionally to file)
+  walk                       Step through the tree from the root as a questionnaire
+  render <format> [file]     Render as dot, mermaid, svg, html, plantuml or d2 (optionally to file)
+         [--attrs key,...]     Pass metadata through as tooltips
+         [--direction TB|LR|BT|RL] [--theme type=color,...]
+         [--subtree id] [--depth n] [--highlight id,id,...]
+  meta <id|from->to>         List metadata attributes of a node or edge
+  meta <ref> set <key> <val> Set a metadata attribute
+  meta <ref> rm <key>        Remove a metadata attribute
   copy <node-id>             Copy a subtree to clipboard
   paste                      Paste clipboard contents
-  save <filename>            Save tree to JSON file
-  load <filename>            Load tree from JSON file
+  save <filename>            Save tree to a file (.dtree text, otherwise JSON)
+  load <filename>            Load a JSON or .dtree file
+       [--format mermaid|dot]  Import a diagram (inferred from .mmd, .dot)
+  source <file> [--continue] Run commands from a file (# starts a comment)
+  learn <csv> --target <col> Learn a classification tree from a CSV file
+        [--criterion entropy|gini] [--max-depth n] [--min-samples-leaf n]
   undo                       Undo last action
   redo                       Redo last undone action
+  begin                      Start a transaction: later edits undo as one step
+  commit                     End the transaction, keeping its edits
+  rollback                   End the transaction, undoing its edits
+  history                    List past changes with their times (saved with the tree)
   help                       Show this help
   quit                       Exit the program
 `
 	fmt.Fprint(s.Out, help)
+	return nil
 }
@@ -2,7 +2,9 @@ package cli
 
 import (
 	"bytes"
+	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -78,6 +80,44 @@ func TestCmdDisconnect(t *testing.T) {
 	}
 }
 
+func TestCmdMove(t *testing.T) {
+	s, out := runCommands(t,
+		`add decision "q1"`,
+		`add decision "q2"`,
+		`add action "a1"`,
+		`connect n1 n2 no`,
+		`connect n1 n3 yes`,
+		`move n3 n2`,
+		`move n2 n3`,
+		`undo`,
+	)
+	if !strings.Contains(out, "Moved n3 under n2") {
+		t.Errorf("output = %q", out)
+	}
+	if !strings.Contains(out, "cannot move n2 under n3: n3 is in its subtree") {
+		t.Errorf("expected cycle error, got %q", out)
+	}
+	if e := s.Tree.GetEdge("n1", "n3"); e == nil || e.Label != "yes" {
+		t.Errorf("undo should restore n1 -> n3, edges = %+v", s.Tree.Edges)
+	}
+
+	s, _ = runCommands(t,
+		`add decision "q1"`,
+		`add action "a1"`,
+		`move n2 n1 "on retry"`,
+	)
+	if e := s.Tree.GetEdge("n1", "n2"); e == nil || e.Label != "on retry" {
+		t.Errorf("edge = %+v", e)
+	}
+}
+
+func TestCmdMoveUsage(t *testing.T) {
+	_, out := runCommands(t, "move n1")
+	if !strings.Contains(out, "Usage: move") {
+		t.Errorf("expected usage, got %q", out)
+	}
+}
+
 func TestCmdRemove(t *testing.T) {
 	s, out := runCommands(t,
 		`add decision "q1"`,
@@ -117,6 +157,182 @@ func TestCmdEditType(t *testing.T) {
 	}
 }
 
+func TestCmdEditEdge(t *testing.T) {
+	s, out := runCommands(t,
+		`add decision "q"`,
+		`add action "a"`,
+		`connect n1 n2 Yes`,
+		`edit n1->n2 label yes`,
+		`edit n1->n2 prob 30%`,
+		`edit n1->n2 color red`,
+		`edit n2->n1 label x`,
+	)
+	if !strings.Contains(out, "Updated n1->n2 label") || !strings.Contains(out, "Updated n1->n2 prob") {
+		t.Errorf("output = %q", out)
+	}
+	e := s.Tree.GetEdge("n1", "n2")
+	if e.Label != "yes" || e.Probability == nil || *e.Probability != 0.3 {
+		t.Errorf("edge = %+v", e)
+	}
+	if !strings.Contains(out, "Unknown edge field") || !strings.Contains(out, "no edge from n2 to n1") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestCmdEditPayoff(t *testing.T) {
+	s, out := runCommands(t,
+		`add startend "win"`,
+		`edit n1 payoff -12.5`,
+	)
+	if !strings.Contains(out, "Updated n1 payoff") {
+		t.Errorf("output = %q", out)
+	}
+	if p := s.Tree.GetNode("n1").Payoff; p == nil || *p != -12.5 {
+		t.Errorf("payoff = %v", p)
+	}
+	_, out = runCommands(t, `add startend "win"`, `edit n1 payoff lots`)
+	if !strings.Contains(out, `invalid number "lots"`) {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestCmdEditNonFinite(t *testing.T) {
+	for _, cmd := range []string{
+		`edit n1 payoff nan`,
+		`edit n1 payoff -Inf`,
+		`edit n1->n2 prob NaN`,
+		`edit n1->n2 prob inf%`,
+	} {
+		s, out := runCommands(t, `add decision "Q"`, `add startend "A"`, `connect n1 n2`, cmd)
+		if !strings.Contains(out, "invalid number") {
+			t.Errorf("%s: output = %q", cmd, out)
+		}
+		if s.Tree.GetNode("n1").Payoff != nil || s.Tree.GetEdge("n1", "n2").Probability != nil {
+			t.Errorf("%s: value was stored", cmd)
+		}
+	}
+}
+
+func TestCmdAnalyzeEV(t *testing.T) {
+	_, out := runCommands(t,
+		`add decision "Launch?"`,
+		`add decision "Market"`,
+		`add startend "Strong"`,
+		`add startend "Weak"`,
+		`add startend "Skip"`,
+		`connect n1 n2 yes`,
+		`connect n1 n5 no`,
+		`connect n2 n3 good`,
+		`connect n2 n4 bad`,
+		`set-root n1`,
+		`edit n3 payoff 100`,
+		`edit n4 payoff -50`,
+		`edit n2->n3 prob 0.5`,
+		`edit n2->n4 prob 0.5`,
+		`analyze ev`,
+	)
+	if !strings.Contains(out, "Expected value: 25.00") {
+		t.Errorf("missing expected value in %q", out)
+	}
+	if !strings.Contains(out, "best: yes") {
+		t.Errorf("missing best choice in %q", out)
+	}
+}
+
+func TestCmdAnalyzeErrors(t *testing.T) {
+	_, out := runCommands(t, "analyze", "analyze ev")
+	if !strings.Contains(out, "Usage: analyze ev") || !strings.Contains(out, "Error: no root set") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestCmdPaths(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "paths.feature")
+	_, out := runCommands(t,
+		`add decision "Auth?"`,
+		`add action "Grant"`,
+		`add io "Show login"`,
+		`connect n1 n2 yes`,
+		`connect n1 n3 no`,
+		`set-root n1`,
+		`paths`,
+		`paths --format gherkin `+file,
+		`paths --format xml`,
+	)
+	for _, want := range []string{
+		"1. Auth? -[yes]-> Grant\n2. Auth? -[no]-> Show login\n2 paths, max depth 1\n",
+		"Wrote 2 paths to " + file,
+		"Usage: paths [--format text|csv|json|gherkin] [filename]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil || !strings.Contains(string(data), "  Scenario: Path 2: Auth? no\n    Given Auth? is \"no\"\n    Then Show login\n") {
+		t.Errorf("feature file = %q, %v", data, err)
+	}
+
+	_, out = runCommands(t, "paths")
+	if !strings.Contains(out, "Error: no root set") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestCmdStats(t *testing.T) {
+	_, out := runCommands(t,
+		`add decision "Auth?"`,
+		`add action "Grant"`,
+		`add io "Show login"`,
+		`add action "Stray"`,
+		`connect n1 n2 yes`,
+		`connect n1 n3 no`,
+		`set-root n1`,
+		`stats`,
+		`stats --format yaml`,
+	)
+	for _, want := range []string{
+		"Nodes:       4 (decision 1, action 2, startend 0, io 1)\n",
+		"Orphans:     1\n",
+		"Paths:       2\n",
+		"Usage: stats [--format text|json]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+}
+
+func TestCmdDiff(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "login.json")
+	_, out := runCommands(t,
+		`add decision "Auth?"`,
+		`add action "Grant"`,
+		`connect n1 n2 yes`,
+		`set-root n1`,
+		`save `+file,
+		`diff `+file,
+		`edit n2 label "Grant access"`,
+		`add io "Show login"`,
+		`connect n1 n3 no`,
+		`diff `+file,
+		`diff`,
+		`diff `+file+` --format svg`,
+	)
+	for _, want := range []string{
+		"No differences\n",
+		`+ node n3 io "Show login"`,
+		`~ node n2 label "Grant" -> "Grant access"`,
+		`+ edge n1 -> n3 "no"`,
+		"Nodes: 1 added, 0 removed, 1 changed; edges: 1 added, 0 removed, 0 relabeled\n",
+		"Usage: diff <filename> [--format text|json|dot|mermaid]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+}
+
 func TestCmdEditUsage(t *testing.T) {
 	_, out := runCommands(t, "edit n1")
 	if !strings.Contains(out, "Usage:") {
@@ -147,6 +363,38 @@ func TestCmdSetRoot(t *testing.T) {
 	}
 }
 
+func TestCmdMode(t *testing.T) {
+	s, out := runCommands(t,
+		`add decision "q"`,
+		`add action "retry"`,
+		`connect n1 n2 no`,
+		`connect n2 n1`,
+		`mode graph`,
+		`connect n2 n1`,
+		`mode`,
+		`mode tree`,
+	)
+	if !strings.Contains(out, "would create a cycle") {
+		t.Errorf("expected cycle error in tree mode, got %q", out)
+	}
+	if !strings.Contains(out, "Mode set to graph") || !strings.Contains(out, "Mode: graph") {
+		t.Errorf("output = %q", out)
+	}
+	if !strings.Contains(out, "is part of a cycle") {
+		t.Errorf("expected error leaving graph mode, got %q", out)
+	}
+	if !s.Tree.Graph || !s.Tree.HasEdge("n2", "n1") {
+		t.Error("loop-back edge should exist in graph mode")
+	}
+}
+
+func TestCmdModeBad(t *testing.T) {
+	_, out := runCommands(t, "mode dag")
+	if !strings.Contains(out, "Unknown mode") {
+		t.Errorf("output = %q", out)
+	}
+}
+
 func TestCmdList(t *testing.T) {
 	_, out := runCommands(t,
 		`add decision "q1"`,
@@ -204,6 +452,67 @@ func TestCmdRenderMermaid(t *testing.T) {
 	}
 }
 
+func TestCmdRenderSVG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.svg")
+	_, out := runCommands(t,
+		`add decision "q1"`,
+		"render svg "+path,
+	)
+	if !strings.Contains(out, "Wrote svg to") {
+		t.Errorf("render output: %q", out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "<svg ") || !strings.Contains(string(data), ">q1</text>") {
+		t.Errorf("svg file = %q, %v", data, err)
+	}
+}
+
+func TestCmdRenderPlantUML(t *testing.T) {
+	_, out := runCommands(t,
+		`add startend "Start"`,
+		`add decision "q1"`,
+		"connect n1 n2",
+		"render plantuml",
+	)
+	if !strings.Contains(out, "@startuml") || !strings.Contains(out, "start\n:q1;") {
+		t.Errorf("render output: %q", out)
+	}
+}
+
+func TestCmdRenderD2(t *testing.T) {
+	_, out := runCommands(t,
+		`add decision "q1"`,
+		"render d2 --direction right",
+	)
+	if !strings.Contains(out, "direction: right") || !strings.Contains(out, "shape: diamond") {
+		t.Errorf("render output: %q", out)
+	}
+
+	_, out = runCommands(t, "render d2 --direction sideways")
+	if !strings.Contains(out, `unknown direction "sideways"`) {
+		t.Errorf("bad direction: %q", out)
+	}
+}
+
+func TestCmdRenderOptions(t *testing.T) {
+	_, out := runCommands(t,
+		`add decision "q1"`,
+		`add action "a1"`,
+		`connect n1 n2 "yes"`,
+		"render mermaid --direction LR --highlight n1,n2 --theme action=#e8f0fe",
+	)
+	for _, want := range []string{"flowchart LR", "linkStyle 0 stroke:#dd3333", "classDef action fill:#e8f0fe"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+
+	_, out = runCommands(t, `add decision "q1"`, "render dot --depth x")
+	if !strings.Contains(out, `invalid depth "x"`) {
+		t.Errorf("bad depth: %q", out)
+	}
+}
+
 func TestCmdRenderUsage(t *testing.T) {
 	_, out := runCommands(t, "render")
 	if !strings.Contains(out, "Usage:") {
@@ -212,12 +521,79 @@ func TestCmdRenderUsage(t *testing.T) {
 }
 
 func TestCmdRenderBadFormat(t *testing.T) {
-	_, out := runCommands(t, "render svg")
+	_, out := runCommands(t, "render png")
 	if !strings.Contains(out, "Unknown format") {
 		t.Errorf("expected unknown format, got %q", out)
 	}
 }
 
+func TestCmdRenderAttrs(t *testing.T) {
+	_, out := runCommands(t,
+		`add action "deploy"`,
+		`meta n1 set owner alice`,
+		`render dot --attrs owner`,
+	)
+	if !strings.Contains(out, `tooltip="owner: alice"`) {
+		t.Errorf("missing tooltip in: %q", out)
+	}
+}
+
+func TestCmdMeta(t *testing.T) {
+	s, out := runCommands(t,
+		`add decision "q"`,
+		`add action "a"`,
+		`connect n1 n2 yes`,
+		`meta n1 set owner "Alice Smith"`,
+		`meta n1 set ticket OPS-12`,
+		`meta n1->n2 set sla 4 hours`,
+		`meta n1`,
+		`meta n1->n2`,
+	)
+	if !strings.Contains(out, "Set owner on n1") || !strings.Contains(out, "Set sla on n1->n2") {
+		t.Errorf("output = %q", out)
+	}
+	if !strings.Contains(out, "owner = Alice Smith\nticket = OPS-12\n") {
+		t.Errorf("missing node attribute listing in %q", out)
+	}
+	if !strings.Contains(out, "sla = 4 hours") {
+		t.Errorf("missing edge attribute listing in %q", out)
+	}
+	if s.Tree.GetEdge("n1", "n2").Attrs["sla"] != "4 hours" {
+		t.Error("edge attribute not stored")
+	}
+}
+
+func TestCmdMetaRemoveAndUndo(t *testing.T) {
+	s, out := runCommands(t,
+		`add action "a"`,
+		`meta n1 set owner alice`,
+		`meta n1 rm owner`,
+		`meta n1`,
+		`undo`,
+	)
+	if !strings.Contains(out, "Removed owner from n1") || !strings.Contains(out, "(no attributes on n1)") {
+		t.Errorf("output = %q", out)
+	}
+	if s.Tree.GetNode("n1").Attrs["owner"] != "alice" {
+		t.Error("undo should restore the removed attribute")
+	}
+}
+
+func TestCmdMetaErrors(t *testing.T) {
+	_, out := runCommands(t,
+		`meta`,
+		`meta n9`,
+		`meta n1->n2`,
+		`meta n1 set onlykey`,
+	)
+	if strings.Count(out, "Usage: meta") != 2 {
+		t.Errorf("expected two usage messages in %q", out)
+	}
+	if !strings.Contains(out, `node "n9" not found`) || !strings.Contains(out, "no edge from n1 to n2") {
+		t.Errorf("output = %q", out)
+	}
+}
+
 func TestCmdCopyPaste(t *testing.T) {
 	s, out := runCommands(t,
 		`add decision "root"`,
@@ -270,6 +646,139 @@ func TestCmdSaveLoad(t *testing.T) {
 	}
 }
 
+func TestCmdSaveLoadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.dtree")
+	runCommands(t,
+		`add decision "q1"`,
+		`set-root n1`,
+		`add action "a1"`,
+		`connect n1 n2 yes`,
+		`undo`,
+		"save "+path,
+	)
+
+	s, out := runCommands(t, "load "+path, "history", "undo", "undo")
+	for _, want := range []string{
+		"Restored undo history (3 steps, 1 undone)",
+		`add decision "q1" as n1`,
+		`connect n1 -> n2 "yes"  (undone)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+	if len(s.Tree.Nodes) != 1 || s.Tree.RootID != "" {
+		t.Errorf("undo after reload: %d nodes, root %q", len(s.Tree.Nodes), s.Tree.RootID)
+	}
+	one := filepath.Join(t.TempDir(), "one.json")
+	runCommands(t, `add action "a"`, "save "+one)
+	if _, out := runCommands(t, "load "+one); !strings.Contains(out, "Restored undo history (1 step, 0 undone)") {
+		t.Errorf("one step: %q", out)
+	}
+
+	// A file changed behind the journal's back loads without its history.
+	os.WriteFile(path, []byte("action \"edited\" #n1\n"), 0644)
+	s, out = runCommands(t, "load "+path)
+	if !strings.Contains(out, "Ignoring "+path+".history: the file was changed without it") || s.History.CanUndo() {
+		t.Errorf("stale history: %q", out)
+	}
+}
+
+func TestCmdRedoAfterReload(t *testing.T) {
+	// .dtree files have no counter, so the reloaded one stops at n2 while
+	// the history can redo n3.
+	path := filepath.Join(t.TempDir(), "t.dtree")
+	runCommands(t, `add decision Q`, `add action A`, `add action B`, `undo`, "save "+path)
+
+	s, out := runCommands(t, "load "+path, "redo", `add action C`, "history")
+	if n := s.Tree.GetNode("n3"); n == nil || n.Label != "B" {
+		t.Errorf("n3 = %+v, want B", n)
+	}
+	if n := s.Tree.GetNode("n4"); n == nil || n.Label != "C" {
+		t.Errorf("n4 = %+v, want C", n)
+	}
+	if strings.Count(out, " as n3") != 1 || !strings.Contains(out, `add action "C" as n4`) {
+		t.Errorf("history = %q", out)
+	}
+}
+
+func TestCmdHistory(t *testing.T) {
+	_, out := runCommands(t, "history")
+	if !strings.Contains(out, "No history") {
+		t.Errorf("output = %q", out)
+	}
+	_, out = runCommands(t,
+		`add decision "q1"`,
+		`begin`,
+		`add action "a1"`,
+		`connect n1 n2`,
+		`commit`,
+		`history`,
+	)
+	if !regexp.MustCompile(`(?m)^  2  \d{4}-\d\d-\d\d \d\d:\d\d:\d\d  add action "a1" as n2; connect n1 -> n2$`).MatchString(out) {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestPlural(t *testing.T) {
+	for n, want := range map[int]string{0: "0 changes", 1: "1 change", 2: "2 changes"} {
+		if got := plural(n, "change"); got != want {
+			t.Errorf("plural(%d) = %q, want %q", n, got, want)
+		}
+	}
+	_, out := runCommands(t, "begin", `add action "a"`, "rollback")
+	if !strings.Contains(out, "Rolled back 1 change\n") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestCmdSaveInTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.json")
+	_, out := runCommands(t, `add decision "q1"`, "begin", "save "+path)
+	if !strings.Contains(out, "cannot save with a transaction open") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestCmdLoadMermaid(t *testing.T) {
+	dir := t.TempDir()
+	src := "flowchart TB\n  n1{Raining?} -- yes --> n2[Umbrella]\n  n1 -- no --> n3([Walk])\n"
+	mmd := filepath.Join(dir, "weather.mmd")
+	txt := filepath.Join(dir, "weather.txt")
+	os.WriteFile(mmd, []byte(src), 0644)
+	os.WriteFile(txt, []byte(src), 0644)
+
+	s, out := runCommands(t, "load "+mmd, "add action Stay")
+	if !strings.Contains(out, `Loaded "weather" (3 nodes)`) {
+		t.Errorf("load output: %q", out)
+	}
+	if n := s.Tree.Nodes["n4"]; n == nil || n.Label != "Stay" {
+		t.Errorf("new node after import = %+v, want n4", n)
+	}
+	if e := s.Tree.GetEdge("n1", "n3"); e == nil || e.Label != "no" {
+		t.Errorf("edge n1->n3 = %+v", e)
+	}
+
+	s, out = runCommands(t, "load "+txt+" --format mermaid")
+	if len(s.Tree.Nodes) != 3 {
+		t.Errorf("load --format mermaid: %q", out)
+	}
+	_, out = runCommands(t, "load "+txt)
+	if !strings.Contains(out, "Error: unmarshal") {
+		t.Errorf("load without format: %q", out)
+	}
+	dot := filepath.Join(dir, "weather.gv")
+	os.WriteFile(dot, []byte("digraph weather {\n  n1 [label=\"Raining?\", shape=diamond]\n  n1 -> n2 [label=yes]\n}\n"), 0644)
+	s, _ = runCommands(t, "load "+dot)
+	if n := s.Tree.Nodes["n1"]; n == nil || n.Type.String() != "decision" || s.Tree.Name != "weather" {
+		t.Errorf("load .gv: tree %q, n1 = %+v", s.Tree.Name, n)
+	}
+	_, out = runCommands(t, "load "+txt+" --format svg")
+	if !strings.Contains(out, "Unknown format: svg") {
+		t.Errorf("load --format svg: %q", out)
+	}
+}
+
 func TestCmdUndoRedo(t *testing.T) {
 	s, out := runCommands(t,
 		`add decision "q1"`,
@@ -301,6 +810,141 @@ func TestCmdUndoEmpty(t *testing.T) {
 	}
 }
 
+func TestCmdTransaction(t *testing.T) {
+	s, out := runCommands(t,
+		`add decision "q1"`,
+		`begin`,
+		`add action "a1"`,
+		`connect n1 n2 yes`,
+		`undo`,
+		`commit`,
+		`undo`,
+	)
+	for _, want := range []string{
+		"Transaction started",
+		"Error: a transaction is open (commit or rollback it first)",
+		"Committed 2 changes as one undo step",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in output %q", want, out)
+		}
+	}
+	if len(s.Tree.Nodes) != 1 || len(s.Tree.Edges) != 0 {
+		t.Errorf("one undo should revert the transaction: %d nodes, %d edges", len(s.Tree.Nodes), len(s.Tree.Edges))
+	}
+}
+
+func TestCmdRollback(t *testing.T) {
+	s, out := runCommands(t,
+		`add decision "q1"`,
+		`begin`,
+		`edit n1 label "changed"`,
+		`add action "a1"`,
+		`rollback`,
+		`rollback`,
+	)
+	if !strings.Contains(out, "Rolled back 2 changes") {
+		t.Errorf("output = %q", out)
+	}
+	if !strings.Contains(out, "Error: no transaction is open") {
+		t.Errorf("expected error for second rollback, got %q", out)
+	}
+	if len(s.Tree.Nodes) != 1 || s.Tree.Nodes["n1"].Label != "q1" {
+		t.Errorf("rollback should restore the tree: %+v", s.Tree.Nodes)
+	}
+}
+
+func TestCmdLint(t *testing.T) {
+	_, out := runCommands(t,
+		`add decision "q1"`,
+		`add startend "done"`,
+		`set-root n1`,
+		`connect n1 n2`,
+		`lint`,
+	)
+	for _, want := range []string{
+		`error: decision n1 "q1" has only one branch [decision-branches]`,
+		"warning: branch n1 -> n2 from decision n1 \"q1\" has no label [unlabeled-branch]",
+		"Lint: 1 error, 1 warning",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+
+	_, out = runCommands(t, `add startend "only"`, `set-root n1`, `lint --format json`)
+	if strings.TrimSpace(out[strings.Index(out, "["):]) != "[]" {
+		t.Errorf("json output = %q", out)
+	}
+	_, out = runCommands(t, `lint --disable typo`)
+	if !strings.Contains(out, `unknown lint rule "typo"`) {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestCmdLintFix(t *testing.T) {
+	s, out := runCommands(t,
+		`add decision "q1"`,
+		`add action "a1"`,
+		`add startend "done"`,
+		`add io "orphan"`,
+		`add action "later"`,
+		`set-root n1`,
+		`connect n1 n2 Yes`,
+		`connect n1 n3 no`,
+		`connect n2 n5 yes`,
+		`lint --fix --dry-run`,
+	)
+	for _, want := range []string{
+		"--- tree (before)\n+++ tree (after)\n",
+		"\n-io \"orphan\" #n4\n",
+		"\n-    yes -> action \"later\" #n5\n+    Yes -> action \"later\" #n5\n+      startend \"End\" #n6\n",
+		"Would make 4 changes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+	if len(s.Tree.Nodes) != 5 {
+		t.Fatalf("dry run changed the tree: %+v", s.Tree.Nodes)
+	}
+
+	var buf bytes.Buffer
+	s.Out = &buf
+	s.Execute(Parse("lint --fix"))
+	out = buf.String()
+	for _, want := range []string{
+		"  remove n4\n",
+		`  edit n2->n5 label "Yes"`,
+		"Fixed with 4 changes as one undo step",
+		"Lint: no problems",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+	buf.Reset()
+	s.Execute(Parse("undo"))
+	s.Execute(Parse("lint --fix --dry-run"))
+	out = buf.String()
+	if len(s.Tree.Nodes) != 5 || !strings.Contains(out, "Would make 4 changes") {
+		t.Errorf("undo did not take back the fix: %q", out)
+	}
+
+	_, out = runCommands(t, `add startend "only"`, `add startend "stray"`, `set-root n1`, `lint --fix`)
+	if !strings.Contains(out, "Fixed with 1 change as one undo step") {
+		t.Errorf("one change: %q", out)
+	}
+	_, out = runCommands(t, `add startend "only"`, `set-root n1`, `lint --fix`)
+	if !strings.Contains(out, "Nothing to fix") || !strings.Contains(out, "Lint: no problems") {
+		t.Errorf("clean tree: %q", out)
+	}
+	_, out = runCommands(t, `lint --dry-run`)
+	if !strings.Contains(out, "--dry-run only applies to --fix") {
+		t.Errorf("output = %q", out)
+	}
+}
+
 func TestCmdHelp(t *testing.T) {
 	_, out := runCommands(t, "help")
 	if !strings.Contains(out, "Commands:") {
@@ -374,3 +1018,39 @@ func TestCmdInitUnknown(t *testing.T) {
 		t.Errorf("expected template list in error output, got %q", out)
 	}
 }
+
+func TestCmdLearn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loans.csv")
+	data := "income,owns_home,approve\nlow,no,no\nlow,yes,no\nhigh,no,yes\nhigh,yes,yes\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, out := runCommands(t, "learn "+path+" --target approve --criterion gini")
+	if !strings.Contains(out, `Learned "loans" from 4 rows (3 nodes, gini)`) {
+		t.Fatalf("output = %q", out)
+	}
+	if root := s.Tree.Nodes[s.Tree.RootID]; root.Label != "income?" {
+		t.Errorf("root label = %q, want income?", root.Label)
+	}
+	if err := s.History.Undo(s.Tree); err == nil {
+		t.Error("learned tree should start with empty history")
+	}
+}
+
+func TestCmdLearnErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "d.csv")
+	if err := os.WriteFile(path, []byte("a,b\nx,y\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, out := runCommands(t,
+		"learn "+path,
+		"learn "+path+" --target c",
+		"learn "+path+" --target b --max-depth -1",
+		"learn "+path+" --target b --criterion foo",
+	)
+	for _, want := range []string{"Usage: learn", `target column "c" not found`, "--max-depth must be", "unknown criterion"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jllovet/decision-tree-cli/internal/eval"
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func (s *Session) cmdEval(args []string) error {
+	if len(args) < 1 {
+		return usage("Usage: eval <records.jsonl> [output.jsonl]")
+	}
+	out := s.Out
+	if len(args) >= 2 {
+		f, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	sum, err := evalFile(s.Tree, args[0], out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Evaluated %d records: %d routed, %d failed\n", sum.Records, sum.Records-sum.Failed, sum.Failed)
+	return nil
+}
+
+func evalFile(t *model.Tree, path string, w io.Writer) (eval.Summary, error) {
+	ev, err := eval.New(t)
+	if err != nil {
+		return eval.Summary{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return eval.Summary{}, err
+	}
+	defer f.Close()
+	return ev.RunJSONL(f, w)
+}
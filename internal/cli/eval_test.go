@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/storage"
+)
+
+// writeRoutingFiles saves a small conditional tree and a records file,
+// returning their paths.
+func writeRoutingFiles(t *testing.T, records string) (string, string) {
+	t.Helper()
+	s, out := runCommands(t,
+		`add decision "Adult?"`,
+		`add action "Allow"`,
+		`add action "Deny"`,
+		`connect n1 n2 yes`,
+		`connect n1 n3 no`,
+		`set-root n1`,
+		`edit n1 condition "age >= 18"`,
+	)
+	if !strings.Contains(out, "Updated n1 condition") {
+		t.Fatalf("output = %q", out)
+	}
+	dir := t.TempDir()
+	treePath := filepath.Join(dir, "tree.json")
+	if err := storage.Save(s.Tree, treePath); err != nil {
+		t.Fatal(err)
+	}
+	recPath := filepath.Join(dir, "records.jsonl")
+	if err := os.WriteFile(recPath, []byte(records), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return treePath, recPath
+}
+
+func TestCmdEval(t *testing.T) {
+	_, recPath := writeRoutingFiles(t, "{\"age\": 30}\n")
+	_, out := runCommands(t,
+		`add decision "Adult?"`,
+		`add action "Allow"`,
+		`add action "Deny"`,
+		`connect n1 n2 adult`,
+		`connect n1 n3 minor`,
+		`set-root n1`,
+		`edit n1 condition "age >= 18"`,
+		`edit n1->n2 outcome true`,
+		`edit n1->n3 outcome else`,
+		`eval `+recPath,
+	)
+	if !strings.Contains(out, `"leaf":"n2"`) {
+		t.Errorf("missing routed record in %q", out)
+	}
+	if !strings.Contains(out, "Evaluated 1 records: 1 routed, 0 failed") {
+		t.Errorf("missing summary in %q", out)
+	}
+}
+
+func TestCmdEditCondition(t *testing.T) {
+	s, out := runCommands(t,
+		`add decision "Q"`,
+		`add action "A"`,
+		`connect n1 n2 yes`,
+		`edit n1 condition "score > "`,
+		`edit n1 condition "score > 3"`,
+		`edit n1->n2 outcome true`,
+		`edit n1->n9 outcome true`,
+	)
+	if !strings.Contains(out, "Error: parse") {
+		t.Errorf("expected parse error in %q", out)
+	}
+	if got := s.Tree.Nodes["n1"].Condition; got != "score > 3" {
+		t.Errorf("condition = %q", got)
+	}
+	if got := s.Tree.GetEdge("n1", "n2").Outcome; got != "true" {
+		t.Errorf("outcome = %q", got)
+	}
+	if !strings.Contains(out, "Error: no edge from n1 to n9") {
+		t.Errorf("expected missing edge error in %q", out)
+	}
+
+	s.Execute(Parse(`edit n1 condition none`))
+	s.Execute(Parse(`edit n1->n2 outcome none`))
+	if s.Tree.Nodes["n1"].Condition != "" || s.Tree.GetEdge("n1", "n2").Outcome != "" {
+		t.Error("none should clear condition and outcome")
+	}
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is how many unchanged lines unifiedDiff shows around each
+// change.
+const diffContext = 3
+
+// unifiedDiff compares two texts line by line and returns the differences
+// in unified diff form, or "" if they are the same. Trees are small, so a
+// plain longest-common-subsequence table is fast enough.
+func unifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	x := strings.SplitAfter(a, "\n")
+	y := strings.SplitAfter(b, "\n")
+	if x[len(x)-1] == "" {
+		x = x[:len(x)-1]
+	}
+	if y[len(y)-1] == "" {
+		y = y[:len(y)-1]
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table into a script of kept (' '), removed ('-') and added
+	// ('+') lines.
+	type line struct {
+		op   byte
+		text string
+	}
+	var script []line
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			script = append(script, line{' ', x[i]})
+			i++
+			j++
+		case j == len(y) || (i < len(x) && lcs[i+1][j] >= lcs[i][j+1]):
+			script = append(script, line{'-', x[i]})
+			i++
+		default:
+			script = append(script, line{'+', y[j]})
+			j++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	aLine, bLine := 1, 1 // line numbers at script[k]
+	for k := 0; k < len(script); {
+		if script[k].op == ' ' {
+			k++
+			aLine++
+			bLine++
+			continue
+		}
+		// A hunk runs from diffContext lines before this change to
+		// diffContext lines after the last change within reach.
+		start := max(k-diffContext, 0)
+		for s := start; s < k; s++ {
+			aLine--
+			bLine--
+		}
+		end := k
+		for end < len(script) {
+			if script[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(script) && script[next].op == ' ' {
+				next++
+			}
+			if next == len(script) || next-end > 2*diffContext {
+				end = min(end+diffContext, len(script))
+				break
+			}
+			end = next
+		}
+		aCount, bCount := 0, 0
+		for _, l := range script[start:end] {
+			if l.op != '+' {
+				aCount++
+			}
+			if l.op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+		for _, l := range script[start:end] {
+			sb.WriteByte(l.op)
+			sb.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		aLine += aCount
+		bLine += bCount
+		k = end
+	}
+	return sb.String()
+}
+
+// hunkRange formats a line range in a hunk header. An empty range names
+// the line before it, as diff does.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
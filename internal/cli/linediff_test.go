@@ -0,0 +1,47 @@
+package cli
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n12\nthirteen\n"
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -8,5 +8,5 @@
+ 8
+ 9
+ 10
+-11
+ 12
++thirteen
+`
+	if got := unifiedDiff("a", "b", a, b); got != want {
+		t.Errorf("diff =\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedDiff("a", "b", a, a); got != "" {
+		t.Errorf("equal texts: %q", got)
+	}
+}
+
+func TestUnifiedDiffEdges(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"", "x\n", "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n"},
+		{"x\ny\n", "y\n", "--- a\n+++ b\n@@ -1,2 +1 @@\n-x\n y\n"},
+		{"x", "x\n", "--- a\n+++ b\n@@ -1 +1 @@\n-x\n\\ No newline at end of file\n+x\n"},
+	}
+	for _, tt := range tests {
+		if got := unifiedDiff("a", "b", tt.a, tt.b); got != tt.want {
+			t.Errorf("diff(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
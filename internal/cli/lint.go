@@ -0,0 +1,302 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jllovet/decision-tree-cli/internal/importer"
+	"github.com/jllovet/decision-tree-cli/internal/lint"
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/storage"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+const lintUsage = "lint [--format text|json] [--disable rule,...] [--rules] [--fix [--dry-run]]"
+
+// lintBoolFlags are the lint flags that take no value.
+var lintBoolFlags = []string{"rules", "fix", "dry-run"}
+
+// lintOptions are the flags shared by the lint command and subcommand.
+type lintOptions struct {
+	rules  []lint.Rule
+	json   bool
+	failOn lint.Severity
+	list   bool
+	fix    bool
+	dryRun bool
+}
+
+func parseLintFlags(flags map[string]string) (lintOptions, error) {
+	opts := lintOptions{
+		failOn: lint.Error,
+		list:   flags["rules"] == "true",
+		fix:    flags["fix"] == "true",
+		dryRun: flags["dry-run"] == "true",
+	}
+	switch flags["format"] {
+	case "", "text":
+	case "json":
+		opts.json = true
+	default:
+		return opts, fmt.Errorf("unknown lint format %q (use text or json)", flags["format"])
+	}
+	if v, ok := flags["fail-on"]; ok {
+		s, err := lint.ParseSeverity(v)
+		if err != nil {
+			return opts, err
+		}
+		opts.failOn = s
+	}
+	var disabled []string
+	if v := flags["disable"]; v != "" {
+		disabled = strings.Split(v, ",")
+	}
+	if opts.dryRun && !opts.fix {
+		return opts, fmt.Errorf("--dry-run only applies to --fix")
+	}
+	if opts.fix && opts.json {
+		return opts, fmt.Errorf("--fix cannot be combined with --format json")
+	}
+	rules, err := lint.Select(lint.DefaultRules(), disabled)
+	if err != nil {
+		return opts, err
+	}
+	opts.rules = rules
+	return opts, nil
+}
+
+// writeLintRules lists the rules with their severities, marking those
+// that --fix repairs.
+func writeLintRules(w io.Writer, rules []lint.Rule) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range rules {
+		fixable := ""
+		if r.Fix != nil {
+			fixable = "fix"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.ID, r.Severity, fixable, r.Description)
+	}
+	tw.Flush()
+}
+
+// applyLintFixes runs the lint repairs on t through h as one undo step.
+func applyLintFixes(t *model.Tree, h *tree.History, rules []lint.Rule) ([]tree.Command, error) {
+	var applied []tree.Command
+	err := h.Atomic(t, func() error {
+		var err error
+		applied, err = lint.Fix(t, rules, func(cmd tree.Command) error {
+			return h.Execute(t, cmd)
+		})
+		return err
+	})
+	return applied, err
+}
+
+// previewLintFixes runs the lint repairs on a copy of t and returns the
+// copy and the number of changes.
+func previewLintFixes(t *model.Tree, rules []lint.Rule) (*model.Tree, int, error) {
+	fixed := t.Clone()
+	applied, err := lint.Fix(fixed, rules, func(cmd tree.Command) error {
+		return cmd.Execute(fixed)
+	})
+	return fixed, len(applied), err
+}
+
+// fixDiff shows a repair as a diff of the tree's .dtree text.
+func fixDiff(name string, before, after *model.Tree) string {
+	return unifiedDiff(name+" (before)", name+" (after)", storage.FormatDTree(before), storage.FormatDTree(after))
+}
+
+// fileFinding is a finding in "dt lint" JSON output, which covers several
+// files.
+type fileFinding struct {
+	File string `json:"file"`
+	lint.Finding
+}
+
+// writeJSON writes v as indented JSON, leaving <, > and & unescaped.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (s *Session) cmdLint(args []string) error {
+	args, flags := splitFlags(args, lintBoolFlags...)
+	if len(args) > 0 {
+		return usage("Usage: " + lintUsage)
+	}
+	opts, err := parseLintFlags(flags)
+	if err != nil {
+		return usage(err.Error())
+	}
+	if opts.list {
+		writeLintRules(s.Out, opts.rules)
+		return nil
+	}
+	if opts.fix {
+		return s.lintFix(opts.rules, opts.dryRun)
+	}
+	findings := lint.Run(s.Tree, opts.rules)
+	if opts.json {
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		return writeJSON(s.Out, findings)
+	}
+	s.printLint(findings)
+	return nil
+}
+
+func (s *Session) printLint(findings []lint.Finding) {
+	for _, f := range findings {
+		fmt.Fprintln(s.Out, f)
+	}
+	fmt.Fprintf(s.Out, "Lint: %s\n", lint.Summary(findings))
+}
+
+// lintFix repairs what the lint rules can as one undo step, then reports
+// what is left. With dryRun it shows the repair as a diff instead.
+func (s *Session) lintFix(rules []lint.Rule, dryRun bool) error {
+	if dryRun {
+		fixed, n, err := previewLintFixes(s.Tree, rules)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			fmt.Fprintln(s.Out, "Nothing to fix")
+			return nil
+		}
+		fmt.Fprint(s.Out, fixDiff("tree", s.Tree, fixed))
+		fmt.Fprintf(s.Out, "Would make %s (run lint --fix to apply them)\n", plural(n, "change"))
+		return nil
+	}
+	applied, err := applyLintFixes(s.Tree, s.History, rules)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(s.Out, "Nothing to fix")
+	} else {
+		for _, cmd := range applied {
+			fmt.Fprintf(s.Out, "  %s\n", tree.Describe(cmd))
+		}
+		fmt.Fprintf(s.Out, "Fixed with %s as one undo step\n", plural(len(applied), "change"))
+	}
+	s.printLint(lint.Run(s.Tree, rules))
+	return nil
+}
+
+// runLint implements "dt lint". It fails if any file cannot be loaded or
+// has findings at or above the --fail-on severity (error by default).
+// With --fix it first repairs each file in place, recording the repair in
+// the file's undo history, and lints the result; --dry-run prints the
+// repair as a diff without writing anything.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	files, flags := splitFlags(args, lintBoolFlags...)
+	opts, err := parseLintFlags(flags)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitUsage
+	}
+	if opts.list {
+		writeLintRules(stdout, opts.rules)
+		return ExitOK
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(stderr, "Usage: dt lint [--format text|json] [--disable rule,...] [--fail-on error|warning|info] [--fix [--dry-run]] <file>...")
+		return ExitUsage
+	}
+	code := ExitOK
+	all := []fileFinding{}
+	for _, path := range files {
+		if opts.fix && !opts.dryRun && importer.FormatForPath(path) != "" {
+			fmt.Fprintf(stderr, "%s: cannot fix an imported file in place (load it in the shell, run lint --fix and save it)\n", path)
+			code = ExitFailure
+			continue
+		}
+		t, err := loadTree(path, "")
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			code = ExitFailure
+			continue
+		}
+		if opts.fix {
+			if t, err = fixFile(path, t, opts, stdout); err != nil {
+				fmt.Fprintf(stderr, "%s: %v\n", path, err)
+				code = ExitFailure
+				continue
+			}
+		}
+		findings := lint.Run(t, opts.rules)
+		if lint.Count(findings, opts.failOn) > 0 {
+			code = ExitFailure
+		}
+		if opts.json {
+			for _, f := range findings {
+				all = append(all, fileFinding{File: path, Finding: f})
+			}
+			continue
+		}
+		for _, f := range findings {
+			fmt.Fprintf(stdout, "%s: %s\n", path, f)
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", path, lint.Summary(findings))
+	}
+	if opts.json {
+		if err := writeJSON(stdout, all); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitFailure
+		}
+	}
+	return code
+}
+
+// fixFile repairs a tree loaded from path and returns the repaired tree.
+// A dry run prints the diff; otherwise the tree and its undo history are
+// saved, so "undo" in the shell takes the repair back.
+func fixFile(path string, t *model.Tree, opts lintOptions, stdout io.Writer) (*model.Tree, error) {
+	if opts.dryRun {
+		fixed, n, err := previewLintFixes(t, opts.rules)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			fmt.Fprint(stdout, fixDiff(path, t, fixed))
+			fmt.Fprintf(stdout, "%s: would make %s\n", path, plural(n, "change"))
+		}
+		return fixed, nil
+	}
+	// A stale journal no longer matches the file and is replaced; any other
+	// failure stops the fix rather than overwrite history that may be
+	// recoverable.
+	h, err := storage.LoadHistory(path)
+	switch {
+	case errors.Is(err, storage.ErrStaleHistory):
+		h = tree.NewHistory()
+	case err != nil:
+		return nil, fmt.Errorf("%w (move %s aside to fix anyway)", err, storage.HistoryPath(path))
+	case h == nil:
+		h = tree.NewHistory()
+	}
+	applied, err := applyLintFixes(t, h, opts.rules)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		return t, nil
+	}
+	if err := storage.Save(t, path); err != nil {
+		return nil, err
+	}
+	if err := storage.SaveHistory(h, path); err != nil {
+		return nil, fmt.Errorf("fixed, but could not save the undo history: %w", err)
+	}
+	fmt.Fprintf(stdout, "%s: fixed with %s\n", path, plural(len(applied), "change"))
+	return t, nil
+}
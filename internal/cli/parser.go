@@ -55,3 +55,61 @@ func tokenize(line string) []string {
 	}
 	return tokens
 }
+
+// splitFlags separates options from positional arguments. Options may be
+// written as "--name value", "--name=value" or "-n value"; names listed in
+// boolFlags take no value and are set to "true".
+func splitFlags(args []string, boolFlags ...string) ([]string, map[string]string) {
+	var positional []string
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		name, ok := flagName(args[i])
+		if !ok {
+			positional = append(positional, args[i])
+			continue
+		}
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			flags[name[:eq]] = name[eq+1:]
+			continue
+		}
+		if isBoolFlag(name, boolFlags) || i+1 >= len(args) {
+			flags[name] = "true"
+			continue
+		}
+		flags[name] = args[i+1]
+		i++
+	}
+	return positional, flags
+}
+
+// flagName returns the option name of arg without its leading dashes.
+// A lone dash or a negative number is not an option.
+func flagName(arg string) (string, bool) {
+	name := strings.TrimLeft(arg, "-")
+	if name == arg || name == "" || len(arg)-len(name) > 2 {
+		return "", false
+	}
+	c := name[0]
+	if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+		return "", false
+	}
+	return name, true
+}
+
+func isBoolFlag(name string, boolFlags []string) bool {
+	for _, b := range boolFlags {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEdgeRef splits an edge reference of the form "from->to".
+func parseEdgeRef(ref string) (fromID, toID string, ok bool) {
+	from, to, found := strings.Cut(ref, "->")
+	if !found || from == "" || to == "" {
+		return "", "", false
+	}
+	return from, to, true
+}
@@ -44,3 +44,35 @@ func TestTokenize(t *testing.T) {
 		t.Errorf("tokens[2] = %q", tokens[2])
 	}
 }
+
+func TestSplitFlags(t *testing.T) {
+	pos, flags := splitFlags([]string{"dot", "--attrs", "owner,sla", "out.dot", "--x=1", "-o", "f", "--fix", "-5"}, "fix")
+	if len(pos) != 3 || pos[0] != "dot" || pos[1] != "out.dot" || pos[2] != "-5" {
+		t.Errorf("positional = %v", pos)
+	}
+	want := map[string]string{"attrs": "owner,sla", "x": "1", "o": "f", "fix": "true"}
+	for k, v := range want {
+		if flags[k] != v {
+			t.Errorf("flags[%q] = %q, want %q", k, flags[k], v)
+		}
+	}
+}
+
+func TestSplitFlagsTrailing(t *testing.T) {
+	_, flags := splitFlags([]string{"--verbose"})
+	if flags["verbose"] != "true" {
+		t.Errorf("trailing flag without value = %q, want true", flags["verbose"])
+	}
+}
+
+func TestParseEdgeRef(t *testing.T) {
+	from, to, ok := parseEdgeRef("n1->n2")
+	if !ok || from != "n1" || to != "n2" {
+		t.Errorf("parseEdgeRef(n1->n2) = %q, %q, %v", from, to, ok)
+	}
+	for _, bad := range []string{"n1", "n1->", "->n2"} {
+		if _, _, ok := parseEdgeRef(bad); ok {
+			t.Errorf("parseEdgeRef(%q) should fail", bad)
+		}
+	}
+}
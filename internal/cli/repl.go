@@ -16,8 +16,13 @@ func Run(r io.Reader, w io.Writer) {
 
 	lr := terminal.NewLineReader(r, w)
 	defer lr.Close()
+	session.lines = lr
 	for {
-		line, err := lr.ReadLine("> ")
+		prompt := "> "
+		if session.History.InTransaction() {
+			prompt = "(tx)> "
+		}
+		line, err := lr.ReadLine(prompt)
 		if err != nil {
 			break
 		}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxSourceDepth limits how deeply scripts may source other scripts, which
+// also stops a script that sources itself.
+const maxSourceDepth = 16
+
+// ScriptError reports the commands of a script that failed.
+type ScriptError struct {
+	Name    string // script name used in messages
+	Lines   []int  // line numbers of the failed commands
+	Stopped bool   // whether the script stopped at the last failure
+}
+
+func (e *ScriptError) Error() string {
+	if e.Stopped {
+		return fmt.Sprintf("%s: stopped at line %d", e.Name, e.Lines[len(e.Lines)-1])
+	}
+	lines := make([]string, len(e.Lines))
+	for i, n := range e.Lines {
+		lines[i] = strconv.Itoa(n)
+	}
+	return fmt.Sprintf("%s: %d of its commands failed (lines %s)", e.Name, len(e.Lines), strings.Join(lines, ", "))
+}
+
+// RunScript runs each line of r as a command. Blank lines and lines whose
+// first non-space character is # are skipped, and quit or exit ends the
+// script. Each failure is reported to s.Err (or s.Out) as
+// "name:line: message". The script stops at the first failure unless
+// continueOnError is set; stopping rolls back a transaction the script
+// began, so a script wrapped in begin and commit applies all or nothing. If
+// any command failed it returns a *ScriptError.
+func (s *Session) RunScript(r io.Reader, name string, continueOnError bool) error {
+	if s.sourceDepth >= maxSourceDepth {
+		return fmt.Errorf("%s: scripts nested more than %d deep", name, maxSourceDepth)
+	}
+	s.sourceDepth++
+	defer func() { s.sourceDepth-- }()
+	inTransaction := s.History.InTransaction()
+
+	var failed []int
+	sc := bufio.NewScanner(r)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		err := s.Run(Parse(line))
+		if errors.Is(err, ErrQuit) {
+			break
+		}
+		if err == nil {
+			continue
+		}
+		fmt.Fprintf(s.errOut(), "%s:%d: %s\n", name, lineNo, formatError(err))
+		failed = append(failed, lineNo)
+		if !continueOnError {
+			if !inTransaction && s.History.InTransaction() {
+				if n, err := s.History.Rollback(s.Tree); err == nil {
+					fmt.Fprintf(s.errOut(), "%s: rolled back %s since begin\n", name, plural(n, "change"))
+				}
+			}
+			return &ScriptError{Name: name, Lines: failed, Stopped: true}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if len(failed) > 0 {
+		return &ScriptError{Name: name, Lines: failed}
+	}
+	return nil
+}
+
+func (s *Session) cmdSource(args []string) error {
+	args, flags := splitFlags(args, "continue")
+	if len(args) != 1 {
+		return usage("Usage: source <file> [--continue]")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.RunScript(f, args[0], flags["continue"] == "true")
+}
+
+// runScriptFile implements "dt --script <file> [--continue]". A file name
+// of "-" reads the script from stdin.
+func runScriptFile(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	rest, flags := splitFlags(args, "continue")
+	path := flags["script"]
+	if len(rest) > 0 || path == "" || path == "true" {
+		fmt.Fprintln(stderr, "Usage: dt --script <file> [--continue]")
+		return ExitUsage
+	}
+	s := NewSession(stdout)
+	s.In = stdin
+	s.Err = stderr
+	r, name := stdin, "stdin"
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitFailure
+		}
+		defer f.Close()
+		r, name = f, path
+	}
+	if err := s.RunScript(r, name, flags["continue"] == "true"); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	return ExitOK
+}
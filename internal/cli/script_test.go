@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScript(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunErrors(t *testing.T) {
+	s := NewSession(&bytes.Buffer{})
+
+	var ue *UsageError
+	if err := s.Run(Parse("add")); !errors.As(err, &ue) || !strings.HasPrefix(ue.Msg, "Usage: add") {
+		t.Errorf("add without args: %v", err)
+	}
+	if err := s.Run(Parse("bogus")); !errors.As(err, &ue) {
+		t.Errorf("unknown command: %v", err)
+	}
+	err := s.Run(Parse("remove n9"))
+	if err == nil || errors.As(err, &ue) {
+		t.Errorf("remove missing node: %v", err)
+	}
+	if err := s.Run(Parse("add action Go")); err != nil {
+		t.Errorf("add: %v", err)
+	}
+	if err := s.Run(Parse("quit")); !errors.Is(err, ErrQuit) {
+		t.Errorf("quit: %v", err)
+	}
+}
+
+func TestExecuteReportsToErr(t *testing.T) {
+	var out, errOut bytes.Buffer
+	s := NewSession(&out)
+	s.Err = &errOut
+	s.Execute(Parse("add action Go"))
+	s.Execute(Parse("remove n9"))
+	if !strings.Contains(out.String(), "Added node n1") || strings.Contains(out.String(), "Error") {
+		t.Errorf("out = %q", out.String())
+	}
+	if errOut.String() != "Error: node n9 not found\n" {
+		t.Errorf("err = %q", errOut.String())
+	}
+}
+
+const sampleScript = `# build a tiny tree
+add decision "Ready?"
+
+  # indented comment
+add action Ship
+connect n1 n2 yes
+remove n9
+set-root n1
+`
+
+func TestRunScriptStopsOnError(t *testing.T) {
+	var out bytes.Buffer
+	s := NewSession(&out)
+	err := s.RunScript(strings.NewReader(sampleScript), "build.dt", false)
+	var se *ScriptError
+	if !errors.As(err, &se) || !se.Stopped || len(se.Lines) != 1 || se.Lines[0] != 7 {
+		t.Fatalf("err = %v", err)
+	}
+	if !strings.Contains(out.String(), "build.dt:7: Error: node n9 not found") {
+		t.Errorf("out = %q", out.String())
+	}
+	if s.Tree.RootID != "" {
+		t.Error("commands after the failure should not run")
+	}
+	if err.Error() != "build.dt: stopped at line 7" {
+		t.Errorf("message = %q", err.Error())
+	}
+}
+
+func TestRunScriptContinueOnError(t *testing.T) {
+	var out bytes.Buffer
+	s := NewSession(&out)
+	script := sampleScript + "frobnicate\n"
+	err := s.RunScript(strings.NewReader(script), "build.dt", true)
+	var se *ScriptError
+	if !errors.As(err, &se) || se.Stopped || len(se.Lines) != 2 {
+		t.Fatalf("err = %v", err)
+	}
+	if s.Tree.RootID != "n1" {
+		t.Error("commands after the failure should run")
+	}
+	if !strings.Contains(out.String(), "build.dt:9: Unknown command: frobnicate") {
+		t.Errorf("out = %q", out.String())
+	}
+	if err.Error() != "build.dt: 2 of its commands failed (lines 7, 9)" {
+		t.Errorf("message = %q", err.Error())
+	}
+}
+
+func TestRunScriptRollsBackTransaction(t *testing.T) {
+	var out bytes.Buffer
+	s := NewSession(&out)
+	script := "add decision \"q1\"\nbegin\nadd action \"a1\"\nconnect n1 n2\nconnect n1 n9\ncommit\n"
+	if err := s.RunScript(strings.NewReader(script), "tx.dt", false); err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(out.String(), "tx.dt: rolled back 2 changes since begin") {
+		t.Errorf("out = %q", out.String())
+	}
+	if len(s.Tree.Nodes) != 1 || len(s.Tree.Edges) != 0 || s.History.InTransaction() {
+		t.Errorf("transaction not rolled back: %d nodes, %d edges", len(s.Tree.Nodes), len(s.Tree.Edges))
+	}
+}
+
+func TestRunScriptQuit(t *testing.T) {
+	s := NewSession(&bytes.Buffer{})
+	if err := s.RunScript(strings.NewReader("add action A\nquit\nadd action B\n"), "q.dt", false); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Tree.Nodes) != 1 {
+		t.Errorf("got %d nodes, want 1", len(s.Tree.Nodes))
+	}
+}
+
+func TestCmdSource(t *testing.T) {
+	inner := writeScript(t, "inner.dt", "add action Inner\n")
+	outer := writeScript(t, "outer.dt", "add decision Outer\nsource "+inner+"\nconnect n1 n2\n")
+	s, out := runCommands(t, "source "+outer, "list")
+	if !strings.Contains(out, "Connected n1 -> n2") || !strings.Contains(out, `n2 [action] "Inner"`) {
+		t.Errorf("out = %q", out)
+	}
+	if len(s.Tree.Edges) != 1 {
+		t.Errorf("got %d edges, want 1", len(s.Tree.Edges))
+	}
+
+	bad := writeScript(t, "bad.dt", "remove n9\nadd action After\n")
+	_, out = runCommands(t, "source "+bad)
+	if !strings.Contains(out, "bad.dt:1: Error") || !strings.Contains(out, "Error: "+bad+": stopped at line 1") {
+		t.Errorf("out = %q", out)
+	}
+	_, out = runCommands(t, "source "+bad+" --continue", "list")
+	if !strings.Contains(out, `"After"`) {
+		t.Errorf("--continue should keep going: %q", out)
+	}
+}
+
+func TestCmdSourceRecursion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "loop.dt")
+	if err := os.WriteFile(path, []byte("source "+path+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, out := runCommands(t, "source "+path)
+	if !strings.Contains(out, "nested more than 16 deep") {
+		t.Errorf("out = %q", out)
+	}
+}
+
+func TestMainScript(t *testing.T) {
+	path := writeScript(t, "build.dt", sampleScript)
+	code, out, errOut := runMain(t, "--script", path)
+	if code != ExitFailure {
+		t.Errorf("exit %d, want %d", code, ExitFailure)
+	}
+	if !strings.Contains(out, "Connected n1 -> n2") || strings.Contains(out, "Error") {
+		t.Errorf("stdout = %q", out)
+	}
+	if !strings.Contains(errOut, "build.dt:7: Error") || !strings.Contains(errOut, "stopped at line 7") {
+		t.Errorf("stderr = %q", errOut)
+	}
+
+	ok := writeScript(t, "ok.dt", "add action A\nlist\n")
+	if code, out, _ := runMain(t, "--script="+ok); code != ExitOK || !strings.Contains(out, `n1 [action] "A"`) {
+		t.Errorf("exit %d, stdout %q", code, out)
+	}
+	if code, _, _ := runMain(t, "--script"); code != ExitUsage {
+		t.Errorf("missing file: exit %d, want %d", code, ExitUsage)
+	}
+}
+
+func TestMainScriptFromStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Main([]string{"--script", "-"}, strings.NewReader("add action A\nbogus\n"), &stdout, &stderr)
+	if code != ExitFailure || !strings.Contains(stderr.String(), "stdin:2: Unknown command: bogus") {
+		t.Errorf("exit %d, stderr %q", code, stderr.String())
+	}
+}
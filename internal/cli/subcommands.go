@@ -0,0 +1,412 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/analysis"
+	"github.com/jllovet/decision-tree-cli/internal/diff"
+	"github.com/jllovet/decision-tree-cli/internal/expr"
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/preview"
+	"github.com/jllovet/decision-tree-cli/internal/storage"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// Exit codes returned by Main.
+const (
+	ExitOK      = 0 // success
+	ExitFailure = 1 // the command ran and failed (bad input file, invalid tree...)
+	ExitUsage   = 2 // the command line itself was wrong
+)
+
+// subcommand runs one non-interactive command and returns its exit code.
+type subcommand func(args []string, stdout, stderr io.Writer) int
+
+var subcommands = map[string]subcommand{
+	"render":   runRender,
+	"preview":  runPreview,
+	"list":     runList,
+	"validate": runValidate,
+	"lint":     runLint,
+	"paths":    runPaths,
+	"stats":    runStats,
+	"diff":     runDiff,
+	"eval":     runEval,
+	"fmt":      runFmt,
+}
+
+const mainUsage = `Usage:
+  dt                                       Start the interactive shell
+  dt --script <file> [--continue]          Run shell commands from a file ("-" for stdin)
+  dt render --format dot|mermaid|svg|html|plantuml|d2 <tree.json> [-o file] [--attrs k1,k2]
+            [--direction TB|LR|BT|RL] [--theme type=color,...] [--subtree id] [--depth n] [--highlight id,id,...]
+  dt preview <tree.json>                   Print the ASCII preview
+  dt list <tree.json>                      List all nodes
+  dt validate <tree.json>                  Check a tree file for problems
+  dt lint [--format text|json] [--disable rule,...] [--fail-on error|warning|info] <file>...
+                                           Check trees against the lint rules (--rules lists them)
+  dt lint --fix [--dry-run] <file>...      Repair trees in place first (--dry-run prints a diff)
+  dt paths [--format text|csv|json|gherkin] <tree.json> [-o file]
+                                           List every root-to-leaf path, e.g. as test scenarios
+  dt stats [--format text|json] <tree.json> Print size and complexity metrics
+  dt diff [--format text|json|dot|mermaid] <old.json> <new.json> [-o file]
+                                           Show what changed between two trees
+  dt eval <tree.json> <records.jsonl>      Route JSON records through the tree
+  dt fmt [-w|--check] <file>...            Print trees in canonical .dtree form
+  dt help                                  Show this help
+
+Exit status is 0 on success, 1 on failure and 2 for usage errors.
+`
+
+// Main runs the dt command line. With no arguments it starts the REPL, and
+// with --script it runs REPL commands from a file; otherwise the first
+// argument names a subcommand. Results go to stdout,
+// errors to stderr, and the return value is the process exit code.
+func Main(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		Run(stdin, stdout)
+		return ExitOK
+	}
+	switch {
+	case args[0] == "help" || args[0] == "-h" || args[0] == "--help":
+		fmt.Fprint(stdout, mainUsage)
+		return ExitOK
+	case args[0] == "--script" || strings.HasPrefix(args[0], "--script="):
+		return runScriptFile(args, stdin, stdout, stderr)
+	}
+	run, ok := subcommands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command: %s\n\n%s", args[0], mainUsage)
+		return ExitUsage
+	}
+	return run(args[1:], stdout, stderr)
+}
+
+func runRender(args []string, stdout, stderr io.Writer) int {
+	args, flags := splitFlags(args)
+	format := flags["format"]
+	if format == "" {
+		format = flags["f"]
+	}
+	out := flags["o"]
+	if out == "" {
+		out = flags["output"]
+	}
+	if format == "" && out != "" {
+		format = formatForExt(filepath.Ext(out))
+	}
+	if len(args) != 1 || format == "" {
+		fmt.Fprintln(stderr, "Usage: dt render --format dot|mermaid|svg|html|plantuml|d2 <tree.json> [-o file] [--attrs k1,k2]\n"+
+			"       [--direction TB|LR|BT|RL] [--theme type=color,...] [--subtree id] [--depth n] [--highlight id,id,...]")
+		return ExitUsage
+	}
+	var tooltipAttrs []string
+	if flags["attrs"] != "" {
+		tooltipAttrs = strings.Split(flags["attrs"], ",")
+	}
+	opts, err := renderOptions(flags)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitUsage
+	}
+	r := newRenderer(format, tooltipAttrs, opts)
+	if r == nil {
+		fmt.Fprintf(stderr, "Unknown format: %s (use 'dot', 'mermaid', 'svg', 'html', 'plantuml' or 'd2')\n", format)
+		return ExitUsage
+	}
+	t, err := loadTree(args[0], "")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	text, err := r.Render(t)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	if out == "" {
+		fmt.Fprint(stdout, text)
+		return ExitOK
+	}
+	if err := os.WriteFile(out, []byte(text), 0644); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// formatForExt guesses a render format from an output file extension.
+func formatForExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".dot", ".gv":
+		return "dot"
+	case ".mmd", ".mermaid":
+		return "mermaid"
+	case ".svg":
+		return "svg"
+	case ".html", ".htm":
+		return "html"
+	case ".puml", ".plantuml":
+		return "plantuml"
+	case ".d2":
+		return "d2"
+	}
+	return ""
+}
+
+func runPreview(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "Usage: dt preview <tree.json>")
+		return ExitUsage
+	}
+	t, err := loadTree(args[0], "")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	fmt.Fprintln(stdout, preview.Render(t))
+	return ExitOK
+}
+
+func runList(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "Usage: dt list <tree.json>")
+		return ExitUsage
+	}
+	t, err := loadTree(args[0], "")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	for _, line := range tree.ListNodes(t) {
+		fmt.Fprintln(stdout, line)
+	}
+	return ExitOK
+}
+
+// runValidate loads a tree, which checks its structure, then checks the
+// rules of its mode, its conditions and its branch probabilities.
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "Usage: dt validate <tree.json>")
+		return ExitUsage
+	}
+	t, err := loadTree(args[0], "")
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", args[0], err)
+		return ExitFailure
+	}
+	var problems []error
+	if !t.Graph {
+		if err := tree.CheckTreeMode(t); err != nil {
+			problems = append(problems, fmt.Errorf("%w (switch to graph mode to allow this)", err))
+		}
+	}
+	for _, id := range t.NodeIDs() {
+		if cond := t.Nodes[id].Condition; cond != "" {
+			if _, err := expr.Parse(cond); err != nil {
+				problems = append(problems, fmt.Errorf("node %s condition: %w", id, err))
+			}
+		}
+	}
+	problems = append(problems, analysis.CheckProbabilities(t)...)
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintf(stderr, "%s: %v\n", args[0], p)
+		}
+		return ExitFailure
+	}
+	fmt.Fprintf(stdout, "%s: OK (%d nodes, %d edges)\n", args[0], len(t.Nodes), len(t.Edges))
+	return ExitOK
+}
+
+// runEval routes each record through the tree and writes one JSON result
+// per line to stdout. It fails if any record could not be routed.
+func runEval(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 {
+		fmt.Fprintln(stderr, "Usage: dt eval <tree.json> <records.jsonl>")
+		return ExitUsage
+	}
+	t, err := loadTree(args[0], "")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	sum, err := evalFile(t, args[1], stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	if sum.Failed > 0 {
+		fmt.Fprintf(stderr, "%d of %d records failed\n", sum.Failed, sum.Records)
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// runFmt prints each tree in canonical .dtree form. With -w it rewrites
+// .dtree files in place instead, and with --check it lists the .dtree files
+// that are not canonical and fails if there are any. Any format storage
+// can load may be printed, which makes fmt a converter to .dtree as well.
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	files, flags := splitFlags(args, "w", "write", "check")
+	write := flags["w"] != "" || flags["write"] != ""
+	check := flags["check"] != ""
+	if len(files) == 0 || (write && check) {
+		fmt.Fprintln(stderr, "Usage: dt fmt [-w|--check] <file>...")
+		return ExitUsage
+	}
+	code := ExitOK
+	for _, path := range files {
+		if (write || check) && !storage.IsDTree(path) {
+			fmt.Fprintf(stderr, "%s: not a .dtree file\n", path)
+			code = ExitFailure
+			continue
+		}
+		t, err := loadTree(path, "")
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			code = ExitFailure
+			continue
+		}
+		text := storage.FormatDTree(t)
+		if !write && !check {
+			fmt.Fprint(stdout, text)
+			continue
+		}
+		current, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			code = ExitFailure
+			continue
+		}
+		if string(current) == text {
+			continue
+		}
+		if check {
+			fmt.Fprintln(stdout, path)
+			code = ExitFailure
+			continue
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			code = ExitFailure
+		}
+	}
+	return code
+}
+
+func runPaths(args []string, stdout, stderr io.Writer) int {
+	args, flags := splitFlags(args)
+	format := strings.ToLower(flags["format"])
+	if format == "" {
+		format = "text"
+	}
+	out := flags["o"]
+	if out == "" {
+		out = flags["output"]
+	}
+	if len(args) != 1 || !slices.Contains(analysis.PathFormats(), format) {
+		fmt.Fprintln(stderr, "Usage: dt paths [--format text|csv|json|gherkin] <tree.json> [-o file]")
+		return ExitUsage
+	}
+	t, err := loadTree(args[0], "")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	paths, err := analysis.Paths(t)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	var buf bytes.Buffer
+	if err := analysis.WritePaths(&buf, t, paths, format); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	if out == "" {
+		stdout.Write(buf.Bytes())
+		return ExitOK
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+func runStats(args []string, stdout, stderr io.Writer) int {
+	args, flags := splitFlags(args)
+	format := strings.ToLower(flags["format"])
+	if len(args) != 1 || (format != "" && format != "text" && format != "json") {
+		fmt.Fprintln(stderr, "Usage: dt stats [--format text|json] <tree.json>")
+		return ExitUsage
+	}
+	t, err := loadTree(args[0], "")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	st, err := analysis.ComputeStats(t)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	if format == "json" {
+		if err := writeJSON(stdout, st); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitFailure
+		}
+		return ExitOK
+	}
+	fmt.Fprint(stdout, analysis.FormatStats(st))
+	return ExitOK
+}
+
+// runDiff implements "dt diff". Like git diff, it succeeds whether or not
+// the trees differ.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	args, flags := splitFlags(args)
+	format := strings.ToLower(flags["format"])
+	if format == "" {
+		format = "text"
+	}
+	out := flags["o"]
+	if out == "" {
+		out = flags["output"]
+	}
+	if len(args) != 2 || !slices.Contains(diff.Formats(), format) {
+		fmt.Fprintln(stderr, "Usage: dt diff [--format text|json|dot|mermaid] <old.json> <new.json> [-o file]")
+		return ExitUsage
+	}
+	var trees [2]*model.Tree
+	for i, path := range args {
+		t, err := loadTree(path, "")
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return ExitFailure
+		}
+		trees[i] = t
+	}
+	var buf bytes.Buffer
+	if err := diff.Write(&buf, trees[0], trees[1], diff.Compare(trees[0], trees[1]), format); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	if out == "" {
+		stdout.Write(buf.Bytes())
+		return ExitOK
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitFailure
+	}
+	return ExitOK
+}
@@ -0,0 +1,528 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/storage"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// runMain runs Main with the given arguments and returns its exit code,
+// stdout and stderr.
+func runMain(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Main(args, strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// saveSample writes the browse sample tree to a temp file.
+func saveSample(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sample.json")
+	if err := storage.Save(buildSampleTree(), path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMainRender(t *testing.T) {
+	path := saveSample(t)
+	code, out, errOut := runMain(t, "render", "--format", "mermaid", path)
+	if code != ExitOK || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	if !strings.HasPrefix(out, "flowchart") {
+		t.Errorf("stdout = %q", out)
+	}
+
+	outPath := filepath.Join(t.TempDir(), "out.dot")
+	code, out, _ = runMain(t, "render", path, "-o", outPath)
+	if code != ExitOK || out != "" {
+		t.Fatalf("exit %d, stdout %q", code, out)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil || !strings.HasPrefix(string(data), "digraph") {
+		t.Errorf("output file = %q, %v", data, err)
+	}
+
+	outPath = filepath.Join(t.TempDir(), "out.svg")
+	if code, _, errOut = runMain(t, "render", path, "-o", outPath); code != ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	data, err = os.ReadFile(outPath)
+	if err != nil || !strings.HasPrefix(string(data), "<svg ") {
+		t.Errorf("output file = %q, %v", data, err)
+	}
+
+	outPath = filepath.Join(t.TempDir(), "guide.html")
+	if code, _, errOut = runMain(t, "render", path, "-o", outPath); code != ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	data, err = os.ReadFile(outPath)
+	if err != nil || !strings.HasPrefix(string(data), "<!DOCTYPE html>") {
+		t.Errorf("output file = %q, %v", data, err)
+	}
+
+	outPath = filepath.Join(t.TempDir(), "flow.puml")
+	if code, _, errOut = runMain(t, "render", path, "-o", outPath); code != ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	data, err = os.ReadFile(outPath)
+	if err != nil || !strings.HasPrefix(string(data), "@startuml") {
+		t.Errorf("output file = %q, %v", data, err)
+	}
+
+	outPath = filepath.Join(t.TempDir(), "flow.d2")
+	if code, _, errOut = runMain(t, "render", path, "-o", outPath, "--direction", "left"); code != ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	data, err = os.ReadFile(outPath)
+	if err != nil || !strings.HasPrefix(string(data), "direction: left\n") {
+		t.Errorf("output file = %q, %v", data, err)
+	}
+}
+
+func TestMainRenderOptions(t *testing.T) {
+	path := saveSample(t)
+	code, out, errOut := runMain(t, "render", "--format", "dot", path,
+		"--direction", "LR", "--subtree", "n2", "--depth", "1", "--highlight", "n2,n3", "--theme", "io=plum")
+	if code != ExitOK || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	for _, want := range []string{"rankdir=LR;", `n2 -> n3 [label="yes", color="#dd3333", penwidth=2];`, `fillcolor="plum"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+	if strings.Contains(out, "n1 [") {
+		t.Errorf("subtree output includes n1: %q", out)
+	}
+}
+
+func TestMainRenderErrors(t *testing.T) {
+	path := saveSample(t)
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"render", path}, ExitUsage, "Usage: dt render"},
+		{[]string{"render", "--format", "png", path}, ExitUsage, "Unknown format: png"},
+		{[]string{"render", "--format=dot", "missing.json"}, ExitFailure, "Error: read"},
+		{[]string{"render", "--format=dot", path, "--depth", "deep"}, ExitUsage, `invalid depth "deep"`},
+		{[]string{"render", "--format=dot", path, "--theme", "shape=red"}, ExitUsage, "Error:"},
+		{[]string{"render", "--format=dot", path, "--highlight", "n1,n3"}, ExitFailure, "no edge from n1 to n3"},
+		{[]string{"render", "--format=plantuml", path, "--direction", "LR"}, ExitFailure, "top to bottom"},
+	}
+	for _, tt := range tests {
+		code, out, errOut := runMain(t, tt.args...)
+		if code != tt.code || !strings.Contains(errOut, tt.want) || out != "" {
+			t.Errorf("%v: exit %d, stdout %q, stderr %q; want exit %d and %q", tt.args, code, out, errOut, tt.code, tt.want)
+		}
+	}
+}
+
+func TestMainPreviewAndList(t *testing.T) {
+	path := saveSample(t)
+	code, out, _ := runMain(t, "preview", path)
+	if code != ExitOK || !strings.Contains(out, "├──") {
+		t.Errorf("preview: exit %d, stdout %q", code, out)
+	}
+	code, out, _ = runMain(t, "list", path)
+	if code != ExitOK || !strings.Contains(out, "n1 [") || !strings.Contains(out, "(root)") {
+		t.Errorf("list: exit %d, stdout %q", code, out)
+	}
+	if code, _, _ := runMain(t, "list"); code != ExitUsage {
+		t.Errorf("list without file: exit %d, want %d", code, ExitUsage)
+	}
+}
+
+func TestMainLint(t *testing.T) {
+	path := saveSample(t)
+	code, out, errOut := runMain(t, "lint", path)
+	if code != ExitOK || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	for _, want := range []string{
+		path + `: warning: action n3 "Grant" is a leaf but not a start/end node [leaf-not-end]`,
+		path + ": 2 warnings",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+
+	code, _, _ = runMain(t, "lint", "--fail-on", "warning", path)
+	if code != ExitFailure {
+		t.Errorf("--fail-on warning: exit %d", code)
+	}
+	code, out, _ = runMain(t, "lint", "--disable", "leaf-not-end", "--fail-on", "warning", path)
+	if code != ExitOK || !strings.Contains(out, "no problems") {
+		t.Errorf("--disable: exit %d, stdout %q", code, out)
+	}
+
+	bad := buildSampleTree()
+	tree.EditNodeType(bad, "n3", model.Decision)
+	badPath := filepath.Join(t.TempDir(), "bad.json")
+	if err := storage.Save(bad, badPath); err != nil {
+		t.Fatal(err)
+	}
+	code, out, _ = runMain(t, "lint", "--format", "json", path, badPath)
+	if code != ExitFailure {
+		t.Errorf("error finding: exit %d", code)
+	}
+	var findings []struct {
+		File, Rule, Severity, Node string
+	}
+	if err := json.Unmarshal([]byte(out), &findings); err != nil {
+		t.Fatalf("%v in %q", err, out)
+	}
+	if len(findings) != 5 || findings[2].File != badPath || findings[2].Rule != "decision-branches" ||
+		findings[2].Severity != "error" || findings[2].Node != "n3" {
+		t.Errorf("findings = %+v", findings)
+	}
+}
+
+func TestMainLintUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"lint"},
+		{"lint", "--format", "xml", "x.json"},
+		{"lint", "--disable", "nope", "x.json"},
+		{"lint", "--fail-on", "fatal", "x.json"},
+		{"lint", "--dry-run", "x.json"},
+		{"lint", "--fix", "--format", "json", "x.json"},
+	} {
+		if code, _, _ := runMain(t, args...); code != ExitUsage {
+			t.Errorf("%v: exit %d, want %d", args, code, ExitUsage)
+		}
+	}
+	code, out, _ := runMain(t, "lint", "--rules")
+	if code != ExitOK || !strings.Contains(out, "unreachable") {
+		t.Errorf("--rules: exit %d, stdout %q", code, out)
+	}
+}
+
+func TestMainLintFix(t *testing.T) {
+	path := saveSample(t)
+	before, _ := os.ReadFile(path)
+	code, out, errOut := runMain(t, "lint", "--fix", "--dry-run", path)
+	if code != ExitOK || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	for _, want := range []string{
+		"--- " + path + " (before)\n",
+		"+      startend \"End\" #n5\n",
+		path + ": would make 4 changes\n",
+		path + ": no problems\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in %q", want, out)
+		}
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Error("--dry-run wrote the file")
+	}
+
+	code, out, _ = runMain(t, "lint", "--fix", "--fail-on", "warning", path)
+	if code != ExitOK || !strings.Contains(out, path+": fixed with 4 changes") {
+		t.Fatalf("exit %d, stdout %q", code, out)
+	}
+	fixed, err := storage.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := storage.LoadHistory(path)
+	if err != nil || h == nil {
+		t.Fatalf("history %v, %v", h, err)
+	}
+	if err := h.Undo(fixed); err != nil || len(fixed.Nodes) != 4 {
+		t.Errorf("undo: %v, %d nodes", err, len(fixed.Nodes))
+	}
+
+	// A corrupt journal is reported, not silently replaced.
+	broken := saveSample(t)
+	os.WriteFile(storage.HistoryPath(broken), []byte("{not json"), 0644)
+	code, _, errOut = runMain(t, "lint", "--fix", broken)
+	if code != ExitFailure || !strings.Contains(errOut, "unmarshal history") {
+		t.Errorf("corrupt history: exit %d, stderr %q", code, errOut)
+	}
+	if data, _ := os.ReadFile(storage.HistoryPath(broken)); string(data) != "{not json" {
+		t.Errorf("corrupt history was overwritten: %q", data)
+	}
+
+	// A stale one is replaced.
+	stale := saveSample(t)
+	os.WriteFile(storage.HistoryPath(stale), []byte(`{"tree_sum":"x"}`), 0644)
+	if code, out, errOut := runMain(t, "lint", "--fix", stale); code != ExitOK || !strings.Contains(out, "fixed with") {
+		t.Errorf("stale history: exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+
+	mmd := filepath.Join(t.TempDir(), "flow.mmd")
+	os.WriteFile(mmd, []byte("flowchart TD\n  A[Go]\n"), 0644)
+	if code, _, errOut := runMain(t, "lint", "--fix", mmd); code != ExitFailure || !strings.Contains(errOut, "cannot fix an imported file") {
+		t.Errorf("imported file: exit %d, stderr %q", code, errOut)
+	}
+}
+
+func TestMainValidate(t *testing.T) {
+	path := saveSample(t)
+	code, out, _ := runMain(t, "validate", path)
+	if code != ExitOK || !strings.Contains(out, "OK (") {
+		t.Errorf("exit %d, stdout %q", code, out)
+	}
+
+	bad := buildSampleTree()
+	bad.Graph = true
+	tree.ConnectNodes(bad, "n3", "n1", "again")
+	bad.Graph = false
+	bad.Nodes["n1"].Condition = "x >"
+	p := 2.0
+	bad.Edges[0].Probability = &p
+	badPath := filepath.Join(t.TempDir(), "bad.json")
+	if err := storage.Save(bad, badPath); err != nil {
+		t.Fatal(err)
+	}
+	code, out, errOut := runMain(t, "validate", badPath)
+	if code != ExitFailure || out != "" {
+		t.Errorf("exit %d, stdout %q", code, out)
+	}
+	for _, want := range []string{"part of a cycle", "node n1 condition", "branch"} {
+		if !strings.Contains(errOut, want) {
+			t.Errorf("stderr missing %q: %q", want, errOut)
+		}
+	}
+
+	if err := os.WriteFile(badPath, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, errOut := runMain(t, "validate", badPath); code != ExitFailure || !strings.Contains(errOut, "unmarshal") {
+		t.Errorf("malformed file: exit %d, stderr %q", code, errOut)
+	}
+}
+
+func TestMainPaths(t *testing.T) {
+	path := saveSample(t)
+	code, out, errOut := runMain(t, "paths", "--format", "csv", path)
+	if code != ExitOK || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	want := "path,depth,outcome,steps,nodes\n" +
+		"1,2,Grant,Start -> Auth? -[yes]-> Grant,n1 n2 n3\n" +
+		"2,2,Show login,Start -> Auth? -[no]-> Show login,n1 n2 n4\n"
+	if out != want {
+		t.Errorf("stdout = %q, want %q", out, want)
+	}
+
+	outFile := filepath.Join(t.TempDir(), "paths.json")
+	if code, _, errOut := runMain(t, "paths", "--format", "json", path, "-o", outFile); code != ExitOK {
+		t.Fatalf("-o: exit %d, stderr %q", code, errOut)
+	}
+	if data, err := os.ReadFile(outFile); err != nil || !strings.Contains(string(data), `"count": 2`) {
+		t.Errorf("output file = %q, %v", data, err)
+	}
+
+	for _, args := range [][]string{{"paths"}, {"paths", "--format", "xml", path}} {
+		if code, _, _ := runMain(t, args...); code != ExitUsage {
+			t.Errorf("%v: exit %d, want %d", args, code, ExitUsage)
+		}
+	}
+}
+
+func TestMainStats(t *testing.T) {
+	path := saveSample(t)
+	code, out, errOut := runMain(t, "stats", "--format", "json", path)
+	if code != ExitOK || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	var st struct {
+		Nodes      int            `json:"nodes"`
+		NodeTypes  map[string]int `json:"node_types"`
+		Paths      int            `json:"paths"`
+		MaxDepth   int            `json:"max_depth"`
+		Branching  map[string]int `json:"branching"`
+		Complexity int            `json:"complexity"`
+	}
+	if err := json.Unmarshal([]byte(out), &st); err != nil {
+		t.Fatalf("%v in %q", err, out)
+	}
+	if st.Nodes != 4 || st.NodeTypes["io"] != 1 || st.Paths != 2 || st.MaxDepth != 2 ||
+		st.Branching["2"] != 1 || st.Complexity != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	code, out, _ = runMain(t, "stats", path)
+	if code != ExitOK || !strings.Contains(out, "Complexity:  2\n") {
+		t.Errorf("exit %d, stdout %q", code, out)
+	}
+	if code, _, _ := runMain(t, "stats"); code != ExitUsage {
+		t.Errorf("no file: exit %d, want %d", code, ExitUsage)
+	}
+}
+
+func TestMainDiff(t *testing.T) {
+	oldPath := saveSample(t)
+	tr := buildSampleTree()
+	tr.Nodes["n3"].Label = "Grant access"
+	delete(tr.Nodes, "n4")
+	tr.Edges = tr.Edges[:2]
+	newPath := filepath.Join(t.TempDir(), "new.json")
+	if err := storage.Save(tr, newPath); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut := runMain(t, "diff", oldPath, newPath)
+	if code != ExitOK || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	want := `- node n4 io "Show login"
+~ node n3 label "Grant" -> "Grant access"
+- edge n2 -> n4 "no"
+Nodes: 0 added, 1 removed, 1 changed; edges: 0 added, 1 removed, 0 relabeled
+`
+	if out != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", out, want)
+	}
+
+	code, out, _ = runMain(t, "diff", "--format", "mermaid", oldPath, newPath)
+	if code != ExitOK || !strings.Contains(out, "removed_n4") || !strings.Contains(out, "style n3 ") {
+		t.Errorf("mermaid: exit %d, stdout %q", code, out)
+	}
+
+	outFile := filepath.Join(t.TempDir(), "diff.json")
+	if code, _, errOut := runMain(t, "diff", "--format", "json", oldPath, oldPath, "-o", outFile); code != ExitOK {
+		t.Fatalf("-o: exit %d, stderr %q", code, errOut)
+	}
+	if data, err := os.ReadFile(outFile); err != nil || !strings.Contains(string(data), `"nodes": []`) {
+		t.Errorf("output file = %q, %v", data, err)
+	}
+
+	for _, args := range [][]string{{"diff", oldPath}, {"diff", "--format", "svg", oldPath, newPath}} {
+		if code, _, _ := runMain(t, args...); code != ExitUsage {
+			t.Errorf("%v: exit %d, want %d", args, code, ExitUsage)
+		}
+	}
+	if code, _, _ := runMain(t, "diff", oldPath, filepath.Join(t.TempDir(), "missing.json")); code != ExitFailure {
+		t.Errorf("missing file: exit %d, want %d", code, ExitFailure)
+	}
+}
+
+func TestMainEval(t *testing.T) {
+	treePath, recPath := writeRoutingFiles(t, "{\"age\": 30}\n{\"age\": 9}\n")
+	code, out, errOut := runMain(t, "eval", treePath, recPath)
+	if code != ExitOK {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	want := `{"line":1,"leaf":"n2","label":"Allow","path":["n1","n2"]}
+{"line":2,"leaf":"n3","label":"Deny","path":["n1","n3"]}
+`
+	if out != want {
+		t.Errorf("stdout =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestMainEvalFailures(t *testing.T) {
+	treePath, recPath := writeRoutingFiles(t, "{\"age\": 30}\n{\"name\": \"x\"}\n")
+	code, out, errOut := runMain(t, "eval", treePath, recPath)
+	if code != ExitFailure {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(out, "undefined variable age") {
+		t.Errorf("stdout = %q", out)
+	}
+	if !strings.Contains(errOut, "1 of 2 records failed") {
+		t.Errorf("stderr = %q", errOut)
+	}
+	if code, _, _ := runMain(t, "eval", treePath); code != ExitUsage {
+		t.Errorf("usage exit code = %d, want 2", code)
+	}
+	if code, _, _ := runMain(t, "eval", "missing.json", recPath); code != ExitFailure {
+		t.Errorf("missing tree exit code = %d, want 1", code)
+	}
+}
+
+func TestMainHelpAndUnknown(t *testing.T) {
+	code, out, _ := runMain(t, "help")
+	if code != ExitOK || !strings.Contains(out, "dt validate <tree.json>") {
+		t.Errorf("help: exit %d, stdout %q", code, out)
+	}
+	code, _, errOut := runMain(t, "frobnicate")
+	if code != ExitUsage || !strings.Contains(errOut, "Unknown command: frobnicate") {
+		t.Errorf("unknown: exit %d, stderr %q", code, errOut)
+	}
+}
+
+func TestMainStartsREPL(t *testing.T) {
+	var stdout bytes.Buffer
+	code := Main(nil, strings.NewReader("add action Go\nquit\n"), &stdout, &bytes.Buffer{})
+	if code != ExitOK || !strings.Contains(stdout.String(), "Added node n1") {
+		t.Errorf("exit %d, stdout %q", code, stdout.String())
+	}
+}
+
+func TestMainFmt(t *testing.T) {
+	path := saveSample(t)
+	code, out, errOut := runMain(t, "fmt", path)
+	if code != ExitOK || errOut != "" {
+		t.Fatalf("exit %d, stderr %q", code, errOut)
+	}
+	if !strings.Contains(out, "#n1") || !strings.Contains(out, "->") {
+		t.Errorf("stdout = %q", out)
+	}
+
+	messy := filepath.Join(t.TempDir(), "messy.dtree")
+	os.WriteFile(messy, []byte("tree \"x\"\naction   Go   # first\n    -> startend Done\n"), 0644)
+	code, out, _ = runMain(t, "fmt", "--check", messy)
+	if code != ExitFailure || out != messy+"\n" {
+		t.Errorf("--check: exit %d, stdout %q", code, out)
+	}
+	if code, _, errOut = runMain(t, "fmt", "-w", messy); code != ExitOK {
+		t.Fatalf("-w: exit %d, stderr %q", code, errOut)
+	}
+	data, _ := os.ReadFile(messy)
+	want := "tree \"x\"\n\naction \"Go\" #n1\n  startend \"Done\" #n2\n"
+	if string(data) != want {
+		t.Errorf("rewritten file = %q, want %q", data, want)
+	}
+	if code, out, _ = runMain(t, "fmt", "--check", messy); code != ExitOK || out != "" {
+		t.Errorf("--check after -w: exit %d, stdout %q", code, out)
+	}
+}
+
+func TestMainFmtErrors(t *testing.T) {
+	path := saveSample(t)
+	bad := filepath.Join(t.TempDir(), "bad.dtree")
+	os.WriteFile(bad, []byte("action A\n  -> @n9\n"), 0644)
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{"fmt"}, ExitUsage, "Usage: dt fmt"},
+		{[]string{"fmt", "-w", "--check", bad}, ExitUsage, "Usage: dt fmt"},
+		{[]string{"fmt", "-w", path}, ExitFailure, "not a .dtree file"},
+		{[]string{"fmt", bad}, ExitFailure, "line 2: unknown node @n9"},
+	}
+	for _, tt := range tests {
+		code, _, errOut := runMain(t, tt.args...)
+		if code != tt.code || !strings.Contains(errOut, tt.want) {
+			t.Errorf("%v: exit %d, stderr %q; want %d, %q", tt.args, code, errOut, tt.code, tt.want)
+		}
+	}
+}
+
+func TestMainImportsDiagrams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.mmd")
+	os.WriteFile(path, []byte("flowchart TB\n  a([Start]) --> b{Ok?}\n  b -->|yes| c[Ship]\n"), 0644)
+	code, out, errOut := runMain(t, "fmt", path)
+	want := "tree \"flow\"\n\nstartend \"Start\" #a\n  decision \"Ok?\" #b\n    yes -> action \"Ship\" #c\n"
+	if code != ExitOK || out != want {
+		t.Errorf("exit %d, stdout %q, stderr %q; want %q", code, out, errOut, want)
+	}
+}
@@ -17,7 +17,7 @@ var templates = []treeTemplate{
 		Name:        "auth-flow",
 		Description: "Authentication flow",
 		Build: func() *model.Tree {
-			t := model.NewTree("auth-flow")
+			t := newGraphTree("auth-flow")
 			tree.AddNode(t, model.StartEnd, "Start")         // n1
 			tree.AddNode(t, model.Decision, "Authenticated?") // n2
 			tree.AddNode(t, model.Action, "Grant access")     // n3
@@ -36,7 +36,7 @@ var templates = []treeTemplate{
 		Name:        "approval",
 		Description: "Approval workflow",
 		Build: func() *model.Tree {
-			t := model.NewTree("approval")
+			t := newGraphTree("approval")
 			tree.AddNode(t, model.StartEnd, "Start")          // n1
 			tree.AddNode(t, model.Action, "Submit request")    // n2
 			tree.AddNode(t, model.Decision, "Approved?")       // n3
@@ -57,7 +57,7 @@ var templates = []treeTemplate{
 		Name:        "troubleshooting",
 		Description: "Troubleshooting guide",
 		Build: func() *model.Tree {
-			t := model.NewTree("troubleshooting")
+			t := newGraphTree("troubleshooting")
 			tree.AddNode(t, model.StartEnd, "Start")           // n1
 			tree.AddNode(t, model.Decision, "Is it plugged in?") // n2
 			tree.AddNode(t, model.Action, "Plug it in")         // n3
@@ -82,7 +82,7 @@ var templates = []treeTemplate{
 		Name:        "bug-triage",
 		Description: "Bug triage and incident response",
 		Build: func() *model.Tree {
-			t := model.NewTree("bug-triage")
+			t := newGraphTree("bug-triage")
 			tree.AddNode(t, model.StartEnd, "Bug reported")                // n1
 			tree.AddNode(t, model.Decision, "Reproducible?")               // n2
 			tree.AddNode(t, model.IO, "Request reproduction steps")        // n3
@@ -127,7 +127,7 @@ var templates = []treeTemplate{
 		Name:        "hiring",
 		Description: "Hiring pipeline",
 		Build: func() *model.Tree {
-			t := model.NewTree("hiring")
+			t := newGraphTree("hiring")
 			tree.AddNode(t, model.StartEnd, "Application received")         // n1
 			tree.AddNode(t, model.Decision, "Meets minimum qualifications?") // n2
 			tree.AddNode(t, model.IO, "Send rejection email")               // n3
@@ -173,7 +173,7 @@ var templates = []treeTemplate{
 		Name:        "medical-triage",
 		Description: "Emergency room triage assessment",
 		Build: func() *model.Tree {
-			t := model.NewTree("medical-triage")
+			t := newGraphTree("medical-triage")
 			tree.AddNode(t, model.StartEnd, "Patient arrives")                // n1
 			tree.AddNode(t, model.Decision, "Conscious?")                     // n2
 			tree.AddNode(t, model.Action, "Call code team")                   // n3
@@ -232,7 +232,7 @@ var templates = []treeTemplate{
 		Name:        "loan-application",
 		Description: "Loan approval decision process",
 		Build: func() *model.Tree {
-			t := model.NewTree("loan-application")
+			t := newGraphTree("loan-application")
 			tree.AddNode(t, model.StartEnd, "Application submitted")        // n1
 			tree.AddNode(t, model.IO, "Pull credit report")                // n2
 			tree.AddNode(t, model.Decision, "Credit score >= 650?")        // n3
@@ -279,6 +279,15 @@ var templates = []treeTemplate{
 	},
 }
 
+// newGraphTree returns an empty tree in graph mode. Every template loops
+// back to an earlier step or shares a step between branches, which tree
+// mode rejects.
+func newGraphTree(name string) *model.Tree {
+	t := model.NewTree(name)
+	t.Graph = true
+	return t
+}
+
 // findTemplate returns the template with the given name, or nil if not found.
 func findTemplate(name string) *treeTemplate {
 	for i := range templates {
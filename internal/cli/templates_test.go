@@ -43,3 +43,21 @@ func TestFindTemplateUnknown(t *testing.T) {
 		t.Error("expected nil for unknown template")
 	}
 }
+
+func TestTemplatesKeepLoopBackEdges(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+	}{
+		{"auth-flow", "n5", "n2"},
+		{"approval", "n6", "n2"},
+		{"troubleshooting", "n6", "n4"},
+		{"hiring", "n15", "n11"},
+	}
+	for _, tc := range tests {
+		tr := findTemplate(tc.name).Build()
+		if !tr.HasEdge(tc.from, tc.to) {
+			t.Errorf("%s: missing edge %s -> %s", tc.name, tc.from, tc.to)
+		}
+	}
+}
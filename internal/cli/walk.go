@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// walkStep records one node visited during a walk and how it was reached.
+type walkStep struct {
+	nodeID    string
+	edgeLabel string // label of the edge taken into this node
+	chosen    bool   // true if the user picked this branch (vs. auto-advance)
+}
+
+// cmdWalk runs the tree as a guided questionnaire, starting at the root and
+// prompting for a branch at every decision until an end node is reached.
+func (s *Session) cmdWalk() error {
+	if s.In == nil {
+		return errors.New("walk requires interactive input")
+	}
+	if s.Tree.RootID == "" {
+		return errors.New("no root set")
+	}
+	if s.Tree.GetNode(s.Tree.RootID) == nil {
+		return fmt.Errorf("root node %q not found", s.Tree.RootID)
+	}
+
+	path := []walkStep{{nodeID: s.Tree.RootID}}
+	for {
+		step := path[len(path)-1]
+		n := s.Tree.GetNode(step.nodeID)
+		fmt.Fprintf(s.Out, "%s%s\n", edgePrefix(step.edgeLabel), nodeDecorator(n))
+
+		children := s.Tree.Children(n.ID)
+		if walkFinished(n, children, len(path)) {
+			fmt.Fprintf(s.Out, "Path: %s\n", walkPath(s.Tree, path))
+			line, err := s.readLine("Reached the end (b=back, Enter=exit): ")
+			if err != nil {
+				return nil
+			}
+			if isBackInput(line) {
+				path = stepBack(s, path)
+				continue
+			}
+			return nil
+		}
+
+		if len(children) == 1 && n.Type != model.Decision {
+			next := children[0]
+			if loopsWithoutChoice(path, next.ToID) {
+				fmt.Fprintf(s.Out, "Loop detected at %s with no decision to make\n", next.ToID)
+				fmt.Fprintf(s.Out, "Path: %s\n", walkPath(s.Tree, path))
+				return nil
+			}
+			path = append(path, walkStep{nodeID: next.ToID, edgeLabel: next.Label})
+			continue
+		}
+
+		for i, e := range children {
+			fmt.Fprintf(s.Out, "  %d) %s\n", i+1, optionText(s.Tree, e))
+		}
+		for {
+			line, err := s.readLine(fmt.Sprintf("Choose [1-%d, b=back, q=quit]: ", len(children)))
+			if err != nil {
+				fmt.Fprintln(s.Out, "Walk aborted")
+				return nil
+			}
+			line = strings.TrimSpace(line)
+			if line == "q" || line == "quit" {
+				fmt.Fprintf(s.Out, "Path: %s\n", walkPath(s.Tree, path))
+				return nil
+			}
+			if isBackInput(line) {
+				path = stepBack(s, path)
+				break
+			}
+			e, ok := matchOption(children, line)
+			if !ok {
+				fmt.Fprintf(s.Out, "Invalid choice %q\n", line)
+				continue
+			}
+			path = append(path, walkStep{nodeID: e.ToID, edgeLabel: e.Label, chosen: true})
+			break
+		}
+	}
+}
+
+// walkFinished reports whether the walk stops at n: either n is a leaf, or
+// it is a start/end node other than the one the walk began on.
+func walkFinished(n *model.Node, children []model.Edge, depth int) bool {
+	if len(children) == 0 {
+		return true
+	}
+	return n.Type == model.StartEnd && depth > 1
+}
+
+// loopsWithoutChoice reports whether auto-advancing to nodeID would revisit
+// a node seen since the user last made a choice, which in graph mode would
+// otherwise loop forever.
+func loopsWithoutChoice(path []walkStep, nodeID string) bool {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].nodeID == nodeID {
+			return true
+		}
+		if path[i].chosen {
+			return false
+		}
+	}
+	return false
+}
+
+// stepBack pops the path back to the most recent node where the user made a
+// choice, so that the choice can be made again.
+func stepBack(s *Session, path []walkStep) []walkStep {
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i].chosen {
+			fmt.Fprintln(s.Out, "Back")
+			return path[:i]
+		}
+	}
+	fmt.Fprintln(s.Out, "Already at the start")
+	return path[:1]
+}
+
+func isBackInput(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "b" || line == "back"
+}
+
+// matchOption resolves user input to an outgoing edge, either by its
+// 1-based position or by a case-insensitive match on the edge label.
+func matchOption(children []model.Edge, input string) (model.Edge, bool) {
+	if i, err := strconv.Atoi(input); err == nil {
+		if i >= 1 && i <= len(children) {
+			return children[i-1], true
+		}
+		return model.Edge{}, false
+	}
+	for _, e := range children {
+		if e.Label != "" && strings.EqualFold(e.Label, input) {
+			return e, true
+		}
+	}
+	return model.Edge{}, false
+}
+
+func optionText(t *model.Tree, e model.Edge) string {
+	if e.Label != "" {
+		return e.Label
+	}
+	if n := t.GetNode(e.ToID); n != nil {
+		return "→ " + nodeDecorator(n)
+	}
+	return "→ " + e.ToID
+}
+
+func edgePrefix(label string) string {
+	if label == "" {
+		return ""
+	}
+	return "[" + label + "] "
+}
+
+// walkPath formats the visited nodes as a single line, in the same notation
+// as the ASCII preview.
+func walkPath(t *model.Tree, path []walkStep) string {
+	parts := make([]string, len(path))
+	for i, step := range path {
+		parts[i] = edgePrefix(step.edgeLabel) + nodeDecorator(t.GetNode(step.nodeID))
+	}
+	return strings.Join(parts, " → ")
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func walkSession(t *testing.T, input string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	s := NewSession(&buf)
+	s.Tree = buildSampleTree()
+	s.In = strings.NewReader(input)
+	s.Execute(Parse("walk"))
+	return buf.String()
+}
+
+func TestWalkChooseByNumber(t *testing.T) {
+	out := walkSession(t, "1\n\n")
+	if !strings.Contains(out, "1) yes") || !strings.Contains(out, "2) no") {
+		t.Errorf("missing options in:\n%s", out)
+	}
+	if !strings.Contains(out, "Path: ([Start]) → <Auth?> → [yes] [Grant]") {
+		t.Errorf("missing path in:\n%s", out)
+	}
+}
+
+func TestWalkChooseByLabel(t *testing.T) {
+	out := walkSession(t, "NO\n\n")
+	if !strings.Contains(out, "Path: ([Start]) → <Auth?> → [no] //Show login//") {
+		t.Errorf("missing path in:\n%s", out)
+	}
+}
+
+func TestWalkInvalidChoice(t *testing.T) {
+	out := walkSession(t, "maybe\n3\nyes\n\n")
+	if strings.Count(out, "Invalid choice") != 2 {
+		t.Errorf("expected two invalid choices in:\n%s", out)
+	}
+	if !strings.Contains(out, "[yes] [Grant]") {
+		t.Errorf("expected yes branch in:\n%s", out)
+	}
+}
+
+func TestWalkStepBack(t *testing.T) {
+	out := walkSession(t, "yes\nb\nno\n\n")
+	if !strings.Contains(out, "Back") {
+		t.Errorf("expected back message in:\n%s", out)
+	}
+	if !strings.Contains(out, "Path: ([Start]) → <Auth?> → [no] //Show login//") {
+		t.Errorf("expected final path through no branch in:\n%s", out)
+	}
+}
+
+func TestWalkBackAtStart(t *testing.T) {
+	out := walkSession(t, "b\nq\n")
+	if !strings.Contains(out, "Already at the start") {
+		t.Errorf("expected start message in:\n%s", out)
+	}
+}
+
+func TestWalkQuit(t *testing.T) {
+	out := walkSession(t, "q\n")
+	if !strings.Contains(out, "Path: ([Start]) → <Auth?>") {
+		t.Errorf("expected partial path in:\n%s", out)
+	}
+}
+
+func TestWalkEOF(t *testing.T) {
+	out := walkSession(t, "")
+	if !strings.Contains(out, "Walk aborted") {
+		t.Errorf("expected abort in:\n%s", out)
+	}
+}
+
+func TestWalkStopsAtStartEnd(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSession(&buf)
+	s.Execute(Parse("init auth-flow"))
+	s.In = strings.NewReader("yes\n\n")
+	s.Execute(Parse("walk"))
+	got := buf.String()
+	if !strings.Contains(got, "Path: ([Start]) → <Authenticated?> → [yes] [Grant access] → ([End])") {
+		t.Errorf("expected walk to stop at End in:\n%s", got)
+	}
+}
+
+func TestWalkNoRoot(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSession(&buf)
+	s.In = strings.NewReader("")
+	s.Execute(Parse("walk"))
+	if !strings.Contains(buf.String(), "Error: no root set") {
+		t.Errorf("output = %q", buf.String())
+	}
+}
+
+func TestWalkRequiresInput(t *testing.T) {
+	_, out := runCommands(t, "walk")
+	if !strings.Contains(out, "requires interactive input") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestWalkLoopBack(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSession(&buf)
+	s.Execute(Parse("init auth-flow"))
+	s.In = strings.NewReader("no\nyes\n\n")
+	s.Execute(Parse("walk"))
+	want := "Path: ([Start]) → <Authenticated?> → [no] [Show login form] → <Authenticated?> → [yes] [Grant access] → ([End])"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected retry loop in path, got:\n%s", buf.String())
+	}
+}
+
+func TestWalkLoopWithoutDecision(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewSession(&buf)
+	for _, line := range []string{
+		"mode graph",
+		"add action a",
+		"add action b",
+		"connect n1 n2",
+		"connect n2 n1",
+		"set-root n1",
+	} {
+		s.Execute(Parse(line))
+	}
+	s.In = strings.NewReader("")
+	s.Execute(Parse("walk"))
+	if !strings.Contains(buf.String(), "Loop detected at n1") {
+		t.Errorf("expected loop detection, got:\n%s", buf.String())
+	}
+}
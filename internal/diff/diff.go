@@ -0,0 +1,267 @@
+// Package diff compares two decision trees node by node and edge by edge,
+// matching nodes by ID and, where IDs have changed, by label and position.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/render"
+)
+
+// The kinds of change.
+const (
+	Added     = "added"
+	Removed   = "removed"
+	Relabeled = "relabeled"
+	Retyped   = "retyped"
+)
+
+// NodeChange is one change to a node. ID is the node's ID in the new tree,
+// or in the old one if it was removed; OldID is set when a node was
+// matched to one with a different ID.
+type NodeChange struct {
+	Change   string `json:"change"`
+	ID       string `json:"id"`
+	OldID    string `json:"old_id,omitempty"`
+	Type     string `json:"type"`
+	Label    string `json:"label"`
+	OldType  string `json:"old_type,omitempty"`
+	OldLabel string `json:"old_label,omitempty"`
+}
+
+// EdgeChange is one change to an edge, between nodes named by their IDs
+// in the new tree, or in the old one if the edge was removed.
+type EdgeChange struct {
+	Change   string `json:"change"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Label    string `json:"label,omitempty"`
+	OldLabel string `json:"old_label,omitempty"`
+}
+
+// Result lists the changes from an old tree to a new one: removals first,
+// then additions, then changes, each in node ID order.
+type Result struct {
+	Nodes []NodeChange `json:"nodes"`
+	Edges []EdgeChange `json:"edges"`
+	// matches maps old node IDs to the new nodes they were matched with.
+	matches map[string]string
+}
+
+// Empty reports whether the trees are the same.
+func (r *Result) Empty() bool {
+	return len(r.Nodes) == 0 && len(r.Edges) == 0
+}
+
+// Compare finds the changes from a to b.
+func Compare(a, b *model.Tree) *Result {
+	m := match(a, b)
+	r := &Result{Nodes: []NodeChange{}, Edges: []EdgeChange{}, matches: m.toNew}
+
+	for _, id := range unmatched(a, m.toNew) {
+		n := a.Nodes[id]
+		r.Nodes = append(r.Nodes, NodeChange{Change: Removed, ID: id, Type: n.Type.String(), Label: n.Label})
+	}
+	for _, id := range unmatched(b, m.toOld) {
+		n := b.Nodes[id]
+		r.Nodes = append(r.Nodes, NodeChange{Change: Added, ID: id, Type: n.Type.String(), Label: n.Label})
+	}
+	for _, id := range b.NodeIDs() {
+		oldID, ok := m.toOld[id]
+		if !ok {
+			continue
+		}
+		na, nb := a.Nodes[oldID], b.Nodes[id]
+		c := NodeChange{ID: id, Type: nb.Type.String(), Label: nb.Label}
+		if oldID != id {
+			c.OldID = oldID
+		}
+		if na.Label != nb.Label {
+			rc := c
+			rc.Change, rc.OldLabel = Relabeled, na.Label
+			r.Nodes = append(r.Nodes, rc)
+		}
+		if na.Type != nb.Type {
+			c.Change, c.OldType = Retyped, na.Type.String()
+			r.Nodes = append(r.Nodes, c)
+		}
+	}
+
+	// Edges are compared with the old tree's endpoints translated to new
+	// IDs; an edge with an unmatched end cannot be in the new tree.
+	oldEdges := make(map[model.EdgeKey]model.Edge)
+	for _, e := range a.Edges {
+		from, okFrom := m.toNew[e.FromID]
+		to, okTo := m.toNew[e.ToID]
+		if okFrom && okTo && b.GetEdge(from, to) != nil {
+			oldEdges[model.EdgeKey{FromID: from, ToID: to}] = e
+			continue
+		}
+		r.Edges = append(r.Edges, EdgeChange{Change: Removed, From: e.FromID, To: e.ToID, Label: e.Label})
+	}
+	var changed []EdgeChange
+	for _, e := range b.Edges {
+		old, ok := oldEdges[e.Key()]
+		switch {
+		case !ok:
+			r.Edges = append(r.Edges, EdgeChange{Change: Added, From: e.FromID, To: e.ToID, Label: e.Label})
+		case old.Label != e.Label:
+			changed = append(changed, EdgeChange{Change: Relabeled, From: e.FromID, To: e.ToID, Label: e.Label, OldLabel: old.Label})
+		}
+	}
+	r.Edges = append(r.Edges, changed...)
+	return r
+}
+
+// Format writes the changes one per line, "-" for a removal, "+" for an
+// addition and "~" for a change, followed by a summary.
+func Format(r *Result) string {
+	if r.Empty() {
+		return "No differences\n"
+	}
+	var b strings.Builder
+	nodeCounts := make(map[string]int)
+	for _, c := range r.Nodes {
+		nodeCounts[c.Change]++
+		id := c.ID
+		if c.OldID != "" {
+			id += " (was " + c.OldID + ")"
+		}
+		switch c.Change {
+		case Removed:
+			fmt.Fprintf(&b, "- node %s %s %q\n", id, c.Type, c.Label)
+		case Added:
+			fmt.Fprintf(&b, "+ node %s %s %q\n", id, c.Type, c.Label)
+		case Relabeled:
+			fmt.Fprintf(&b, "~ node %s label %q -> %q\n", id, c.OldLabel, c.Label)
+		case Retyped:
+			fmt.Fprintf(&b, "~ node %s type %s -> %s\n", id, c.OldType, c.Type)
+		}
+	}
+	edgeCounts := make(map[string]int)
+	for _, c := range r.Edges {
+		edgeCounts[c.Change]++
+		switch c.Change {
+		case Removed:
+			fmt.Fprintf(&b, "- edge %s -> %s%s\n", c.From, c.To, quoted(c.Label))
+		case Added:
+			fmt.Fprintf(&b, "+ edge %s -> %s%s\n", c.From, c.To, quoted(c.Label))
+		case Relabeled:
+			fmt.Fprintf(&b, "~ edge %s -> %s label %q -> %q\n", c.From, c.To, c.OldLabel, c.Label)
+		}
+	}
+	fmt.Fprintf(&b, "Nodes: %d added, %d removed, %d changed; edges: %d added, %d removed, %d relabeled\n",
+		nodeCounts[Added], nodeCounts[Removed], nodeCounts[Relabeled]+nodeCounts[Retyped],
+		edgeCounts[Added], edgeCounts[Removed], edgeCounts[Relabeled])
+	return b.String()
+}
+
+func quoted(label string) string {
+	if label == "" {
+		return ""
+	}
+	return fmt.Sprintf(" %q", label)
+}
+
+// Colors of changes in annotated diagrams.
+const (
+	addedColor   = "#2e9e44"
+	removedColor = "#dd3333"
+	changedColor = "#e08a00"
+)
+
+// removedPrefix is put before the IDs of removed nodes in an annotated
+// tree, which may clash with IDs in the new tree.
+const removedPrefix = "removed_"
+
+// Annotate merges the old tree into the new one for drawing: removed nodes
+// and edges are put back, changed labels note what they were, and the
+// returned options color additions green, removals red and changes orange.
+// The notes avoid parentheses, which Mermaid reads as node shapes.
+func Annotate(a, b *model.Tree, r *Result) (*model.Tree, render.Options) {
+	t := b.Clone()
+	opts := render.Options{NodeColors: make(map[string]string), EdgeColors: make(map[model.EdgeKey]string)}
+
+	// newID is where an old node appears in the merged tree.
+	newID := func(oldID string) string {
+		if id, ok := r.matches[oldID]; ok {
+			return id
+		}
+		return removedPrefix + oldID
+	}
+	was := make(map[string][]string) // node ID -> what it was
+	for _, c := range r.Nodes {
+		switch c.Change {
+		case Removed:
+			n := a.Nodes[c.ID].Clone()
+			n.ID = removedPrefix + c.ID
+			t.Nodes[n.ID] = n
+			opts.NodeColors[n.ID] = removedColor
+		case Added:
+			opts.NodeColors[c.ID] = addedColor
+		case Retyped:
+			was[c.ID] = append([]string{c.OldType}, was[c.ID]...)
+			opts.NodeColors[c.ID] = changedColor
+		case Relabeled:
+			was[c.ID] = append(was[c.ID], fmt.Sprintf("%q", c.OldLabel))
+			opts.NodeColors[c.ID] = changedColor
+		}
+	}
+	for id, old := range was {
+		t.Nodes[id].Label += " — was " + strings.Join(old, " ")
+	}
+	for _, c := range r.Edges {
+		switch c.Change {
+		case Removed:
+			e := a.GetEdge(c.From, c.To).Clone()
+			e.FromID, e.ToID = newID(c.From), newID(c.To)
+			t.Edges = append(t.Edges, e)
+			opts.EdgeColors[e.Key()] = removedColor
+		case Added:
+			opts.EdgeColors[model.EdgeKey{FromID: c.From, ToID: c.To}] = addedColor
+		case Relabeled:
+			e := t.GetEdge(c.From, c.To)
+			e.Label += fmt.Sprintf(" — was %q", c.OldLabel)
+			opts.EdgeColors[e.Key()] = changedColor
+		}
+	}
+	return t, opts
+}
+
+// Formats lists the output formats Write accepts.
+func Formats() []string {
+	return []string{"text", "json", "dot", "mermaid"}
+}
+
+// Write writes the changes from a to b in one of Formats. The dot and
+// mermaid formats draw the annotated tree.
+func Write(w io.Writer, a, b *model.Tree, r *Result, format string) error {
+	switch format {
+	case "text":
+		_, err := io.WriteString(w, Format(r))
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "dot", "mermaid":
+		t, opts := Annotate(a, b, r)
+		var rd render.Renderer = &render.DOTRenderer{Options: opts}
+		if format == "mermaid" {
+			rd = &render.MermaidRenderer{Options: opts}
+		}
+		out, err := rd.Render(t)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, out)
+		return err
+	default:
+		return fmt.Errorf("unknown diff format %q (use %s)", format, strings.Join(Formats(), ", "))
+	}
+}
@@ -0,0 +1,213 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// buildTree returns Start -> Auth? -[yes]-> Grant, -[no]-> Show login.
+func buildTree() *model.Tree {
+	t := model.NewTree("login")
+	t.Nodes["n1"] = &model.Node{ID: "n1", Type: model.StartEnd, Label: "Start"}
+	t.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Auth?"}
+	t.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Grant"}
+	t.Nodes["n4"] = &model.Node{ID: "n4", Type: model.IO, Label: "Show login"}
+	t.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n4", Label: "no"},
+	}
+	t.RootID = "n1"
+	t.Counter = 4
+	return t
+}
+
+func TestCompareSame(t *testing.T) {
+	r := Compare(buildTree(), buildTree())
+	if !r.Empty() {
+		t.Errorf("expected no changes, got %+v", r)
+	}
+	if got := Format(r); got != "No differences\n" {
+		t.Errorf("Format = %q", got)
+	}
+}
+
+func TestCompareChanges(t *testing.T) {
+	a, b := buildTree(), buildTree()
+	b.Nodes["n3"].Label = "Grant access"
+	b.Nodes["n4"].Type = model.Action
+	b.Nodes["n5"] = &model.Node{ID: "n5", Type: model.StartEnd, Label: "Done"}
+	b.Edges[1].Label = "Yes"
+	b.Edges = append(b.Edges, model.Edge{FromID: "n3", ToID: "n5"})
+
+	r := Compare(a, b)
+	want := `+ node n5 startend "Done"
+~ node n3 label "Grant" -> "Grant access"
+~ node n4 type io -> action
++ edge n3 -> n5
+~ edge n2 -> n3 label "yes" -> "Yes"
+Nodes: 1 added, 0 removed, 2 changed; edges: 1 added, 0 removed, 1 relabeled
+`
+	if got := Format(r); got != want {
+		t.Errorf("Format =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompareRemoved(t *testing.T) {
+	a, b := buildTree(), buildTree()
+	delete(b.Nodes, "n4")
+	b.Edges = b.Edges[:2]
+
+	r := Compare(a, b)
+	if len(r.Nodes) != 1 || r.Nodes[0] != (NodeChange{Change: Removed, ID: "n4", Type: "io", Label: "Show login"}) {
+		t.Errorf("nodes = %+v", r.Nodes)
+	}
+	if len(r.Edges) != 1 || r.Edges[0] != (EdgeChange{Change: Removed, From: "n2", To: "n4", Label: "no"}) {
+		t.Errorf("edges = %+v", r.Edges)
+	}
+}
+
+func TestCompareMatchesRenumberedNodes(t *testing.T) {
+	// Cutting and pasting the "no" branch gives it a new ID, and a new
+	// decision with fresh IDs and edited labels takes the place of Grant.
+	a, b := buildTree(), buildTree()
+	a.Nodes["n5"] = &model.Node{ID: "n5", Type: model.Action, Label: "Log attempt"}
+	a.Edges = append(a.Edges, model.Edge{FromID: "n4", ToID: "n5"})
+
+	delete(b.Nodes, "n4")
+	b.Nodes["n7"] = &model.Node{ID: "n7", Type: model.IO, Label: "Show login"}
+	b.Nodes["n8"] = &model.Node{ID: "n8", Type: model.Action, Label: "Log attempts"}
+	b.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n7", Label: "no"},
+		{FromID: "n7", ToID: "n8"},
+	}
+
+	r := Compare(a, b)
+	for _, c := range r.Nodes {
+		if c.Change == Added || c.Change == Removed {
+			t.Errorf("unexpected %s node %s", c.Change, c.ID)
+		}
+	}
+	if len(r.Edges) != 0 {
+		t.Errorf("edges = %+v, want none", r.Edges)
+	}
+	if len(r.Nodes) != 1 || r.Nodes[0] != (NodeChange{Change: Relabeled, ID: "n8", OldID: "n5", Type: "action", Label: "Log attempts", OldLabel: "Log attempt"}) {
+		t.Errorf("nodes = %+v", r.Nodes)
+	}
+	if got := Format(r); !strings.Contains(got, `~ node n8 (was n5) label "Log attempt" -> "Log attempts"`) {
+		t.Errorf("Format = %q", got)
+	}
+}
+
+func TestCompareIDReusedForOtherNode(t *testing.T) {
+	// n4 now names an unrelated, unconnected node: neither type nor label
+	// agrees, so the old n4 is removed and the new one added.
+	a, b := buildTree(), buildTree()
+	b.Nodes["n4"] = &model.Node{ID: "n4", Type: model.StartEnd, Label: "Abort"}
+	b.Edges = b.Edges[:2]
+
+	r := Compare(a, b)
+	got := Format(r)
+	for _, want := range []string{`- node n4 io "Show login"`, `+ node n4 startend "Abort"`, "1 added, 1 removed, 0 changed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Format missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		x, y string
+		want float64
+	}{
+		{"", "", 1},
+		{"Grant", "grant", 1},
+		{"abcd", "abce", 0.75},
+		{"abc", "xyz", 0},
+	}
+	for _, tt := range tests {
+		if got := similarity(tt.x, tt.y); got != tt.want {
+			t.Errorf("similarity(%q, %q) = %g, want %g", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	a, b := buildTree(), buildTree()
+	delete(b.Nodes, "n4")
+	b.Edges = b.Edges[:2]
+	b.Nodes["n3"].Label = "Grant access"
+	b.Nodes["n5"] = &model.Node{ID: "n5", Type: model.StartEnd, Label: "Done"}
+	b.Edges = append(b.Edges, model.Edge{FromID: "n3", ToID: "n5"})
+
+	merged, opts := Annotate(a, b, Compare(a, b))
+	if n := merged.GetNode("removed_n4"); n == nil || n.Label != "Show login" {
+		t.Fatalf("removed node not restored: %v", merged.NodeIDs())
+	}
+	if e := merged.GetEdge("n2", "removed_n4"); e == nil || e.Label != "no" {
+		t.Errorf("removed edge not restored: %+v", merged.Edges)
+	}
+	if got := merged.Nodes["n3"].Label; got != `Grant access — was "Grant"` {
+		t.Errorf("changed label = %q", got)
+	}
+	if b.Nodes["n3"].Label != "Grant access" || b.GetNode("removed_n4") != nil {
+		t.Error("Annotate modified the new tree")
+	}
+	wantNodes := map[string]string{"removed_n4": removedColor, "n3": changedColor, "n5": addedColor}
+	for id, c := range wantNodes {
+		if opts.NodeColors[id] != c {
+			t.Errorf("color of %s = %q, want %q", id, opts.NodeColors[id], c)
+		}
+	}
+	if len(opts.NodeColors) != len(wantNodes) {
+		t.Errorf("node colors = %v", opts.NodeColors)
+	}
+	if opts.EdgeColors[model.EdgeKey{FromID: "n2", ToID: "removed_n4"}] != removedColor ||
+		opts.EdgeColors[model.EdgeKey{FromID: "n3", ToID: "n5"}] != addedColor {
+		t.Errorf("edge colors = %v", opts.EdgeColors)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	a, b := buildTree(), buildTree()
+	b.Nodes["n3"].Label = "Grant access"
+	r := Compare(a, b)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, a, b, r, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var got Result
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.Nodes) != 1 || got.Nodes[0].Change != Relabeled || got.Edges == nil {
+		t.Errorf("JSON = %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, a, b, r, "dot"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `n3 [label="Grant access — was \"Grant\"", shape=box, color="#e08a00", penwidth=2]`) {
+		t.Errorf("DOT output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, a, b, r, "mermaid"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "style n3 ") || !strings.Contains(buf.String(), "#e08a00") {
+		t.Errorf("Mermaid output:\n%s", buf.String())
+	}
+
+	if err := Write(&buf, a, b, r, "svg"); err == nil || !strings.Contains(err.Error(), "unknown diff format") {
+		t.Errorf("err = %v", err)
+	}
+}
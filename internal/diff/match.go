@@ -0,0 +1,184 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// minSimilarity is how alike two labels must be, from 0 to 1, for the
+// last matching pass to pair their nodes.
+const minSimilarity = 0.5
+
+// matcher pairs nodes of an old and a new tree. Pasting a subtree gives
+// its nodes fresh IDs, so an ID is trusted only when the type or label
+// agrees; the remaining nodes are paired by identical labels, by position
+// under nodes already paired, and finally by similar labels.
+type matcher struct {
+	a, b  *model.Tree
+	toNew map[string]string // old ID -> new ID
+	toOld map[string]string // new ID -> old ID
+}
+
+func match(a, b *model.Tree) *matcher {
+	m := &matcher{a: a, b: b, toNew: make(map[string]string), toOld: make(map[string]string)}
+	m.byID()
+	m.byLabel()
+	m.byPosition()
+	m.bySimilarity()
+	return m
+}
+
+func (m *matcher) pair(oldID, newID string) {
+	m.toNew[oldID] = newID
+	m.toOld[newID] = oldID
+}
+
+// unmatched returns the IDs of a tree's nodes that have no partner yet.
+func unmatched(t *model.Tree, paired map[string]string) []string {
+	var ids []string
+	for _, id := range t.NodeIDs() {
+		if _, ok := paired[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (m *matcher) byID() {
+	for _, id := range m.a.NodeIDs() {
+		na, nb := m.a.Nodes[id], m.b.Nodes[id]
+		if nb != nil && (na.Type == nb.Type || na.Label == nb.Label) {
+			m.pair(id, id)
+		}
+	}
+}
+
+func labelKey(n *model.Node) string {
+	return n.Type.String() + "\x00" + strings.ToLower(strings.TrimSpace(n.Label))
+}
+
+// byLabel pairs nodes with the same type and label, when no other
+// unpaired node on either side has them too.
+func (m *matcher) byLabel() {
+	group := func(t *model.Tree, ids []string) map[string][]string {
+		g := make(map[string][]string)
+		for _, id := range ids {
+			k := labelKey(t.Nodes[id])
+			g[k] = append(g[k], id)
+		}
+		return g
+	}
+	olds := group(m.a, unmatched(m.a, m.toNew))
+	news := group(m.b, unmatched(m.b, m.toOld))
+	for _, id := range unmatched(m.a, m.toNew) {
+		k := labelKey(m.a.Nodes[id])
+		if len(olds[k]) == 1 && len(news[k]) == 1 {
+			m.pair(id, news[k][0])
+		}
+	}
+}
+
+// byPosition pairs the unpaired children of paired nodes: first those
+// reached by edges with the same label, then a last child of the same
+// type left on each side. Each pairing may make more children eligible,
+// so it repeats until nothing changes.
+func (m *matcher) byPosition() {
+	for changed := true; changed; {
+		changed = false
+		for _, oldID := range m.a.NodeIDs() {
+			newID, ok := m.toNew[oldID]
+			if !ok {
+				continue
+			}
+			olds := m.unpairedChildren(m.a, oldID, m.toNew)
+			news := m.unpairedChildren(m.b, newID, m.toOld)
+			for _, oe := range olds {
+				for _, ne := range news {
+					if oe.Label != "" && strings.EqualFold(oe.Label, ne.Label) && m.free(oe.ToID, ne.ToID) {
+						m.pair(oe.ToID, ne.ToID)
+						changed = true
+					}
+				}
+			}
+			olds = m.unpairedChildren(m.a, oldID, m.toNew)
+			news = m.unpairedChildren(m.b, newID, m.toOld)
+			if len(olds) == 1 && len(news) == 1 && m.a.Nodes[olds[0].ToID].Type == m.b.Nodes[news[0].ToID].Type {
+				m.pair(olds[0].ToID, news[0].ToID)
+				changed = true
+			}
+		}
+	}
+}
+
+func (m *matcher) unpairedChildren(t *model.Tree, id string, paired map[string]string) []model.Edge {
+	var out []model.Edge
+	for _, e := range t.Children(id) {
+		if _, ok := paired[e.ToID]; !ok {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (m *matcher) free(oldID, newID string) bool {
+	_, a := m.toNew[oldID]
+	_, b := m.toOld[newID]
+	return !a && !b
+}
+
+// bySimilarity pairs the remaining nodes of the same type whose labels are
+// at least minSimilarity alike, most alike first.
+func (m *matcher) bySimilarity() {
+	type candidate struct {
+		oldID, newID string
+		score        float64
+	}
+	var cands []candidate
+	for _, oldID := range unmatched(m.a, m.toNew) {
+		for _, newID := range unmatched(m.b, m.toOld) {
+			na, nb := m.a.Nodes[oldID], m.b.Nodes[newID]
+			if na.Type != nb.Type {
+				continue
+			}
+			if s := similarity(na.Label, nb.Label); s >= minSimilarity {
+				cands = append(cands, candidate{oldID, newID, s})
+			}
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].score > cands[j].score })
+	for _, c := range cands {
+		if m.free(c.oldID, c.newID) {
+			m.pair(c.oldID, c.newID)
+		}
+	}
+}
+
+// similarity is 1 minus the edit distance between two labels, ignoring
+// case, over the length of the longer one.
+func similarity(x, y string) float64 {
+	a := []rune(strings.ToLower(strings.TrimSpace(x)))
+	b := []rune(strings.ToLower(strings.TrimSpace(y)))
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(b)])/float64(longest)
+}
@@ -0,0 +1,271 @@
+// Package eval runs input records through a tree, using each node's
+// condition and its edges' outcomes to choose a path from the root to a leaf.
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/expr"
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// Evaluator routes records through a tree. Conditions and outcomes are
+// parsed once when it is created.
+type Evaluator struct {
+	t          *model.Tree
+	conditions map[string]*expr.Expr
+	outcomes   map[model.EdgeKey]outcome
+}
+
+// Result is the leaf a record reached and the node IDs visited on the way.
+type Result struct {
+	LeafID string
+	Label  string
+	Path   []string
+}
+
+// New prepares a tree for evaluation. It fails if the tree has no root, a
+// condition does not parse, or a conditional node has an edge without an
+// outcome or label.
+func New(t *model.Tree) (*Evaluator, error) {
+	if t.RootID == "" {
+		return nil, errors.New("no root set")
+	}
+	if t.GetNode(t.RootID) == nil {
+		return nil, fmt.Errorf("root node %q not found", t.RootID)
+	}
+	ev := &Evaluator{
+		t:          t,
+		conditions: make(map[string]*expr.Expr),
+		outcomes:   make(map[model.EdgeKey]outcome),
+	}
+	for _, id := range t.NodeIDs() {
+		n := t.Nodes[id]
+		if strings.TrimSpace(n.Condition) == "" {
+			continue
+		}
+		e, err := expr.Parse(n.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", id, err)
+		}
+		ev.conditions[id] = e
+		defaults := 0
+		for _, edge := range t.Children(id) {
+			o, err := parseOutcome(edge.OutcomeText())
+			if err != nil {
+				return nil, fmt.Errorf("edge %s->%s: %w", edge.FromID, edge.ToID, err)
+			}
+			if o.def {
+				defaults++
+			}
+			ev.outcomes[edge.Key()] = o
+		}
+		if defaults > 1 {
+			return nil, fmt.Errorf("node %s has %d default branches", id, defaults)
+		}
+	}
+	return ev, nil
+}
+
+// Route follows a record from the root to a leaf. A node with a condition
+// takes the edge whose outcome matches the condition's value, falling back
+// to a default ("else") edge. A node without a condition must have a single
+// outgoing edge.
+func (ev *Evaluator) Route(record map[string]any) (*Result, error) {
+	visited := make(map[string]bool)
+	id := ev.t.RootID
+	var path []string
+	for {
+		if visited[id] {
+			return nil, fmt.Errorf("loop at %s: the record revisits a node", id)
+		}
+		visited[id] = true
+		path = append(path, id)
+		n := ev.t.Nodes[id]
+		children := ev.t.Children(id)
+		if len(children) == 0 {
+			return &Result{LeafID: id, Label: n.Label, Path: path}, nil
+		}
+		cond, ok := ev.conditions[id]
+		if !ok {
+			if len(children) > 1 {
+				return nil, fmt.Errorf("%s (%s) has %d branches but no condition", id, n.Label, len(children))
+			}
+			id = children[0].ToID
+			continue
+		}
+		next, err := ev.choose(id, cond, children, record)
+		if err != nil {
+			return nil, fmt.Errorf("%s (%s): %w", id, n.Label, err)
+		}
+		id = next
+	}
+}
+
+func (ev *Evaluator) choose(id string, cond *expr.Expr, children []model.Edge, record map[string]any) (string, error) {
+	v, err := cond.Eval(record)
+	if err != nil {
+		return "", err
+	}
+	var matches []model.Edge
+	def := ""
+	for _, e := range children {
+		o := ev.outcomes[e.Key()]
+		if o.def {
+			def = e.ToID
+			continue
+		}
+		ok, err := o.match(v)
+		if err != nil {
+			return "", fmt.Errorf("edge to %s: %w", e.ToID, err)
+		}
+		if ok {
+			matches = append(matches, e)
+		}
+	}
+	switch {
+	case len(matches) == 1:
+		return matches[0].ToID, nil
+	case len(matches) > 1:
+		ids := make([]string, len(matches))
+		for i, e := range matches {
+			ids[i] = e.ToID
+		}
+		return "", fmt.Errorf("ambiguous: %s = %s matches branches to %s", cond, expr.FormatValue(v), strings.Join(ids, ", "))
+	case def != "":
+		return def, nil
+	}
+	return "", fmt.Errorf("no branch matches %s = %s", cond, expr.FormatValue(v))
+}
+
+// Summary counts the records processed by RunJSONL.
+type Summary struct {
+	Records int
+	Failed  int
+}
+
+// lineResult is one line of RunJSONL output.
+type lineResult struct {
+	Line  int      `json:"line"`
+	Leaf  string   `json:"leaf,omitempty"`
+	Label string   `json:"label,omitempty"`
+	Path  []string `json:"path,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// RunJSONL routes every JSON object in r, one per line, and writes one JSON
+// result per record to w. Records that cannot be routed are reported in the
+// output and counted in the summary; the returned error is for I/O only.
+// Blank lines are skipped.
+func (ev *Evaluator) RunJSONL(r io.Reader, w io.Writer) (Summary, error) {
+	var sum Summary
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		sum.Records++
+		out := lineResult{Line: line}
+		var record map[string]any
+		if err := json.Unmarshal(text, &record); err != nil {
+			out.Error = "invalid record: " + err.Error()
+		} else if res, err := ev.Route(record); err != nil {
+			out.Error = err.Error()
+		} else {
+			out.Leaf, out.Label, out.Path = res.LeafID, res.Label, res.Path
+		}
+		if out.Error != "" {
+			sum.Failed++
+		}
+		if err := enc.Encode(out); err != nil {
+			return sum, err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return sum, err
+	}
+	return sum, bw.Flush()
+}
+
+// outcome is a parsed edge outcome: a value to match, a comparison against
+// a value ("> 10"), or the default branch.
+type outcome struct {
+	def   bool
+	op    string // comparison operator, or "" to match by value
+	text  string // the value to compare with or match
+	value any    // text as a number or string, for comparisons
+}
+
+var outcomeOps = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+func parseOutcome(text string) (outcome, error) {
+	text = strings.TrimSpace(text)
+	switch strings.ToLower(text) {
+	case "":
+		return outcome{}, errors.New("no outcome or label to match")
+	case "else", "default", "otherwise", "*":
+		return outcome{def: true}, nil
+	}
+	for _, op := range outcomeOps {
+		if rest, ok := strings.CutPrefix(text, op); ok {
+			rest = strings.TrimSpace(rest)
+			if rest == "" {
+				return outcome{}, fmt.Errorf("outcome %q has no value", text)
+			}
+			o := outcome{op: op, text: unquote(rest), value: unquote(rest)}
+			if f, err := strconv.ParseFloat(rest, 64); err == nil {
+				o.value = f
+			}
+			return o, nil
+		}
+	}
+	return outcome{text: unquote(text)}, nil
+}
+
+// match reports whether a condition value selects this outcome. Booleans
+// match true/yes and false/no, numbers match numerically, and strings
+// match case-insensitively.
+func (o outcome) match(v any) (bool, error) {
+	if o.op != "" {
+		return expr.Compare(v, o.op, o.value)
+	}
+	switch x := v.(type) {
+	case bool:
+		switch strings.ToLower(o.text) {
+		case "true", "yes", "y":
+			return x, nil
+		case "false", "no", "n":
+			return !x, nil
+		}
+	case float64:
+		f, err := strconv.ParseFloat(o.text, 64)
+		return err == nil && f == x, nil
+	case string:
+		return strings.EqualFold(o.text, x), nil
+	case nil:
+		switch strings.ToLower(o.text) {
+		case "null", "none":
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,218 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// buildRouting builds:
+//
+//	n1 Start -> n2 "Age?" [age >= 18]
+//	  n2 -yes-> n3 "Country?" [country]
+//	    n3 -US-> n4 "Approve"
+//	    n3 -else-> n5 "Review"
+//	  n2 -no-> n6 "Reject"
+func buildRouting(t *testing.T) *model.Tree {
+	t.Helper()
+	tr := model.NewTree("routing")
+	tree.AddNode(tr, model.StartEnd, "Start")
+	tree.AddNode(tr, model.Decision, "Age?")
+	tree.AddNode(tr, model.Decision, "Country?")
+	tree.AddNode(tr, model.Action, "Approve")
+	tree.AddNode(tr, model.Action, "Review")
+	tree.AddNode(tr, model.Action, "Reject")
+	for _, e := range [][3]string{
+		{"n1", "n2", ""}, {"n2", "n3", "yes"}, {"n2", "n6", "no"},
+		{"n3", "n4", "US"}, {"n3", "n5", "other"},
+	} {
+		if err := tree.ConnectNodes(tr, e[0], e[1], e[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tree.SetRoot(tr, "n1")
+	tr.Nodes["n2"].Condition = "age >= 18"
+	tr.Nodes["n3"].Condition = "country"
+	tr.GetEdge("n3", "n5").Outcome = "else"
+	return tr
+}
+
+func mustNew(t *testing.T, tr *model.Tree) *Evaluator {
+	t.Helper()
+	ev, err := New(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func TestRoute(t *testing.T) {
+	ev := mustNew(t, buildRouting(t))
+	tests := []struct {
+		record map[string]any
+		leaf   string
+		path   string
+	}{
+		{map[string]any{"age": 30.0, "country": "us"}, "n4", "n1 n2 n3 n4"},
+		{map[string]any{"age": 30.0, "country": "FR"}, "n5", "n1 n2 n3 n5"},
+		{map[string]any{"age": 12.0}, "n6", "n1 n2 n6"},
+	}
+	for _, tt := range tests {
+		res, err := ev.Route(tt.record)
+		if err != nil {
+			t.Errorf("Route(%v): %v", tt.record, err)
+			continue
+		}
+		if res.LeafID != tt.leaf || strings.Join(res.Path, " ") != tt.path {
+			t.Errorf("Route(%v) = %s via %v, want %s via %s", tt.record, res.LeafID, res.Path, tt.leaf, tt.path)
+		}
+	}
+}
+
+func TestRouteNoMatch(t *testing.T) {
+	tr := buildRouting(t)
+	tr.GetEdge("n3", "n5").Outcome = "CA"
+	_, err := mustNew(t, tr).Route(map[string]any{"age": 40.0, "country": "FR"})
+	if err == nil || !strings.Contains(err.Error(), `no branch matches country = "FR"`) {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRouteAmbiguous(t *testing.T) {
+	tr := buildRouting(t)
+	tr.GetEdge("n3", "n5").Outcome = "us"
+	_, err := mustNew(t, tr).Route(map[string]any{"age": 40.0, "country": "US"})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") || !strings.Contains(err.Error(), "n4, n5") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRouteMissingVariable(t *testing.T) {
+	_, err := mustNew(t, buildRouting(t)).Route(map[string]any{"country": "US"})
+	if err == nil || !strings.Contains(err.Error(), "n2 (Age?): undefined variable age") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRouteNoCondition(t *testing.T) {
+	tr := buildRouting(t)
+	tr.Nodes["n2"].Condition = ""
+	_, err := mustNew(t, tr).Route(map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "has 2 branches but no condition") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRouteComparisonOutcomes(t *testing.T) {
+	tr := buildRouting(t)
+	tr.Nodes["n2"].Condition = "age"
+	tr.GetEdge("n2", "n3").Outcome = ">= 18"
+	tr.GetEdge("n2", "n6").Outcome = "< 18"
+	ev := mustNew(t, tr)
+	for age, want := range map[float64]string{17: "n6", 18: "n4"} {
+		res, err := ev.Route(map[string]any{"age": age, "country": "US"})
+		if err != nil || res.LeafID != want {
+			t.Errorf("age %v: got %v, %v; want %s", age, res, err, want)
+		}
+	}
+}
+
+func TestRouteLoop(t *testing.T) {
+	tr := buildRouting(t)
+	tr.Graph = true
+	if err := tree.ConnectNodes(tr, "n5", "n3", ""); err != nil {
+		t.Fatal(err)
+	}
+	_, err := mustNew(t, tr).Route(map[string]any{"age": 20.0, "country": "FR"})
+	if err == nil || !strings.Contains(err.Error(), "loop at n3") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tr := buildRouting(t)
+	tr.Nodes["n2"].Condition = "age >="
+	if _, err := New(tr); err == nil || !strings.Contains(err.Error(), "node n2") {
+		t.Errorf("error = %v", err)
+	}
+
+	tr = buildRouting(t)
+	tr.GetEdge("n1", "n2").Label = ""
+	tr.Nodes["n1"].Condition = "true"
+	if _, err := New(tr); err == nil || !strings.Contains(err.Error(), "edge n1->n2") {
+		t.Errorf("error = %v", err)
+	}
+
+	if _, err := New(model.NewTree("empty")); err == nil {
+		t.Error("expected error for tree without root")
+	}
+}
+
+func TestRunJSONL(t *testing.T) {
+	input := `{"age": 30, "country": "US"}
+
+{"age": 5}
+not json
+{"country": "US"}
+`
+	var out bytes.Buffer
+	sum, err := mustNew(t, buildRouting(t)).RunJSONL(strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Records != 4 || sum.Failed != 2 {
+		t.Errorf("summary = %+v, want 4 records, 2 failed", sum)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d output lines:\n%s", len(lines), out.String())
+	}
+	if lines[0] != `{"line":1,"leaf":"n4","label":"Approve","path":["n1","n2","n3","n4"]}` {
+		t.Errorf("line 1 = %s", lines[0])
+	}
+	var res lineResult
+	if err := json.Unmarshal([]byte(lines[1]), &res); err != nil || res.Line != 3 || res.Leaf != "n6" {
+		t.Errorf("line 3 = %s", lines[1])
+	}
+	if !strings.Contains(lines[2], `"line":4,"error":"invalid record`) {
+		t.Errorf("line 4 = %s", lines[2])
+	}
+	if !strings.Contains(lines[3], "undefined variable age") {
+		t.Errorf("line 5 = %s", lines[3])
+	}
+}
+
+func TestOutcomeMatch(t *testing.T) {
+	tests := []struct {
+		text  string
+		value any
+		want  bool
+	}{
+		{"yes", true, true},
+		{"No", false, true},
+		{"yes", false, false},
+		{"3", 3.0, true},
+		{"3.0", 3.0, true},
+		{"gold", "Gold", true},
+		{`"a b"`, "a b", true},
+		{"> 2.5", 3.0, true},
+		{"<= 2.5", 3.0, false},
+		{"!= 'x'", "y", true},
+		{"none", nil, true},
+		{"1", "1", true},
+	}
+	for _, tt := range tests {
+		o, err := parseOutcome(tt.text)
+		if err != nil {
+			t.Fatalf("parseOutcome(%q): %v", tt.text, err)
+		}
+		got, err := o.match(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("%q matches %v = %v, %v; want %v", tt.text, tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+// Package expr parses and evaluates the small expression language used by
+// node conditions: literals, variables, arithmetic, comparisons and boolean
+// logic over values decoded from JSON.
+package expr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses an expression. The grammar, loosest binding first:
+//
+//	a || b, a or b
+//	a && b, a and b
+//	!a, not a
+//	a == b, a != b, a < b, a <= b, a > b, a >= b
+//	a + b, a - b
+//	a * b, a / b, a % b
+//	-a
+//	42, 1.5, "text", 'text', true, false, null, name, user.age, `odd name`, (a)
+func Parse(src string) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: %w", src, err)
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: %w", src, err)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source text of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against a set of variables. The result is
+// nil, a bool, a float64 or a string.
+func (e *Expr) Eval(vars map[string]any) (any, error) {
+	return e.root.eval(vars)
+}
+
+// Equal reports whether two values are equal. Values of different types are
+// never equal.
+func Equal(a, b any) bool {
+	a, b = normalize(a), normalize(b)
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case float64:
+		y, ok := b.(float64)
+		return ok && x == y
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	}
+	return false
+}
+
+// Compare applies a comparison operator (==, !=, <, <=, >, >=) to two
+// values. Ordering is defined between two numbers or two strings.
+func Compare(a any, op string, b any) (bool, error) {
+	switch op {
+	case "==":
+		return Equal(a, b), nil
+	case "!=":
+		return !Equal(a, b), nil
+	}
+	a, b = normalize(a), normalize(b)
+	var c int
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %s and %s", typeName(a), typeName(b))
+		}
+		c = cmpFloat(x, y)
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %s and %s", typeName(a), typeName(b))
+		}
+		c = strings.Compare(x, y)
+	default:
+		return false, fmt.Errorf("cannot compare %s and %s", typeName(a), typeName(b))
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// FormatValue formats a value the way it would be written in an expression.
+func FormatValue(v any) string {
+	switch x := normalize(v).(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(x)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func cmpFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// normalize converts the numeric types a caller may supply to float64.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case json.Number:
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+		return x.String()
+	}
+	return v
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// node is an element of the expression tree.
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literal struct{ v any }
+
+func (n literal) eval(map[string]any) (any, error) { return n.v, nil }
+
+// variable looks up a name. A dotted name that is not itself a key is
+// resolved through nested objects.
+type variable struct{ name string }
+
+func (n variable) eval(vars map[string]any) (any, error) {
+	if v, ok := vars[n.name]; ok {
+		return normalize(v), nil
+	}
+	parts := strings.Split(n.name, ".")
+	var cur any = vars
+	for _, part := range parts {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("undefined variable %s", n.name)
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("undefined variable %s", n.name)
+		}
+	}
+	return normalize(cur), nil
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+func (n unary) eval(vars map[string]any) (any, error) {
+	v, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! needs a bool, got %s", typeName(v))
+		}
+		return !b, nil
+	default:
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("operator - needs a number, got %s", typeName(v))
+		}
+		return -f, nil
+	}
+}
+
+type binary struct {
+	op   string
+	x, y node
+}
+
+func (n binary) eval(vars map[string]any) (any, error) {
+	a, err := n.x.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		ab, ok := a.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bools, got %s", n.op, typeName(a))
+		}
+		if (n.op == "&&") != ab {
+			return ab, nil // short-circuit
+		}
+		b, err := n.y.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		bb, ok := b.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bools, got %s", n.op, typeName(b))
+		}
+		return bb, nil
+	}
+	b, err := n.y.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return Compare(a, n.op, b)
+	}
+	if n.op == "+" {
+		if as, ok := a.(string); ok {
+			if bs, ok := b.(string); ok {
+				return as + bs, nil
+			}
+		}
+	}
+	x, xok := a.(float64)
+	y, yok := b.(float64)
+	if !xok || !yok {
+		return nil, fmt.Errorf("operator %s needs numbers, got %s and %s", n.op, typeName(a), typeName(b))
+	}
+	switch n.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return x / y, nil
+	case "%":
+		if y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return math.Mod(x, y), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
@@ -0,0 +1,134 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]any{
+		"age":      float64(34),
+		"country":  "US",
+		"verified": true,
+		"score":    7,
+		"user":     map[string]any{"plan": "pro", "seats": float64(12)},
+		"odd name": "x",
+		"missing":  nil,
+	}
+	tests := []struct {
+		src  string
+		want any
+	}{
+		{"age", float64(34)},
+		{"age >= 18", true},
+		{"age < 18 || country == 'US'", true},
+		{`verified && country != "CA"`, true},
+		{"not verified", false},
+		{"!(age > 30 and score > 5)", false},
+		{"score * 2 + 1", float64(15)},
+		{"-age + 40", float64(6)},
+		{"10 % 4", float64(2)},
+		{"1 + 2 * 3", float64(7)},
+		{"(1 + 2) * 3", float64(9)},
+		{"user.plan", "pro"},
+		{"user.seats > 10", true},
+		{"`odd name` == \"x\"", true},
+		{"missing == null", true},
+		{"country + '-' + user.plan", "US-pro"},
+		{"'abc' < 'abd'", true},
+		{"1.5e2", float64(150)},
+		{"age == '34'", false},
+		{"false or age > 1", true},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+			continue
+		}
+		got, err := e.Eval(vars)
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %v (%T), want %v", tt.src, got, got, tt.want)
+		}
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	e, err := Parse("false && nope > 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := e.Eval(nil); err != nil || got != false {
+		t.Errorf("got %v, %v; want false", got, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"", "unexpected end of expression at column 1"},
+		{"age >", "unexpected end of expression"},
+		{"(age", "unexpected end of expression"},
+		{"age 1", `unexpected "1" at column 5`},
+		{"'open", "unterminated string"},
+		{"age # 3", `unexpected '#' at column 5`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	vars := map[string]any{"age": float64(3), "name": "a"}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"height > 1", "undefined variable height"},
+		{"name > 1", "cannot compare string and number"},
+		{"age && true", "operator && needs bools"},
+		{"age / 0", "division by zero"},
+		{"-name", "operator - needs a number"},
+		{"name * 2", "operator * needs numbers"},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.src, err)
+		}
+		_, err = e.Eval(vars)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Eval(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	if ok, err := Compare(3, "<=", 3.5); err != nil || !ok {
+		t.Errorf("3 <= 3.5 = %v, %v", ok, err)
+	}
+	if ok, _ := Compare("a", "!=", 1); !ok {
+		t.Error(`"a" != 1 should be true`)
+	}
+	if _, err := Compare(true, "<", false); err == nil {
+		t.Error("expected error ordering bools")
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := map[any]string{nil: "null", true: "true", 2.5: "2.5", 3: "3", "hi": `"hi"`}
+	for v, want := range tests {
+		if got := FormatValue(v); got != want {
+			t.Errorf("FormatValue(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
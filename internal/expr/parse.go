@@ -0,0 +1,272 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string // operator, identifier or unquoted string
+	num  float64
+	pos  int  // 1-based column
+	raw  bool // identifier written as `name`, never a keyword
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// twoCharOps lists the operators longer than one character.
+var twoCharOps = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == 'e' || rs[j] == 'E' ||
+				(rs[j] == '-' || rs[j] == '+') && (rs[j-1] == 'e' || rs[j-1] == 'E')) {
+				j++
+			}
+			f, err := strconv.ParseFloat(string(rs[i:j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q at column %d", string(rs[i:j]), pos)
+			}
+			toks = append(toks, token{kind: tokNumber, text: string(rs[i:j]), num: f, pos: pos})
+			i = j
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != r; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at column %d", pos)
+			}
+			toks = append(toks, token{kind: tokString, text: sb.String(), pos: pos})
+			i = j + 1
+		case r == '`':
+			j := i + 1
+			for j < len(rs) && rs[j] != '`' {
+				j++
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated `name` at column %d", pos)
+			}
+			toks = append(toks, token{kind: tokIdent, text: string(rs[i+1 : j]), pos: pos, raw: true})
+			i = j + 1
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.') {
+				j++
+			}
+			word := string(rs[i:j])
+			switch word {
+			case "and":
+				toks = append(toks, token{kind: tokOp, text: "&&", pos: pos})
+			case "or":
+				toks = append(toks, token{kind: tokOp, text: "||", pos: pos})
+			case "not":
+				toks = append(toks, token{kind: tokOp, text: "!", pos: pos})
+			default:
+				toks = append(toks, token{kind: tokIdent, text: word, pos: pos})
+			}
+			i = j
+		default:
+			op := ""
+			if i+1 < len(rs) {
+				for _, two := range twoCharOps {
+					if string(rs[i:i+2]) == two {
+						op = two
+					}
+				}
+			}
+			if op == "" && strings.ContainsRune("+-*/%<>!()", r) {
+				op = string(r)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at column %d", r, pos)
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: pos})
+			i += len([]rune(op))
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(rs) + 1}), nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators.
+func (p *parser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	return fmt.Errorf("unexpected %s at column %d", t, t.pos)
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseNot, "&&")
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: "!", x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	x, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<", "<=", ">", ">="); ok {
+		y, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return binary{op: op, x: x, y: y}, nil
+	}
+	return x, nil
+}
+
+func (p *parser) parseAdd() (node, error) {
+	return p.parseBinary(p.parseMul, "+", "-")
+}
+
+func (p *parser) parseMul() (node, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+// parseBinary parses a left-associative chain of operators.
+func (p *parser) parseBinary(operand func() (node, error), ops ...string) (node, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return x, nil
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op: op, x: x, y: y}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: "-", x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.next()
+		return literal{t.num}, nil
+	case tokString:
+		p.next()
+		return literal{t.text}, nil
+	case tokIdent:
+		p.next()
+		if t.raw {
+			return variable{t.text}, nil
+		}
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		return variable{t.text}, nil
+	case tokOp:
+		if t.text == "(" {
+			p.next()
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, p.unexpected()
+			}
+			return x, nil
+		}
+	}
+	return nil, p.unexpected()
+}
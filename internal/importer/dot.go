@@ -0,0 +1,473 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// dotShapes maps Graphviz shapes to node types. The first four are the
+// shapes render.DOTRenderer draws.
+var dotShapes = map[string]model.NodeType{
+	"diamond":       model.Decision,
+	"box":           model.Action,
+	"ellipse":       model.StartEnd,
+	"parallelogram": model.IO,
+	"rect":          model.Action,
+	"rectangle":     model.Action,
+	"square":        model.Action,
+	"oval":          model.StartEnd,
+	"circle":        model.StartEnd,
+	"doublecircle":  model.StartEnd,
+}
+
+// ParseDOT imports a Graphviz digraph. Each node's shape attribute becomes
+// its type (diamond decision, box action, ellipse start/end, parallelogram
+// I/O; nodes without a shape are actions) and its label attribute its
+// label. Edge labels are kept and other attributes are ignored. Subgraphs
+// are flattened, and "node [...]" defaults apply to the nodes that follow
+// in the same scope. Errors give the line number.
+func ParseDOT(src string) (*model.Tree, error) {
+	toks, err := lexDOT(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &dotParser{toks: toks, b: newBuilder()}
+	if err := p.graph(); err != nil {
+		return nil, err
+	}
+	return p.b.finish(), nil
+}
+
+type dotToken struct {
+	text   string
+	line   int
+	quoted bool // a quoted string, never a keyword or punctuation
+	eof    bool
+}
+
+func (t dotToken) String() string {
+	switch {
+	case t.eof:
+		return "end of file"
+	case t.quoted:
+		return fmt.Sprintf("%q", t.text)
+	}
+	return "\"" + t.text + "\""
+}
+
+// is reports whether the token is the given punctuation or keyword. DOT
+// keywords are case-insensitive.
+func (t dotToken) is(s string) bool {
+	return !t.quoted && !t.eof && strings.EqualFold(t.text, s)
+}
+
+// isID reports whether the token can be an ID: a name, numeral or quoted
+// string.
+func (t dotToken) isID() bool {
+	if t.quoted {
+		return true
+	}
+	if t.eof || t.text == "" {
+		return false
+	}
+	c := t.text[0]
+	return c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func lexDOT(src string) ([]dotToken, error) {
+	var toks []dotToken
+	line := 1
+	atLineStart := true
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			atLineStart = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#' && atLineStart:
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		atLineStart = false
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			toks = append(toks, dotToken{text: src[i : i+2], line: line})
+			i += 2
+		case strings.ContainsRune("{}[];,=:+", rune(c)):
+			toks = append(toks, dotToken{text: string(c), line: line})
+			i++
+		case c == '"':
+			start := line
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					if src[j+1] == '\n' { // line continuation
+						line++
+						j++
+						continue
+					}
+					b.WriteByte(src[j])
+					j++
+				}
+				if src[j] == '\n' {
+					line++
+				}
+				b.WriteByte(src[j])
+			}
+			if j == len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			toks = append(toks, dotToken{text: b.String(), line: start, quoted: true})
+			i = j + 1
+		case c == '<':
+			return nil, fmt.Errorf("line %d: HTML strings are not supported", line)
+		default:
+			j := i
+			if src[j] == '-' {
+				j++
+			}
+			for j < len(src) && isDOTIDChar(src[j]) {
+				j++
+			}
+			if j == i || src[i:j] == "-" {
+				return nil, fmt.Errorf("line %d: unexpected %q", line, src[i])
+			}
+			toks = append(toks, dotToken{text: src[i:j], line: line})
+			i = j
+		}
+	}
+	return append(toks, dotToken{line: line, eof: true}), nil
+}
+
+func isDOTIDChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// dotScope holds the node and edge attribute defaults of a graph or
+// subgraph body.
+type dotScope struct {
+	node, edge []dotAttr
+}
+
+// dotAttr is one key=value pair and the line it was on.
+type dotAttr struct {
+	key, value string
+	line       int
+}
+
+type dotParser struct {
+	toks      []dotToken
+	pos       int
+	b         *builder
+	mentioned []string // node IDs in the order statements name them
+}
+
+// errorf reports an error at the line of the next token.
+func (p *dotParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: "+format, append([]any{p.peek().line}, args...)...)
+}
+
+func (p *dotParser) peek() dotToken { return p.toks[p.pos] }
+
+func (p *dotParser) next() dotToken {
+	t := p.toks[p.pos]
+	if !t.eof {
+		p.pos++
+	}
+	return t
+}
+
+func (p *dotParser) expect(s string) error {
+	if t := p.peek(); !t.is(s) {
+		return p.errorf("expected %q, got %s", s, t)
+	}
+	p.next()
+	return nil
+}
+
+// id reads an ID, joining quoted strings concatenated with "+".
+func (p *dotParser) id(what string) (string, error) {
+	t := p.peek()
+	if !t.isID() {
+		return "", p.errorf("expected %s, got %s", what, t)
+	}
+	p.next()
+	text := t.text
+	for t.quoted && p.peek().is("+") {
+		p.next()
+		if t = p.peek(); !t.quoted {
+			return "", p.errorf("expected a string after \"+\", got %s", t)
+		}
+		p.next()
+		text += t.text
+	}
+	return text, nil
+}
+
+func (p *dotParser) graph() error {
+	if p.peek().is("strict") {
+		p.next()
+	}
+	switch t := p.peek(); {
+	case t.is("graph"):
+		return p.errorf("undirected graphs are not supported; use digraph")
+	case !t.is("digraph"):
+		return p.errorf("expected digraph, got %s", t)
+	}
+	p.next()
+	if p.peek().isID() {
+		name, err := p.id("a graph name")
+		if err != nil {
+			return err
+		}
+		p.b.t.Name = name
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	if err := p.stmts(&dotScope{}); err != nil {
+		return err
+	}
+	if t := p.peek(); !t.eof {
+		return p.errorf("unexpected %s after the graph", t)
+	}
+	return nil
+}
+
+// stmts reads statements up to and including the closing brace.
+func (p *dotParser) stmts(scope *dotScope) error {
+	for {
+		switch t := p.peek(); {
+		case t.is("}"):
+			p.next()
+			return nil
+		case t.eof:
+			return p.errorf(`expected "}", got end of file`)
+		case t.is(";"):
+			p.next()
+		default:
+			if err := p.stmt(scope); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *dotParser) stmt(scope *dotScope) error {
+	t := p.peek()
+	if t.is("graph") || t.is("node") || t.is("edge") {
+		p.next()
+		attrs, err := p.attrs()
+		if err != nil {
+			return err
+		}
+		switch {
+		case t.is("node"):
+			scope.node = append(scope.node, attrs...)
+		case t.is("edge"):
+			scope.edge = append(scope.edge, attrs...)
+		}
+		return nil
+	}
+	if t.isID() && p.toks[p.pos+1].is("=") {
+		p.next()
+		p.next()
+		_, err := p.id("a value")
+		return err
+	}
+
+	line := t.line
+	left, isNode, err := p.operand(scope)
+	if err != nil {
+		return err
+	}
+	var chain [][]string
+	for p.peek().is("->") || p.peek().is("--") {
+		if p.peek().is("--") {
+			return p.errorf(`undirected edge "--"; use "->"`)
+		}
+		p.next()
+		right, _, err := p.operand(scope)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, right)
+	}
+	var attrs []dotAttr
+	if p.peek().is("[") {
+		if attrs, err = p.attrs(); err != nil {
+			return err
+		}
+	}
+	if len(chain) == 0 {
+		if isNode {
+			return p.setNodeAttrs(left[0], attrs)
+		}
+		return nil
+	}
+	label := ""
+	for _, kv := range append(append([]dotAttr(nil), scope.edge...), attrs...) {
+		if kv.key == "label" {
+			label = dotUnescape(kv.value)
+		}
+	}
+	for _, right := range chain {
+		for _, from := range left {
+			for _, to := range right {
+				if err := p.b.edge(from, to, label); err != nil {
+					return fmt.Errorf("line %d: %w", line, err)
+				}
+			}
+		}
+		left = right
+	}
+	return nil
+}
+
+// operand reads a node ID (with an optional port, which is ignored) or a
+// subgraph, and returns the node IDs it names and whether it was a node.
+func (p *dotParser) operand(scope *dotScope) ([]string, bool, error) {
+	if t := p.peek(); t.is("subgraph") || t.is("{") {
+		ids, err := p.subgraph(scope)
+		return ids, false, err
+	}
+	id, err := p.id("a node ID")
+	if err != nil {
+		return nil, false, err
+	}
+	for i := 0; i < 2 && p.peek().is(":"); i++ {
+		p.next()
+		if _, err := p.id("a port"); err != nil {
+			return nil, false, err
+		}
+	}
+	if p.b.t.Nodes[id] == nil {
+		p.b.node(id)
+		if err := p.setNodeAttrs(id, scope.node); err != nil {
+			return nil, false, err
+		}
+	}
+	p.mentioned = append(p.mentioned, id)
+	return []string{id}, true, nil
+}
+
+// subgraph reads a subgraph body with its own copy of the defaults and
+// returns the node IDs it names.
+func (p *dotParser) subgraph(scope *dotScope) ([]string, error) {
+	if p.next().is("subgraph") {
+		if p.peek().isID() {
+			if _, err := p.id("a subgraph name"); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+	}
+	inner := &dotScope{
+		node: append([]dotAttr(nil), scope.node...),
+		edge: append([]dotAttr(nil), scope.edge...),
+	}
+	start := len(p.mentioned)
+	if err := p.stmts(inner); err != nil {
+		return nil, err
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range p.mentioned[start:] {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// attrs reads one or more bracketed attribute lists.
+func (p *dotParser) attrs() ([]dotAttr, error) {
+	var list []dotAttr
+	for p.peek().is("[") {
+		p.next()
+		for !p.peek().is("]") {
+			line := p.peek().line
+			key, err := p.id("an attribute name")
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			value, err := p.id("an attribute value")
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, dotAttr{key, value, line})
+			if t := p.peek(); t.is(",") || t.is(";") {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return list, nil
+}
+
+func (p *dotParser) setNodeAttrs(id string, attrs []dotAttr) error {
+	n := p.b.t.Nodes[id]
+	for _, kv := range attrs {
+		switch kv.key {
+		case "label":
+			n.Label = dotUnescape(kv.value)
+		case "shape":
+			typ, ok := dotShapes[strings.ToLower(kv.value)]
+			if !ok {
+				return fmt.Errorf("line %d: unsupported shape %q for node %s (use diamond, box, ellipse or parallelogram)", kv.line, kv.value, id)
+			}
+			n.Type = typ
+		}
+	}
+	return nil
+}
+
+// dotUnescape decodes the escapes render.DOTRenderer writes in labels.
+// Graphviz's justified line breaks \l and \r are read as newlines too.
+func dotUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'l', 'r':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/render"
+)
+
+func TestParseDOTGolden(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join(testdataDir(), "expected-dot.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseDOT(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "auth_flow" {
+		t.Errorf("Name = %q, want auth_flow", got.Name)
+	}
+	assertEquivalent(t, got, loadSample(t))
+}
+
+func TestParseDOTRoundTrip(t *testing.T) {
+	want := loadSample(t)
+	want.Graph = true
+	want.Nodes["n2"].Label = "Say \"yes\"\nor \\no?"
+	want.Edges = append(want.Edges, model.Edge{FromID: "n4", ToID: "n2", Label: "retry"})
+	text, err := (&render.DOTRenderer{TooltipAttrs: []string{"owner"}}).Render(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseDOT(text)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, text)
+	}
+	assertEquivalent(t, got, want)
+}
+
+func TestParseDOTSyntax(t *testing.T) {
+	src := `/* hand-written */
+strict digraph "Support flow" {
+  graph [rankdir=LR]; fontsize = 10
+  node [shape=box]
+  # a preprocessor line
+  call [label="Call", shape=oval];
+  call -> on:n [label = "Is it" + " on?"]
+  on [shape=diamond, label="On?"]
+  on -> { photo reboot } [label=no] // two edges
+  subgraph cluster_fix {
+    node [shape=parallelogram]
+    photo [label="Ask for\lphoto\l"]
+    wait
+  }
+  edge [label=next]
+  reboot -> done -> "the end"
+  "the end" [shape=ellipse]
+}
+`
+	tr, err := ParseDOT(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Name != "Support flow" || tr.RootID != "call" || tr.Graph {
+		t.Errorf("name, root, graph = %q, %q, %v", tr.Name, tr.RootID, tr.Graph)
+	}
+	nodes := map[string]struct {
+		typ   model.NodeType
+		label string
+	}{
+		"call":    {model.StartEnd, "Call"},
+		"on":      {model.Decision, "On?"},
+		"photo":   {model.Action, "Ask for\nphoto"}, // defaults only apply to new nodes
+		"wait":    {model.IO, "wait"},
+		"reboot":  {model.Action, "reboot"},
+		"done":    {model.Action, "done"},
+		"the end": {model.StartEnd, "the end"},
+	}
+	for id, want := range nodes {
+		if n := tr.Nodes[id]; n == nil || n.Type != want.typ || n.Label != want.label {
+			t.Errorf("node %s = %+v, want %s %q", id, n, want.typ, want.label)
+		}
+	}
+	edges := []string{"call->on Is it on?", "on->photo no", "on->reboot no", "reboot->done next", "done->the end next"}
+	if len(tr.Edges) != len(edges) {
+		t.Fatalf("edges = %+v", tr.Edges)
+	}
+	for i, want := range edges {
+		e := tr.Edges[i]
+		if got := e.FromID + "->" + e.ToID + " " + e.Label; got != want {
+			t.Errorf("edge %d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestParseDOTErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"", `line 1: expected digraph, got end of file`},
+		{"graph g {\n  a -- b\n}\n", "line 1: undirected graphs are not supported"},
+		{"digraph {\n  a -- b\n}\n", `line 2: undirected edge "--"`},
+		{"digraph {\n  a [shape=hexagon]\n}\n", `line 2: unsupported shape "hexagon" for node a`},
+		{"digraph {\n  node [shape=star]\n  a\n}\n", `line 2: unsupported shape "star" for node a`},
+		{"digraph {\n  a -> b\n  a -> b [label=again]\n}\n", "line 3: duplicate link from a to b"},
+		{"digraph {\n  a [label=<<b>A</b>>]\n}\n", "line 2: HTML strings are not supported"},
+		{"digraph {\n  a [label=\"A]\n}\n", "line 2: unterminated string"},
+		{"digraph {\n  a ->\n}\n", `line 3: expected a node ID, got "}"`},
+		{"digraph {\n  a [label]\n}\n", `line 2: expected "=", got "]"`},
+		{"digraph {\n  a\n", `line 3: expected "}", got end of file`},
+		{"digraph {}\ndigraph {}\n", `line 2: unexpected "digraph" after the graph`},
+	}
+	for _, tt := range tests {
+		_, err := ParseDOT(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseDOT(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package importer reads trees drawn in other tools' diagram languages.
+// It is the inverse of the render package: each parser maps shapes back to
+// node types and labeled links back to edges.
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// parsers maps each import format to its parser.
+var parsers = map[string]func(string) (*model.Tree, error){
+	"mermaid": ParseMermaid,
+	"dot":     ParseDOT,
+}
+
+// Formats lists the names of the import formats.
+func Formats() []string {
+	return []string{"mermaid", "dot"}
+}
+
+// FormatForPath guesses an import format from a file extension. It returns
+// "" for files that are not in an import format.
+func FormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mmd", ".mermaid":
+		return "mermaid"
+	case ".dot", ".gv":
+		return "dot"
+	}
+	return ""
+}
+
+// Parse imports a tree from source text in the named format.
+func Parse(format, src string) (*model.Tree, error) {
+	parse, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown import format %q (use %s)", format, strings.Join(Formats(), " or "))
+	}
+	return parse(src)
+}
+
+// ImportFile reads and imports a file. A tree whose source gives it no
+// name is named after the file.
+func ImportFile(path, format string) (*model.Tree, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	t, err := Parse(format, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("import %s: %w", filepath.Base(path), err)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return t, nil
+}
+
+// builder collects nodes and edges in the order a diagram mentions them.
+type builder struct {
+	t     *model.Tree
+	order []string
+}
+
+func newBuilder() *builder {
+	return &builder{t: model.NewTree("")}
+}
+
+// node returns the node with the given ID, creating it as an action labeled
+// with its ID if the diagram has not mentioned it before.
+func (b *builder) node(id string) *model.Node {
+	if n := b.t.Nodes[id]; n != nil {
+		return n
+	}
+	n := &model.Node{ID: id, Type: model.Action, Label: id}
+	b.t.Nodes[id] = n
+	b.order = append(b.order, id)
+	return n
+}
+
+// edge adds an edge between two existing nodes.
+func (b *builder) edge(from, to, label string) error {
+	if b.t.HasEdge(from, to) {
+		return fmt.Errorf("duplicate link from %s to %s", from, to)
+	}
+	b.t.Edges = append(b.t.Edges, model.Edge{FromID: from, ToID: to, Label: label})
+	return nil
+}
+
+// finish picks the root, which is the first node without a parent (or the
+// first node if every node has one), turns on graph mode if the diagram
+// shares nodes or loops, and moves the ID counter past any nN IDs.
+func (b *builder) finish() *model.Tree {
+	t := b.t
+	for _, id := range b.order {
+		t.ReserveID(id)
+		if t.RootID == "" && len(t.Parents(id)) == 0 {
+			t.RootID = id
+		}
+	}
+	if t.RootID == "" && len(b.order) > 0 {
+		t.RootID = b.order[0]
+	}
+	t.Graph = tree.CheckTreeMode(t) != nil
+	return t
+}
@@ -0,0 +1,315 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// mermaidShapes lists the node shapes that map to node types, longest
+// opening bracket first. They mirror render.MermaidRenderer, plus round
+// and circle shapes, which are read as start/end nodes.
+var mermaidShapes = []struct {
+	open, close string
+	typ         model.NodeType
+}{
+	{"([", "])", model.StartEnd},
+	{"((", "))", model.StartEnd},
+	{"[/", "/]", model.IO},
+	{"[", "]", model.Action},
+	{"{", "}", model.Decision},
+	{"(", ")", model.StartEnd},
+}
+
+// mermaidOtherShapes are shapes with no node type, checked before
+// mermaidShapes so that "[[" is not read as "[".
+var mermaidOtherShapes = []string{"(((", "[[", "[(", "[\\", "{{", ">", "@{"}
+
+// mermaidIgnored lists statements that only affect styling or layout.
+// Subgraphs are flattened.
+var mermaidIgnored = map[string]bool{
+	"click": true, "style": true, "classDef": true, "class": true,
+	"linkStyle": true, "direction": true, "subgraph": true, "end": true,
+}
+
+var (
+	mermaidHeader = regexp.MustCompile(`^(?:flowchart|graph)(?:\s+(?:TB|TD|BT|LR|RL))?;?$`)
+	// mermaidArrow matches a link without inline text: -->, ---, -.->,
+	// -.-, ==>, === and their longer forms.
+	mermaidArrow = regexp.MustCompile(`^(?:-{2,}>|-{3,}|-\.+->|-\.+-|={2,}>|={3,})`)
+	// mermaidTextLink matches the start of a link with inline text, such
+	// as "-- yes -->"; mermaidTextEnd holds the matching ends.
+	mermaidTextLink = regexp.MustCompile(`^(?:--|-\.|==)`)
+	mermaidTextEnd  = map[string]*regexp.Regexp{
+		"--": regexp.MustCompile(`\s*(?:-{2,}>|-{3,})`),
+		"-.": regexp.MustCompile(`\s*\.-+>?`),
+		"==": regexp.MustCompile(`\s*(?:={2,}>|={3,})`),
+	}
+	mermaidPipeLabel   = regexp.MustCompile(`^\s*\|([^|]*)\|`)
+	mermaidOtherLink   = regexp.MustCompile(`^(?:<[-=.]\S*|[-=]{2,}[xo]\b|~~~)`)
+	mermaidEntity      = regexp.MustCompile(`#(\w+);`)
+	mermaidEntityNames = map[string]string{"quot": `"`, "amp": "&", "lt": "<", "gt": ">", "nbsp": " "}
+)
+
+// ParseMermaid imports a Mermaid flowchart. Node shapes become node types
+// ({} decision, ([]) start/end, [/ /] I/O, [] action) and links become
+// edges, labeled by "-- text -->" or "-->|text|". Styling statements are
+// ignored. Errors give the line number.
+func ParseMermaid(src string) (*model.Tree, error) {
+	b := newBuilder()
+	lines := strings.Split(src, "\n")
+	start, title := mermaidFrontMatter(lines)
+	b.t.Name = title
+	header := false
+	for i := start; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "%%") {
+			continue
+		}
+		if !header {
+			if !mermaidHeader.MatchString(line) {
+				return nil, fmt.Errorf("line %d: expected a flowchart, got %q", i+1, line)
+			}
+			header = true
+			continue
+		}
+		for _, stmt := range splitMermaidStatements(line) {
+			if err := parseMermaidStatement(b, stmt); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+	}
+	if !header {
+		return nil, errors.New("expected a flowchart, got an empty file")
+	}
+	return b.finish(), nil
+}
+
+// mermaidFrontMatter skips a "---" front matter block and returns the line
+// after it and the diagram title, if any.
+func mermaidFrontMatter(lines []string) (int, string) {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return 0, ""
+	}
+	title := ""
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "---" {
+			return i + 1, title
+		}
+		if v, ok := strings.CutPrefix(line, "title:"); ok {
+			title = strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	return 0, ""
+}
+
+// splitMermaidStatements splits a line at semicolons outside labels.
+func splitMermaidStatements(line string) []string {
+	var stmts []string
+	depth, start := 0, 0
+	quoted, piped := false, false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '|' && depth == 0:
+			piped = !piped
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			depth--
+		case c == ';' && depth <= 0 && !piped:
+			stmts = append(stmts, line[start:i])
+			start = i + 1
+		}
+	}
+	return append(stmts, line[start:])
+}
+
+// mermaidScanner reads one statement: a chain of node groups joined by
+// links, where a group is one or more nodes joined by "&".
+type mermaidScanner struct {
+	s   string
+	pos int
+}
+
+func parseMermaidStatement(b *builder, stmt string) error {
+	stmt = strings.TrimSpace(stmt)
+	if fields := strings.Fields(stmt); len(fields) == 0 || mermaidIgnored[fields[0]] {
+		return nil
+	}
+	p := &mermaidScanner{s: stmt}
+	left, err := p.nodeGroup(b)
+	if err != nil {
+		return err
+	}
+	for {
+		p.skipSpace()
+		if p.pos == len(p.s) {
+			return nil
+		}
+		label, err := p.link()
+		if err != nil {
+			return err
+		}
+		right, err := p.nodeGroup(b)
+		if err != nil {
+			return err
+		}
+		for _, from := range left {
+			for _, to := range right {
+				if err := b.edge(from, to, label); err != nil {
+					return err
+				}
+			}
+		}
+		left = right
+	}
+}
+
+func (p *mermaidScanner) rest() string { return p.s[p.pos:] }
+
+func (p *mermaidScanner) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *mermaidScanner) nodeGroup(b *builder) ([]string, error) {
+	var ids []string
+	for {
+		p.skipSpace()
+		id, err := p.node(b)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		p.skipSpace()
+		if !strings.HasPrefix(p.rest(), "&") {
+			return ids, nil
+		}
+		p.pos++
+	}
+}
+
+// node reads a node ID and an optional shape, and defines or updates the
+// node. A later shape for the same ID replaces the earlier one.
+func (p *mermaidScanner) node(b *builder) (string, error) {
+	start := p.pos
+	for p.pos < len(p.s) && isMermaidIDChar(p.s[p.pos]) {
+		p.pos++
+	}
+	id := p.s[start:p.pos]
+	if id == "" {
+		if p.pos == len(p.s) {
+			return "", errors.New("expected a node ID at end of line")
+		}
+		return "", fmt.Errorf("expected a node ID at %q", p.rest())
+	}
+	n := b.node(id)
+	rest := p.rest()
+	for _, open := range mermaidOtherShapes {
+		if strings.HasPrefix(rest, open) {
+			return "", fmt.Errorf("unsupported node shape %q for node %s", open, id)
+		}
+	}
+	for _, shape := range mermaidShapes {
+		if !strings.HasPrefix(rest, shape.open) {
+			continue
+		}
+		p.pos += len(shape.open)
+		label, err := p.shapeLabel(shape.close)
+		if err != nil {
+			return "", fmt.Errorf("node %s: %w", id, err)
+		}
+		n.Type, n.Label = shape.typ, label
+		break
+	}
+	if strings.HasPrefix(p.rest(), ":::") {
+		p.pos += 3
+		for p.pos < len(p.s) && isMermaidIDChar(p.s[p.pos]) {
+			p.pos++
+		}
+	}
+	return id, nil
+}
+
+// shapeLabel reads a node label up to the closing bracket of its shape.
+func (p *mermaidScanner) shapeLabel(close string) (string, error) {
+	rest := p.rest()
+	if strings.HasPrefix(rest, `"`) {
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		if !strings.HasPrefix(rest[end+2:], close) {
+			return "", fmt.Errorf("expected %q after the label", close)
+		}
+		p.pos += end + 2 + len(close)
+		return mermaidUnescape(rest[1 : end+1]), nil
+	}
+	end := strings.Index(rest, close)
+	if end < 0 {
+		return "", fmt.Errorf("missing %q", close)
+	}
+	p.pos += end + len(close)
+	return mermaidUnescape(strings.TrimSpace(rest[:end])), nil
+}
+
+// link reads a link and returns its label, if any.
+func (p *mermaidScanner) link() (string, error) {
+	rest := p.rest()
+	if m := mermaidOtherLink.FindString(rest); m != "" {
+		return "", fmt.Errorf("unsupported link %q", m)
+	}
+	if m := mermaidArrow.FindString(rest); m != "" {
+		p.pos += len(m)
+		if pm := mermaidPipeLabel.FindStringSubmatch(p.rest()); pm != nil {
+			p.pos += len(pm[0])
+			return mermaidLinkText(pm[1]), nil
+		}
+		return "", nil
+	}
+	if m := mermaidTextLink.FindString(rest); m != "" {
+		text := rest[len(m):]
+		loc := mermaidTextEnd[m].FindStringIndex(text)
+		if loc == nil {
+			return "", fmt.Errorf("unterminated link at %q", rest)
+		}
+		p.pos += len(m) + loc[1]
+		return mermaidLinkText(text[:loc[0]]), nil
+	}
+	return "", fmt.Errorf("expected a link or end of statement at %q", rest)
+}
+
+func mermaidLinkText(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	return mermaidUnescape(s)
+}
+
+// mermaidUnescape decodes entity codes such as #quot; and #35;.
+func mermaidUnescape(s string) string {
+	return mermaidEntity.ReplaceAllStringFunc(s, func(m string) string {
+		name := m[1 : len(m)-1]
+		if v, ok := mermaidEntityNames[name]; ok {
+			return v
+		}
+		if code, err := strconv.Atoi(name); err == nil {
+			return string(rune(code))
+		}
+		return m
+	})
+}
+
+func isMermaidIDChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
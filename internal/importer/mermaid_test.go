@@ -0,0 +1,191 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/render"
+	"github.com/jllovet/decision-tree-cli/internal/storage"
+)
+
+func testdataDir() string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(filename), "..", "..", "testdata")
+}
+
+func loadSample(t *testing.T) *model.Tree {
+	t.Helper()
+	tr, err := storage.Load(filepath.Join(testdataDir(), "sample-tree.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+// assertEquivalent checks that two trees have the same root, nodes (type
+// and label) and labeled edges in the same order.
+func assertEquivalent(t *testing.T, got, want *model.Tree) {
+	t.Helper()
+	if got.RootID != want.RootID || got.Graph != want.Graph || got.Counter != want.Counter {
+		t.Errorf("root, graph, counter = %q, %v, %d; want %q, %v, %d",
+			got.RootID, got.Graph, got.Counter, want.RootID, want.Graph, want.Counter)
+	}
+	if len(got.Nodes) != len(want.Nodes) {
+		t.Errorf("got %d nodes, want %d", len(got.Nodes), len(want.Nodes))
+	}
+	for id, w := range want.Nodes {
+		g := got.Nodes[id]
+		if g == nil || g.Type != w.Type || g.Label != w.Label {
+			t.Errorf("node %s = %+v, want %s %q", id, g, w.Type, w.Label)
+		}
+	}
+	if len(got.Edges) != len(want.Edges) {
+		t.Fatalf("edges = %+v, want %+v", got.Edges, want.Edges)
+	}
+	for i, w := range want.Edges {
+		if g := got.Edges[i]; g.FromID != w.FromID || g.ToID != w.ToID || g.Label != w.Label {
+			t.Errorf("edge %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestParseMermaidGolden(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join(testdataDir(), "expected-mermaid.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseMermaid(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEquivalent(t, got, loadSample(t))
+}
+
+func TestParseMermaidRoundTrip(t *testing.T) {
+	want := loadSample(t)
+	want.Graph = true
+	want.Nodes["n2"].Label = `Say "yes"?`
+	want.Edges = append(want.Edges, model.Edge{FromID: "n4", ToID: "n2", Label: "retry"}, model.Edge{FromID: "n5", ToID: "n1"})
+	text, err := (&render.MermaidRenderer{}).Render(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseMermaid(text)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, text)
+	}
+	assertEquivalent(t, got, want)
+}
+
+func TestParseMermaidSyntax(t *testing.T) {
+	src := `---
+title: Support
+---
+%% a comment
+graph LR
+  classDef red fill:#f00
+  A((Call)) -->|"Is it on?"| B{On?}:::red
+  B -- no --> C[/Ask for a photo/] & D[Turn it on]; D --> E(Done)
+  subgraph fix [Fix it]
+    B == yes ==> F["Reboot #quot;now#quot;"] --> E
+  end
+  C -.-> G
+  click A "https://example.com"
+  style B fill:#eee
+`
+	tr, err := ParseMermaid(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Name != "Support" || tr.RootID != "A" || !tr.Graph { // E has two parents
+		t.Errorf("name, root, graph = %q, %q, %v", tr.Name, tr.RootID, tr.Graph)
+	}
+	nodes := map[string]struct {
+		typ   model.NodeType
+		label string
+	}{
+		"A": {model.StartEnd, "Call"},
+		"B": {model.Decision, "On?"},
+		"C": {model.IO, "Ask for a photo"},
+		"D": {model.Action, "Turn it on"},
+		"E": {model.StartEnd, "Done"},
+		"F": {model.Action, `Reboot "now"`},
+		"G": {model.Action, "G"},
+	}
+	for id, want := range nodes {
+		if n := tr.Nodes[id]; n == nil || n.Type != want.typ || n.Label != want.label {
+			t.Errorf("node %s = %+v, want %s %q", id, n, want.typ, want.label)
+		}
+	}
+	edges := []string{"A->B Is it on?", "B->C no", "B->D no", "D->E ", "B->F yes", "F->E ", "C->G "}
+	if len(tr.Edges) != len(edges) {
+		t.Fatalf("edges = %+v", tr.Edges)
+	}
+	for i, want := range edges {
+		e := tr.Edges[i]
+		if got := e.FromID + "->" + e.ToID + " " + e.Label; got != want {
+			t.Errorf("edge %d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestParseMermaidGraphMode(t *testing.T) {
+	tr, err := ParseMermaid("flowchart TD\n  n1[Try] --> n2{Worked?}\n  n2 -. no .-> n1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tr.Graph || tr.RootID != "n1" || tr.Counter != 2 {
+		t.Errorf("graph, root, counter = %v, %q, %d", tr.Graph, tr.RootID, tr.Counter)
+	}
+	if e := tr.GetEdge("n2", "n1"); e == nil || e.Label != "no" {
+		t.Errorf("loop edge = %+v", e)
+	}
+}
+
+func TestParseMermaidErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"", "expected a flowchart"},
+		{"sequenceDiagram\n  A->>B: hi\n", `line 1: expected a flowchart, got "sequenceDiagram"`},
+		{"flowchart TB\n  A[[Sub]]\n", `line 2: unsupported node shape "[[" for node A`},
+		{"flowchart TB\n  A --> B\n  B{{Prep}}\n", `line 3: unsupported node shape "{{" for node B`},
+		{"flowchart TB\n\n  A <--> B\n", `line 3: unsupported link "<-->"`},
+		{"flowchart TB\n  A --x B\n", `line 2: unsupported link "--x"`},
+		{"flowchart TB\n  A[Start\n", `line 2: node A: missing "]"`},
+		{"flowchart TB\n  A -- yes B\n", "line 2: unterminated link"},
+		{"flowchart TB\n  A --> B\n  A --> B\n", "line 3: duplicate link from A to B"},
+		{"flowchart TB\n  A -->\n", "line 2: expected a node ID at end of line"},
+		{"flowchart TB\n  A B\n", `line 2: expected a link or end of statement at "B"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseMermaid(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseMermaid(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestImportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "my-flow.mmd")
+	os.WriteFile(path, []byte("flowchart TB\n  a[Go]\n"), 0644)
+	if FormatForPath(path) != "mermaid" {
+		t.Errorf("FormatForPath(%q) = %q", path, FormatForPath(path))
+	}
+	tr, err := ImportFile(path, "mermaid")
+	if err != nil || tr.Name != "my-flow" {
+		t.Fatalf("ImportFile = %+v, %v", tr, err)
+	}
+	if _, err := ImportFile(path, "svg"); err == nil || !strings.Contains(err.Error(), `unknown import format "svg"`) {
+		t.Errorf("error = %v", err)
+	}
+	os.WriteFile(path, []byte("flowchart TB\n  a[[x]]\n"), 0644)
+	if _, err := ImportFile(path, "mermaid"); err == nil || !strings.Contains(err.Error(), "import my-flow.mmd: line 2") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+// Package learn induces decision trees from tabular data using ID3-style
+// information gain or CART-style Gini impurity.
+package learn
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// minGain is the smallest impurity decrease worth splitting on.
+const minGain = 1e-12
+
+// Criterion selects the impurity measure used to choose splits.
+type Criterion int
+
+const (
+	Entropy Criterion = iota // Information gain (ID3)
+	Gini                     // Gini impurity (CART)
+)
+
+func (c Criterion) String() string {
+	switch c {
+	case Entropy:
+		return "entropy"
+	case Gini:
+		return "gini"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseCriterion converts a string to a Criterion.
+func ParseCriterion(s string) (Criterion, error) {
+	switch strings.ToLower(s) {
+	case "entropy", "gain", "id3":
+		return Entropy, nil
+	case "gini", "cart":
+		return Gini, nil
+	default:
+		return 0, fmt.Errorf("unknown criterion: %q", s)
+	}
+}
+
+// Options controls tree induction.
+type Options struct {
+	Target         string    // column holding the class to predict
+	Criterion      Criterion // impurity measure
+	MaxDepth       int       // maximum number of splits on any path; 0 means unlimited
+	MinSamplesLeaf int       // minimum rows in every leaf; values below 1 mean 1
+}
+
+// Dataset is a table of string values with named columns.
+type Dataset struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// ReadCSV reads a dataset whose first record holds the column names.
+func ReadCSV(r io.Reader) (*Dataset, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("csv has no header row")
+	}
+	ds := &Dataset{Columns: records[0]}
+	for i := range ds.Columns {
+		ds.Columns[i] = strings.TrimSpace(ds.Columns[i])
+	}
+	for _, rec := range records[1:] {
+		for i := range rec {
+			rec[i] = strings.TrimSpace(rec[i])
+		}
+		ds.Rows = append(ds.Rows, rec)
+	}
+	return ds, nil
+}
+
+// feature describes one input column.
+type feature struct {
+	name    string
+	col     int
+	numeric bool
+	values  []float64 // parsed values per row, when numeric
+}
+
+// split is a candidate partition of a node's rows.
+type split struct {
+	feature   *feature
+	threshold float64  // numeric splits: rows <= threshold go first
+	values    []string // categorical splits: one branch per value
+	groups    [][]int  // row indices per branch
+	gain      float64
+}
+
+// learner holds the state of one induction run.
+type learner struct {
+	ds     *Dataset
+	opts   Options
+	target int
+	t      *model.Tree
+}
+
+// Learn induces a classification tree. Splits become Decision nodes labeled
+// with the column name, branches are labeled with the category value or the
+// numeric threshold, and leaves are StartEnd nodes labeled with the
+// predicted class. Each split's condition is its column, so the branch
+// labels double as outcomes and the tree can be evaluated against records.
+// Every node records its sample count and class distribution in the
+// "samples" and "distribution" attributes.
+func Learn(ds *Dataset, opts Options) (*model.Tree, error) {
+	target := -1
+	for i, c := range ds.Columns {
+		if c == opts.Target {
+			target = i
+		}
+	}
+	if target < 0 {
+		return nil, fmt.Errorf("target column %q not found", opts.Target)
+	}
+	if len(ds.Rows) == 0 {
+		return nil, errors.New("dataset has no rows")
+	}
+	for i, row := range ds.Rows {
+		if len(row) != len(ds.Columns) {
+			return nil, fmt.Errorf("row %d has %d fields, want %d", i+2, len(row), len(ds.Columns))
+		}
+	}
+	if opts.MinSamplesLeaf < 1 {
+		opts.MinSamplesLeaf = 1
+	}
+
+	l := &learner{ds: ds, opts: opts, target: target, t: model.NewTree("learned")}
+	var features []*feature
+	for i, name := range ds.Columns {
+		if i != target {
+			features = append(features, l.newFeature(name, i))
+		}
+	}
+	rows := make([]int, len(ds.Rows))
+	for i := range rows {
+		rows[i] = i
+	}
+	root := l.grow(rows, features, 0)
+	tree.SetRoot(l.t, root)
+	return l.t, nil
+}
+
+// newFeature treats a column as numeric when every value parses as a number.
+func (l *learner) newFeature(name string, col int) *feature {
+	f := &feature{name: name, col: col, numeric: true}
+	for _, row := range l.ds.Rows {
+		v, err := strconv.ParseFloat(row[col], 64)
+		if err != nil {
+			return &feature{name: name, col: col}
+		}
+		f.values = append(f.values, v)
+	}
+	return f
+}
+
+// grow builds the subtree for rows and returns its node ID.
+func (l *learner) grow(rows []int, features []*feature, depth int) string {
+	counts := l.classCounts(rows)
+	pure := len(counts) == 1
+	atDepth := l.opts.MaxDepth > 0 && depth >= l.opts.MaxDepth
+	var best *split
+	if !pure && !atDepth && len(rows) >= 2*l.opts.MinSamplesLeaf {
+		best = l.bestSplit(rows, counts, features)
+	}
+	if best == nil {
+		id := tree.AddNode(l.t, model.StartEnd, majority(counts))
+		l.annotate(id, rows, counts)
+		return id
+	}
+
+	id := tree.AddNode(l.t, model.Decision, best.feature.name+"?")
+	tree.SetCondition(l.t, id, variableRef(best.feature.name))
+	l.annotate(id, rows, counts)
+	remaining := features
+	if !best.feature.numeric {
+		// A categorical column is exhausted once split on.
+		remaining = nil
+		for _, f := range features {
+			if f != best.feature {
+				remaining = append(remaining, f)
+			}
+		}
+	}
+	for i, group := range best.groups {
+		child := l.grow(group, remaining, depth+1)
+		tree.ConnectNodes(l.t, id, child, best.branchLabel(i))
+	}
+	return id
+}
+
+// variableRef writes a column name as an expression variable, quoting it
+// with backticks unless it is a plain identifier.
+func variableRef(name string) string {
+	switch name {
+	case "and", "or", "not", "true", "false", "null":
+		return "`" + name + "`"
+	}
+	for i, r := range name {
+		if !(r == '_' || unicode.IsLetter(r) || i > 0 && (unicode.IsDigit(r) || r == '.')) {
+			return "`" + name + "`"
+		}
+	}
+	return name
+}
+
+func (sp *split) branchLabel(i int) string {
+	if !sp.feature.numeric {
+		return sp.values[i]
+	}
+	t := strconv.FormatFloat(sp.threshold, 'g', -1, 64)
+	if i == 0 {
+		return "<= " + t
+	}
+	return "> " + t
+}
+
+func (l *learner) annotate(id string, rows []int, counts map[string]int) {
+	classes := sortedKeys(counts)
+	parts := make([]string, len(classes))
+	for i, c := range classes {
+		parts[i] = fmt.Sprintf("%s=%d", c, counts[c])
+	}
+	tree.SetNodeAttr(l.t, id, "samples", strconv.Itoa(len(rows)))
+	tree.SetNodeAttr(l.t, id, "distribution", strings.Join(parts, ", "))
+}
+
+// bestSplit returns the split with the highest impurity decrease, or nil if
+// none improves on the parent while respecting the leaf size limit.
+func (l *learner) bestSplit(rows []int, counts map[string]int, features []*feature) *split {
+	parent := l.impurity(counts, len(rows))
+	var best *split
+	for _, f := range features {
+		var candidates []*split
+		if f.numeric {
+			candidates = l.numericSplits(rows, f)
+		} else {
+			candidates = l.categoricalSplits(rows, f)
+		}
+		for _, sp := range candidates {
+			if !l.allowed(sp) {
+				continue
+			}
+			weighted := 0.0
+			for _, g := range sp.groups {
+				weighted += float64(len(g)) / float64(len(rows)) * l.impurity(l.classCounts(g), len(g))
+			}
+			sp.gain = parent - weighted
+			if sp.gain > minGain && (best == nil || sp.gain > best.gain+minGain) {
+				best = sp
+			}
+		}
+	}
+	return best
+}
+
+func (l *learner) allowed(sp *split) bool {
+	if len(sp.groups) < 2 {
+		return false
+	}
+	for _, g := range sp.groups {
+		if len(g) < l.opts.MinSamplesLeaf {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *learner) categoricalSplits(rows []int, f *feature) []*split {
+	byValue := make(map[string][]int)
+	for _, r := range rows {
+		v := l.ds.Rows[r][f.col]
+		byValue[v] = append(byValue[v], r)
+	}
+	sp := &split{feature: f, values: sortedKeys(byValue)}
+	for _, v := range sp.values {
+		sp.groups = append(sp.groups, byValue[v])
+	}
+	return []*split{sp}
+}
+
+// numericSplits proposes a binary split at the midpoint between each pair
+// of adjacent distinct values.
+func (l *learner) numericSplits(rows []int, f *feature) []*split {
+	sorted := append([]int(nil), rows...)
+	sort.SliceStable(sorted, func(i, j int) bool { return f.values[sorted[i]] < f.values[sorted[j]] })
+	var splits []*split
+	for i := 1; i < len(sorted); i++ {
+		lo, hi := f.values[sorted[i-1]], f.values[sorted[i]]
+		if lo == hi {
+			continue
+		}
+		splits = append(splits, &split{
+			feature:   f,
+			threshold: (lo + hi) / 2,
+			groups:    [][]int{sorted[:i], sorted[i:]},
+		})
+	}
+	return splits
+}
+
+func (l *learner) classCounts(rows []int) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range rows {
+		counts[l.ds.Rows[r][l.target]]++
+	}
+	return counts
+}
+
+func (l *learner) impurity(counts map[string]int, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	total := float64(n)
+	switch l.opts.Criterion {
+	case Gini:
+		g := 1.0
+		for _, c := range counts {
+			p := float64(c) / total
+			g -= p * p
+		}
+		return g
+	default:
+		h := 0.0
+		for _, c := range counts {
+			p := float64(c) / total
+			h -= p * math.Log2(p)
+		}
+		return h
+	}
+}
+
+// majority returns the most frequent class, breaking ties alphabetically.
+func majority(counts map[string]int) string {
+	best, bestCount := "", -1
+	for _, c := range sortedKeys(counts) {
+		if counts[c] > bestCount {
+			best, bestCount = c, counts[c]
+		}
+	}
+	return best
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
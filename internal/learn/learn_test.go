@@ -0,0 +1,236 @@
+package learn
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/eval"
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+const weatherCSV = `outlook,temp,humidity,windy,play
+sunny,hot,high,false,no
+sunny,hot,high,true,no
+overcast,hot,high,false,yes
+rainy,mild,high,false,yes
+rainy,cool,normal,false,yes
+rainy,cool,normal,true,no
+overcast,cool,normal,true,yes
+sunny,mild,high,false,no
+sunny,cool,normal,false,yes
+rainy,mild,normal,false,yes
+sunny,mild,normal,true,yes
+overcast,mild,high,true,yes
+overcast,hot,normal,false,yes
+rainy,mild,high,true,no
+`
+
+func mustRead(t *testing.T, data string) *Dataset {
+	t.Helper()
+	ds, err := ReadCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+// classify follows the learned tree for one record and returns the leaf label.
+func classify(t *testing.T, tr *model.Tree, record map[string]string) string {
+	t.Helper()
+	id := tr.RootID
+	for {
+		n := tr.Nodes[id]
+		children := tr.Children(id)
+		if len(children) == 0 {
+			return n.Label
+		}
+		attr := strings.TrimSuffix(n.Label, "?")
+		next := ""
+		for _, e := range children {
+			if e.Label == record[attr] {
+				next = e.ToID
+			}
+		}
+		if next == "" {
+			t.Fatalf("no branch for %s=%q at %s", attr, record[attr], id)
+		}
+		id = next
+	}
+}
+
+func leaves(tr *model.Tree) []string {
+	var ids []string
+	for _, id := range tr.NodeIDs() {
+		if len(tr.Children(id)) == 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestLearnID3Weather(t *testing.T) {
+	tr, err := Learn(mustRead(t, weatherCSV), Options{Target: "play"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tr.Nodes[tr.RootID]
+	if root.Label != "outlook?" || root.Type != model.Decision {
+		t.Fatalf("root = %s %q, want decision \"outlook?\"", root.Type, root.Label)
+	}
+	if got := len(tr.Children(tr.RootID)); got != 3 {
+		t.Errorf("root has %d branches, want 3", got)
+	}
+	if root.Attrs["samples"] != "14" || root.Attrs["distribution"] != "no=5, yes=9" {
+		t.Errorf("root attrs = %v", root.Attrs)
+	}
+
+	ds := mustRead(t, weatherCSV)
+	for _, row := range ds.Rows {
+		rec := make(map[string]string)
+		for i, c := range ds.Columns {
+			rec[c] = row[i]
+		}
+		if got := classify(t, tr, rec); got != rec["play"] {
+			t.Errorf("classify(%v) = %q, want %q", row, got, rec["play"])
+		}
+	}
+}
+
+func TestLearnGini(t *testing.T) {
+	tr, err := Learn(mustRead(t, weatherCSV), Options{Target: "play", Criterion: Gini})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaves(tr)) == 0 {
+		t.Fatal("expected leaves")
+	}
+	for _, id := range leaves(tr) {
+		if tr.Nodes[id].Type != model.StartEnd {
+			t.Errorf("leaf %s has type %s", id, tr.Nodes[id].Type)
+		}
+	}
+}
+
+func TestLearnNumericThreshold(t *testing.T) {
+	data := "age,buys\n22,no\n25,no\n30,no\n35,yes\n40,yes\n50,yes\n"
+	tr, err := Learn(mustRead(t, data), Options{Target: "buys"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges := tr.Children(tr.RootID)
+	if len(edges) != 2 {
+		t.Fatalf("got %d branches, want 2", len(edges))
+	}
+	if edges[0].Label != "<= 32.5" || edges[1].Label != "> 32.5" {
+		t.Errorf("branch labels = %q, %q", edges[0].Label, edges[1].Label)
+	}
+	if tr.Nodes[edges[0].ToID].Label != "no" || tr.Nodes[edges[1].ToID].Label != "yes" {
+		t.Errorf("leaves = %q, %q", tr.Nodes[edges[0].ToID].Label, tr.Nodes[edges[1].ToID].Label)
+	}
+}
+
+func TestLearnMaxDepth(t *testing.T) {
+	tr, err := Learn(mustRead(t, weatherCSV), Options{Target: "play", MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range tr.Children(tr.RootID) {
+		if len(tr.Children(e.ToID)) != 0 {
+			t.Errorf("node %s below depth limit has children", e.ToID)
+		}
+	}
+}
+
+func TestLearnMinSamplesLeaf(t *testing.T) {
+	tr, err := Learn(mustRead(t, weatherCSV), Options{Target: "play", MinSamplesLeaf: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range leaves(tr) {
+		if n, _ := strconv.Atoi(tr.Nodes[id].Attrs["samples"]); n < 5 {
+			t.Errorf("leaf %s has %d samples", id, n)
+		}
+	}
+	// With five rows per leaf no three-way outlook split is possible.
+	if tr.Nodes[tr.RootID].Label == "outlook?" {
+		t.Error("split should respect min samples per leaf")
+	}
+}
+
+func TestLearnPureData(t *testing.T) {
+	tr, err := Learn(mustRead(t, "x,y\na,yes\nb,yes\n"), Options{Target: "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Nodes) != 1 || tr.Nodes[tr.RootID].Label != "yes" {
+		t.Errorf("expected a single leaf, got %d nodes", len(tr.Nodes))
+	}
+}
+
+func TestLearnErrors(t *testing.T) {
+	if _, err := Learn(mustRead(t, weatherCSV), Options{Target: "missing"}); err == nil {
+		t.Error("expected error for unknown target")
+	}
+	if _, err := Learn(mustRead(t, "a,b\n"), Options{Target: "b"}); err == nil {
+		t.Error("expected error for empty dataset")
+	}
+	if _, err := ReadCSV(strings.NewReader("")); err == nil {
+		t.Error("expected error for missing header")
+	}
+}
+
+func TestParseCriterion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Criterion
+	}{
+		{"entropy", Entropy},
+		{"ID3", Entropy},
+		{"gini", Gini},
+		{"cart", Gini},
+	}
+	for _, tt := range tests {
+		got, err := ParseCriterion(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCriterion(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+	if _, err := ParseCriterion("bogus"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestLearnedTreeEvaluates(t *testing.T) {
+	data := "owns home,income,approve\nyes,40,yes\nyes,20,no\nno,60,yes\nno,10,no\n"
+	tr, err := Learn(mustRead(t, data), Options{Target: "approve"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := eval.New(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ev.Route(map[string]any{"owns home": "no", "income": 55.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Label != "yes" {
+		t.Errorf("routed to %q, want yes", res.Label)
+	}
+}
+
+func TestVariableRef(t *testing.T) {
+	tests := map[string]string{
+		"age":       "age",
+		"user.plan": "user.plan",
+		"owns home": "`owns home`",
+		"2nd":       "`2nd`",
+		"not":       "`not`",
+	}
+	for in, want := range tests {
+		if got := variableRef(in); got != want {
+			t.Errorf("variableRef(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package lint checks decision trees for problems that model.Tree.Validate
+// accepts: the tree is well formed, but a decision leads nowhere, a branch
+// is ambiguous or part of the tree can never be reached.
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// Severity is how serious a finding is.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSeverity converts a severity name to a Severity.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "info":
+		return Info, nil
+	case "warning", "warn":
+		return Warning, nil
+	case "error":
+		return Error, nil
+	default:
+		return 0, fmt.Errorf("unknown severity %q (use info, warning or error)", s)
+	}
+}
+
+// MarshalText writes the severity by name, as in JSON output.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads a severity name.
+func (s *Severity) UnmarshalText(text []byte) error {
+	v, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// Finding is one problem reported by a rule. NodeID, or From and To for an
+// edge, locate it in the tree.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	NodeID   string   `json:"node,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       string   `json:"to,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s [%s]", f.Severity, f.Message, f.Rule)
+}
+
+// Rule is one check. Check reports the problems it finds; Run fills in
+// each finding's rule ID and severity. Fix, if set, repairs them by
+// passing commands to apply, which executes each one before returning.
+// Fixes add nodes, rename branches, and remove the part of the tree the
+// root cannot reach, disconnected subtrees included; a dry run shows
+// exactly what would go.
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	Check       func(t *model.Tree) []Finding
+	Fix         func(t *model.Tree, apply func(tree.Command) error) error
+}
+
+// Run checks the tree against every rule, in order.
+func Run(t *model.Tree, rules []Rule) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		for _, f := range r.Check(t) {
+			f.Rule = r.ID
+			f.Severity = r.Severity
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Fix runs the repairs of the rules that have one, in order, so each
+// repair sees the tree as the ones before it left it. apply must execute
+// the command on t, usually through a History. Fix returns the commands
+// that were applied, including those before any error.
+func Fix(t *model.Tree, rules []Rule, apply func(tree.Command) error) ([]tree.Command, error) {
+	var applied []tree.Command
+	for _, r := range rules {
+		if r.Fix == nil {
+			continue
+		}
+		err := r.Fix(t, func(cmd tree.Command) error {
+			if err := apply(cmd); err != nil {
+				return err
+			}
+			applied = append(applied, cmd)
+			return nil
+		})
+		if err != nil {
+			return applied, fmt.Errorf("fixing %s: %w", r.ID, err)
+		}
+	}
+	return applied, nil
+}
+
+// Select returns the rules without the disabled IDs. It fails on an ID
+// that names no rule, so a typo does not silently lint less.
+func Select(rules []Rule, disabled []string) ([]Rule, error) {
+	off := make(map[string]bool)
+	for _, id := range disabled {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if Find(rules, id) == nil {
+			return nil, fmt.Errorf("unknown lint rule %q", id)
+		}
+		off[id] = true
+	}
+	var out []Rule
+	for _, r := range rules {
+		if !off[r.ID] {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// Find returns the rule with the given ID, or nil.
+func Find(rules []Rule, id string) *Rule {
+	for i := range rules {
+		if rules[i].ID == id {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// Count returns how many findings are at or above a severity.
+func Count(findings []Finding, min Severity) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity >= min {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary describes findings as "2 errors, 1 warning", or "no problems".
+func Summary(findings []Finding) string {
+	counts := make(map[Severity]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	var parts []string
+	for _, s := range []Severity{Error, Warning, Info} {
+		n := counts[s]
+		if n == 0 {
+			continue
+		}
+		name := s.String()
+		if n > 1 && s != Info {
+			name += "s"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", n, name))
+	}
+	if len(parts) == 0 {
+		return "no problems"
+	}
+	return strings.Join(parts, ", ")
+}
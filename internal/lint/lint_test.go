@@ -0,0 +1,71 @@
+package lint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func TestRunFillsRuleAndSeverity(t *testing.T) {
+	rules := []Rule{{
+		ID:       "always",
+		Severity: Warning,
+		Check: func(t *model.Tree) []Finding {
+			return []Finding{{NodeID: "n1", Message: "found"}}
+		},
+	}}
+	got := Run(model.NewTree("t"), rules)
+	if len(got) != 1 || got[0].Rule != "always" || got[0].Severity != Warning {
+		t.Fatalf("findings = %+v", got)
+	}
+	if s := got[0].String(); s != "warning: found [always]" {
+		t.Errorf("String = %q", s)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	rules, err := Select(DefaultRules(), []string{"leaf-not-end", " empty-label", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != len(DefaultRules())-2 || Find(rules, "leaf-not-end") != nil || Find(rules, "empty-label") != nil {
+		t.Errorf("rules = %+v", rules)
+	}
+	if _, err := Select(DefaultRules(), []string{"no-such-rule"}); err == nil {
+		t.Error("expected error for an unknown rule")
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	for _, s := range []Severity{Info, Warning, Error} {
+		got, err := ParseSeverity(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseSeverity(%q) = %v, %v", s, got, err)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("expected error for fatal")
+	}
+	data, _ := json.Marshal(Finding{Rule: "r", Severity: Error, Message: "m"})
+	if string(data) != `{"rule":"r","severity":"error","message":"m"}` {
+		t.Errorf("json = %s", data)
+	}
+	var f Finding
+	if err := json.Unmarshal(data, &f); err != nil || f.Severity != Error {
+		t.Errorf("unmarshal = %+v, %v", f, err)
+	}
+}
+
+func TestCountAndSummary(t *testing.T) {
+	findings := []Finding{{Severity: Error}, {Severity: Warning}, {Severity: Warning}, {Severity: Info}}
+	if n := Count(findings, Warning); n != 3 {
+		t.Errorf("Count(warning) = %d", n)
+	}
+	if s := Summary(findings); s != "1 error, 2 warnings, 1 info" {
+		t.Errorf("Summary = %q", s)
+	}
+	if s := Summary(nil); s != "no problems" {
+		t.Errorf("Summary(nil) = %q", s)
+	}
+}
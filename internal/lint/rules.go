@@ -0,0 +1,274 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// DefaultRules returns the built-in rules, in the order they run.
+func DefaultRules() []Rule {
+	return []Rule{
+		{"no-root", Error, "the tree has nodes but no root", checkNoRoot, nil},
+		{"multiple-roots", Error, "nodes other than the root have no parent", checkMultipleRoots, nil},
+		{"unreachable", Error, "nodes with a parent cannot be reached from the root", checkUnreachable, fixUnreachable},
+		{"decision-branches", Error, "decision nodes have fewer than two branches", checkDecisionBranches, nil},
+		{"duplicate-branch-label", Error, "branches from the same node have the same label", checkDuplicateLabels, nil},
+		{"unlabeled-branch", Warning, "branches from a decision node have no label", checkUnlabeledBranches, nil},
+		{"branch-label-case", Warning, "branch labels differ from others only in case", checkLabelCase, fixLabelCase},
+		{"leaf-not-end", Warning, "leaves are not start/end nodes", checkLeafNotEnd, fixLeafNotEnd},
+		{"empty-label", Warning, "nodes have an empty label", checkEmptyLabel, nil},
+	}
+}
+
+// ref names a node in messages by ID and label.
+func ref(n *model.Node) string {
+	return fmt.Sprintf("%s %q", n.ID, n.Label)
+}
+
+func checkNoRoot(t *model.Tree) []Finding {
+	if t.RootID != "" || len(t.Nodes) == 0 {
+		return nil
+	}
+	return []Finding{{Message: "no root set (set-root <node-id>)"}}
+}
+
+func checkMultipleRoots(t *model.Tree) []Finding {
+	if t.RootID == "" {
+		return nil
+	}
+	var findings []Finding
+	for _, id := range t.NodeIDs() {
+		if id != t.RootID && len(t.Parents(id)) == 0 {
+			findings = append(findings, Finding{NodeID: id,
+				Message: fmt.Sprintf("%s has no parent but is not the root %s", ref(t.Nodes[id]), t.RootID)})
+		}
+	}
+	return findings
+}
+
+// reachable returns the nodes that can be reached from the root, or nil
+// if the tree has no root.
+func reachable(t *model.Tree) map[string]bool {
+	if t.GetNode(t.RootID) == nil {
+		return nil
+	}
+	seen := map[string]bool{t.RootID: true}
+	queue := []string{t.RootID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, e := range t.Children(id) {
+			if !seen[e.ToID] {
+				seen[e.ToID] = true
+				queue = append(queue, e.ToID)
+			}
+		}
+	}
+	return seen
+}
+
+// checkUnreachable reports the nodes below a node with no parent, or on a
+// loop of their own. Parentless nodes are left to multiple-roots, so one
+// problem counts once.
+func checkUnreachable(t *model.Tree) []Finding {
+	seen := reachable(t)
+	if seen == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, id := range t.NodeIDs() {
+		if !seen[id] && len(t.Parents(id)) > 0 {
+			findings = append(findings, Finding{NodeID: id,
+				Message: fmt.Sprintf("%s cannot be reached from the root %s", ref(t.Nodes[id]), t.RootID)})
+		}
+	}
+	return findings
+}
+
+// fixUnreachable removes every node the root cannot reach: parentless
+// nodes, which multiple-roots reports, and whatever hangs below them.
+// Without a root there is no telling which part of the tree to keep.
+func fixUnreachable(t *model.Tree, apply func(tree.Command) error) error {
+	seen := reachable(t)
+	if seen == nil {
+		return nil
+	}
+	for _, id := range t.NodeIDs() {
+		if !seen[id] {
+			if err := apply(tree.NewRemoveNodeCmd(id)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkDecisionBranches(t *model.Tree) []Finding {
+	var findings []Finding
+	for _, id := range t.NodeIDs() {
+		n := t.Nodes[id]
+		if n.Type != model.Decision {
+			continue
+		}
+		switch k := len(t.Children(id)); k {
+		case 0:
+			findings = append(findings, Finding{NodeID: id, Message: fmt.Sprintf("decision %s has no branches", ref(n))})
+		case 1:
+			findings = append(findings, Finding{NodeID: id, Message: fmt.Sprintf("decision %s has only one branch", ref(n))})
+		}
+	}
+	return findings
+}
+
+// checkDuplicateLabels ignores case: "yes" and "YES" from one node are as
+// ambiguous as two "yes" branches.
+func checkDuplicateLabels(t *model.Tree) []Finding {
+	var findings []Finding
+	for _, id := range t.NodeIDs() {
+		first := make(map[string]string) // folded label -> first child with it
+		for _, e := range t.Children(id) {
+			label := strings.TrimSpace(e.Label)
+			if label == "" {
+				continue
+			}
+			key := strings.ToLower(label)
+			if prev, ok := first[key]; ok {
+				findings = append(findings, Finding{From: id, To: e.ToID,
+					Message: fmt.Sprintf("branches %s -> %s and %s -> %s are both labeled %q", id, prev, id, e.ToID, label)})
+				continue
+			}
+			first[key] = e.ToID
+		}
+	}
+	return findings
+}
+
+func checkUnlabeledBranches(t *model.Tree) []Finding {
+	var findings []Finding
+	for _, id := range t.NodeIDs() {
+		if t.Nodes[id].Type != model.Decision {
+			continue
+		}
+		for _, e := range t.Children(id) {
+			if strings.TrimSpace(e.Label) == "" {
+				findings = append(findings, Finding{From: id, To: e.ToID,
+					Message: fmt.Sprintf("branch %s -> %s from decision %s has no label", id, e.ToID, ref(t.Nodes[id]))})
+			}
+		}
+	}
+	return findings
+}
+
+func checkLeafNotEnd(t *model.Tree) []Finding {
+	var findings []Finding
+	for _, id := range t.NodeIDs() {
+		n := t.Nodes[id]
+		if n.Type != model.StartEnd && len(t.Children(id)) == 0 {
+			findings = append(findings, Finding{NodeID: id,
+				Message: fmt.Sprintf("%s %s is a leaf but not a start/end node", n.Type, ref(n))})
+		}
+	}
+	return findings
+}
+
+// fixLeafNotEnd ends action and io leaves with a new "End" node. A
+// decision leaf is missing its branches, not an ending, so it is left for
+// decision-branches to report.
+func fixLeafNotEnd(t *model.Tree, apply func(tree.Command) error) error {
+	for _, id := range t.NodeIDs() {
+		n := t.Nodes[id]
+		if (n.Type != model.Action && n.Type != model.IO) || len(t.Children(id)) > 0 {
+			continue
+		}
+		add := tree.NewAddNodeCmd(model.StartEnd, "End")
+		if err := apply(add); err != nil {
+			return err
+		}
+		type idGetter interface{ ID() string }
+		ig, ok := add.(idGetter)
+		if !ok {
+			return fmt.Errorf("cannot tell the ID of the End node added under %s", id)
+		}
+		if err := apply(tree.NewConnectCmd(id, ig.ID(), "")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkEmptyLabel(t *model.Tree) []Finding {
+	var findings []Finding
+	for _, id := range t.NodeIDs() {
+		if strings.TrimSpace(t.Nodes[id].Label) == "" {
+			findings = append(findings, Finding{NodeID: id, Message: fmt.Sprintf("%s has an empty label", id)})
+		}
+	}
+	return findings
+}
+
+// miscasedBranch is a branch whose label differs only in case from the
+// spelling the tree uses most, the first one used breaking ties. Branches
+// whose labels collide with a sibling's are left to
+// duplicate-branch-label: renaming one could not make them distinct.
+type miscasedBranch struct {
+	from, to    string
+	label, want string
+}
+
+func miscasedBranches(t *model.Tree) []miscasedBranch {
+	counts := make(map[string]int)
+	var spellings []string // in order of first use
+	for _, e := range t.Edges {
+		label := strings.TrimSpace(e.Label)
+		if label == "" {
+			continue
+		}
+		if counts[label] == 0 {
+			spellings = append(spellings, label)
+		}
+		counts[label]++
+	}
+	best := make(map[string]string) // folded label -> spelling to use
+	for _, label := range spellings {
+		key := strings.ToLower(label)
+		if b, ok := best[key]; !ok || counts[label] > counts[b] {
+			best[key] = label
+		}
+	}
+	siblings := make(map[[2]string]int) // from ID and folded label -> branches
+	for _, e := range t.Edges {
+		siblings[[2]string{e.FromID, strings.ToLower(strings.TrimSpace(e.Label))}]++
+	}
+	var out []miscasedBranch
+	for _, e := range t.Edges {
+		label := strings.TrimSpace(e.Label)
+		if label == "" || siblings[[2]string{e.FromID, strings.ToLower(label)}] > 1 {
+			continue
+		}
+		if want := best[strings.ToLower(label)]; label != want {
+			out = append(out, miscasedBranch{e.FromID, e.ToID, label, want})
+		}
+	}
+	return out
+}
+
+func checkLabelCase(t *model.Tree) []Finding {
+	var findings []Finding
+	for _, b := range miscasedBranches(t) {
+		findings = append(findings, Finding{From: b.from, To: b.to,
+			Message: fmt.Sprintf("branch %s -> %s is labeled %q but other branches use %q", b.from, b.to, b.label, b.want)})
+	}
+	return findings
+}
+
+func fixLabelCase(t *model.Tree, apply func(tree.Command) error) error {
+	for _, b := range miscasedBranches(t) {
+		if err := apply(tree.NewEditEdgeLabelCmd(b.from, b.to, b.want)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
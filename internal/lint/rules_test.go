@@ -0,0 +1,285 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// lintTree is Start -> Auth? with "yes" and "Yes" branches to two actions,
+// plus an unreachable decision and an unlabeled, empty io node.
+func lintTree() *model.Tree {
+	tr := model.NewTree("lint")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.StartEnd, Label: "Start"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Auth?"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Grant"}
+	tr.Nodes["n4"] = &model.Node{ID: "n4", Type: model.StartEnd, Label: "Done"}
+	tr.Nodes["n5"] = &model.Node{ID: "n5", Type: model.IO, Label: " "}
+	tr.Nodes["n6"] = &model.Node{ID: "n6", Type: model.Decision, Label: "Orphan"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n4", Label: "Yes"},
+		{FromID: "n2", ToID: "n5"},
+		{FromID: "n3", ToID: "n4"},
+	}
+	tr.RootID = "n1"
+	tr.Counter = 6
+	return tr
+}
+
+func TestDefaultRules(t *testing.T) {
+	tests := []struct {
+		rule string
+		want []string // node IDs, or from->to for edge findings
+	}{
+		{"no-root", nil},
+		{"multiple-roots", []string{"n6"}},
+		{"unreachable", nil}, // n6 is reported once, as a second root
+		{"decision-branches", []string{"n6"}},
+		{"duplicate-branch-label", []string{"n2->n4"}}, // "yes" and "Yes"
+		{"unlabeled-branch", []string{"n2->n5"}},
+		{"branch-label-case", nil}, // n2's "yes" and "Yes" are duplicates
+		{"leaf-not-end", []string{"n5", "n6"}},
+		{"empty-label", []string{"n5"}},
+	}
+	tr := lintTree()
+	for _, tt := range tests {
+		r := Find(DefaultRules(), tt.rule)
+		if r == nil {
+			t.Fatalf("no rule %s", tt.rule)
+		}
+		var got []string
+		for _, f := range r.Check(tr) {
+			if f.NodeID != "" {
+				got = append(got, f.NodeID)
+			} else {
+				got = append(got, f.From+"->"+f.To)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.rule, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.rule, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRulesDoNotOverlap(t *testing.T) {
+	tr := lintTree()
+	tr.Nodes["n7"] = &model.Node{ID: "n7", Type: model.StartEnd, Label: "Below orphan"}
+	tr.Edges = append(tr.Edges,
+		model.Edge{FromID: "n6", ToID: "n7", Label: "yes"},
+		model.Edge{FromID: "n6", ToID: "n4", Label: "yes"})
+
+	seen := make(map[string]string) // node or edge -> rule that reported it
+	for _, f := range Run(tr, DefaultRules()) {
+		if f.Severity != Error {
+			continue
+		}
+		key := f.NodeID
+		if key == "" {
+			key = f.From + "->" + f.To
+		}
+		if prev, ok := seen[key]; ok {
+			t.Errorf("%s reported by both %s and %s", key, prev, f.Rule)
+		}
+		seen[key] = f.Rule
+	}
+	want := map[string]string{"n6": "multiple-roots", "n7": "unreachable", "n6->n4": "duplicate-branch-label"}
+	for key, rule := range want {
+		if seen[key] != rule {
+			t.Errorf("%s reported by %q, want %s", key, seen[key], rule)
+		}
+	}
+}
+
+func TestNoRoot(t *testing.T) {
+	tr := lintTree()
+	tr.RootID = ""
+	findings := Run(tr, DefaultRules())
+	if len(findings) == 0 || findings[0].Rule != "no-root" {
+		t.Fatalf("findings = %+v", findings)
+	}
+	for _, f := range findings {
+		if f.Rule == "multiple-roots" || f.Rule == "unreachable" {
+			t.Errorf("%s should not run without a root: %+v", f.Rule, f)
+		}
+	}
+}
+
+func TestDecisionWithOneBranch(t *testing.T) {
+	tr := lintTree()
+	tr.Edges = tr.Edges[:2]
+	findings := Find(DefaultRules(), "decision-branches").Check(tr)
+	if len(findings) != 2 || findings[0].Message != `decision n2 "Auth?" has only one branch` {
+		t.Errorf("findings = %+v", findings)
+	}
+}
+
+func TestCleanTree(t *testing.T) {
+	tr := model.NewTree("clean")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Decision, Label: "Raining?"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.StartEnd, Label: "Umbrella"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.StartEnd, Label: "Walk"}
+	tr.Edges = []model.Edge{{FromID: "n1", ToID: "n2", Label: "yes"}, {FromID: "n1", ToID: "n3", Label: "no"}}
+	tr.RootID = "n1"
+	if findings := Run(tr, DefaultRules()); len(findings) != 0 {
+		t.Errorf("findings = %+v", findings)
+	}
+}
+
+func TestLabelCasePrefersCommonSpelling(t *testing.T) {
+	tr := lintTree()
+	tr.Edges[2].Label = "no" // n2->n4, so n2's branches no longer collide
+	tr.Nodes["n7"] = &model.Node{ID: "n7", Type: model.Decision, Label: "Again?"}
+	tr.Nodes["n8"] = &model.Node{ID: "n8", Type: model.Decision, Label: "Still?"}
+	tr.Edges = append(tr.Edges,
+		model.Edge{FromID: "n7", ToID: "n3", Label: "Yes"},
+		model.Edge{FromID: "n8", ToID: "n4", Label: "Yes"})
+	findings := Find(DefaultRules(), "branch-label-case").Check(tr)
+	if len(findings) != 1 || findings[0].From != "n2" || findings[0].To != "n3" {
+		t.Fatalf("findings = %+v", findings)
+	}
+	if want := `branch n2 -> n3 is labeled "yes" but other branches use "Yes"`; findings[0].Message != want {
+		t.Errorf("message = %q", findings[0].Message)
+	}
+}
+
+func TestFix(t *testing.T) {
+	tr := lintTree()
+	tr.Edges[2].Label = "no"  // n2->n4
+	tr.Edges[4].Label = "Yes" // n3->n4, against n2's earlier "yes"
+	h := tree.NewHistory()
+	var applied []tree.Command
+	err := h.Atomic(tr, func() error {
+		var err error
+		applied, err = Fix(tr, DefaultRules(), func(cmd tree.Command) error {
+			return h.Execute(tr, cmd)
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Remove n6, relabel n3->n4, and end n5 with a new node.
+	if len(applied) != 4 {
+		t.Fatalf("applied %d commands", len(applied))
+	}
+	if tr.GetNode("n6") != nil {
+		t.Error("orphan n6 not removed")
+	}
+	if e := tr.GetEdge("n3", "n4"); e == nil || e.Label != "yes" {
+		t.Errorf("n3->n4 = %+v", e)
+	}
+	end := tr.Children("n5")
+	if len(end) != 1 || tr.Nodes[end[0].ToID].Type != model.StartEnd || tr.Nodes[end[0].ToID].Label != "End" {
+		t.Errorf("n5 children = %+v", end)
+	}
+	for _, f := range Run(tr, DefaultRules()) {
+		switch f.Rule {
+		case "unreachable", "multiple-roots", "branch-label-case", "leaf-not-end":
+			t.Errorf("still reported after fix: %s", f)
+		}
+	}
+
+	// The whole fix is one undo step.
+	if err := h.Undo(tr); err != nil {
+		t.Fatal(err)
+	}
+	if tr.GetNode("n6") == nil || tr.GetEdge("n3", "n4").Label != "Yes" || len(tr.Children("n5")) != 0 {
+		t.Errorf("undo did not restore the tree: %+v", tr.Edges)
+	}
+	if h.CanUndo() {
+		t.Error("fix took more than one undo step")
+	}
+}
+
+func TestFixLabelCaseKeepsSiblingsDistinct(t *testing.T) {
+	// n2 has "yes", "no" and "YES"; n5 uses "yes" too, which makes it the
+	// common spelling, and n6 has a lone "Yes" that can safely be renamed.
+	tr := model.NewTree("case")
+	for _, id := range []string{"n1", "n2", "n3", "n4", "n5", "n6", "n7", "n8", "n9", "n10"} {
+		tr.Nodes[id] = &model.Node{ID: id, Type: model.StartEnd, Label: id}
+	}
+	for _, id := range []string{"n2", "n5", "n6"} {
+		tr.Nodes[id].Type = model.Decision
+	}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n4", Label: "no"},
+		{FromID: "n2", ToID: "n10", Label: "YES"},
+		{FromID: "n2", ToID: "n5", Label: "maybe"},
+		{FromID: "n5", ToID: "n7", Label: "yes"},
+		{FromID: "n5", ToID: "n6", Label: "no"},
+		{FromID: "n6", ToID: "n8", Label: "Yes"},
+		{FromID: "n6", ToID: "n9", Label: "no"},
+	}
+	tr.RootID = "n1"
+	tr.Counter = 10
+
+	errors := func() []string {
+		var out []string
+		for _, f := range Run(tr, DefaultRules()) {
+			if f.Severity == Error {
+				out = append(out, f.String())
+			}
+		}
+		return out
+	}
+	before := errors()
+	if _, err := Fix(tr, DefaultRules(), func(cmd tree.Command) error { return cmd.Execute(tr) }); err != nil {
+		t.Fatal(err)
+	}
+	after := errors()
+	if len(after) != len(before) || len(after) != 1 || !strings.Contains(after[0], "duplicate-branch-label") {
+		t.Errorf("errors before fix %v, after %v", before, after)
+	}
+	if got := tr.GetEdge("n2", "n10").Label; got != "YES" {
+		t.Errorf("n2->n10 renamed to %q", got)
+	}
+	if got := tr.GetEdge("n6", "n8").Label; got != "yes" {
+		t.Errorf("n6->n8 = %q, want yes", got)
+	}
+}
+
+func TestFixRemovesOrphanSubtrees(t *testing.T) {
+	tr := lintTree()
+	tr.Nodes["n7"] = &model.Node{ID: "n7", Type: model.Decision, Label: "Below orphan"}
+	tr.Nodes["n8"] = &model.Node{ID: "n8", Type: model.StartEnd, Label: "Leaf"}
+	tr.Edges = append(tr.Edges,
+		model.Edge{FromID: "n6", ToID: "n7", Label: "yes"},
+		model.Edge{FromID: "n7", ToID: "n8", Label: "yes"})
+	tr.ReserveID("n8")
+	if _, err := Fix(tr, DefaultRules(), func(cmd tree.Command) error { return cmd.Execute(tr) }); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"n6", "n7", "n8"} {
+		if tr.GetNode(id) != nil {
+			t.Errorf("unreachable %s not removed", id)
+		}
+	}
+	for _, f := range Run(tr, DefaultRules()) {
+		if f.Rule == "unreachable" || f.Rule == "multiple-roots" {
+			t.Errorf("still reported after fix: %s", f)
+		}
+	}
+}
+
+func TestFixLeavesDecisionLeaves(t *testing.T) {
+	tr := model.NewTree("t")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Decision, Label: "Why?"}
+	tr.RootID = "n1"
+	applied, err := Fix(tr, DefaultRules(), func(cmd tree.Command) error { return cmd.Execute(tr) })
+	if err != nil || len(applied) != 0 {
+		t.Errorf("applied %d, err %v", len(applied), err)
+	}
+}
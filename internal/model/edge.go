@@ -2,7 +2,41 @@ package model
 
 // Edge represents a directed connection between two nodes.
 type Edge struct {
-	FromID string `json:"from"`
-	ToID   string `json:"to"`
-	Label  string `json:"label,omitempty"`
+	FromID string            `json:"from"`
+	ToID   string            `json:"to"`
+	Label  string            `json:"label,omitempty"`
+	Attrs  map[string]string `json:"attrs,omitempty"` // free-form metadata
+	// Probability marks the edge as a chance outcome for expected-value
+	// analysis. Nil means the edge is a choice (or a plain step).
+	Probability *float64 `json:"probability,omitempty"`
+	// Outcome is the value of the source node's condition that selects this
+	// edge. Empty means the label is used instead.
+	Outcome string `json:"outcome,omitempty"`
+}
+
+// OutcomeText returns the edge's outcome, falling back to its label.
+func (e Edge) OutcomeText() string {
+	if e.Outcome != "" {
+		return e.Outcome
+	}
+	return e.Label
+}
+
+// Clone returns a deep copy of the edge.
+func (e Edge) Clone() Edge {
+	e.Attrs = CloneAttrs(e.Attrs)
+	e.Probability = cloneFloat(e.Probability)
+	return e
+}
+
+// EdgeKey identifies an edge by its endpoints. At most one edge exists
+// between any ordered pair of nodes.
+type EdgeKey struct {
+	FromID string
+	ToID   string
+}
+
+// Key returns the edge's endpoints as a comparable key.
+func (e Edge) Key() EdgeKey {
+	return EdgeKey{FromID: e.FromID, ToID: e.ToID}
 }
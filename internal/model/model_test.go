@@ -75,6 +75,20 @@ func TestNextID(t *testing.T) {
 	}
 }
 
+func TestReserveID(t *testing.T) {
+	tr := NewTree("test")
+	tr.Counter = 2
+	for _, id := range []string{"n7", "n3", "x9", "n", "nine"} {
+		tr.ReserveID(id)
+	}
+	if tr.Counter != 7 {
+		t.Errorf("Counter = %d, want 7", tr.Counter)
+	}
+	if id := tr.NextID(); id != "n8" {
+		t.Errorf("NextID = %q, want n8", id)
+	}
+}
+
 func TestTreeChildren(t *testing.T) {
 	tr := NewTree("test")
 	tr.Nodes["n1"] = &Node{ID: "n1", Type: Decision, Label: "root"}
@@ -173,3 +187,107 @@ func TestNodeIDs(t *testing.T) {
 		t.Errorf("NodeIDs() = %v, want [a b]", ids)
 	}
 }
+
+func TestParents(t *testing.T) {
+	tr := NewTree("test")
+	tr.Edges = []Edge{
+		{FromID: "a", ToID: "c"},
+		{FromID: "b", ToID: "c"},
+		{FromID: "a", ToID: "b"},
+	}
+	if got := tr.Parents("c"); len(got) != 2 {
+		t.Errorf("Parents(c) = %v, want 2 edges", got)
+	}
+	if got := tr.Parents("a"); len(got) != 0 {
+		t.Errorf("Parents(a) = %v, want none", got)
+	}
+}
+
+func TestAncestorsGraph(t *testing.T) {
+	tr := NewTree("test")
+	tr.Graph = true
+	tr.Edges = []Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3"},
+		{FromID: "n4", ToID: "n3"},
+		{FromID: "n3", ToID: "n2"},
+	}
+	anc := tr.Ancestors("n3")
+	for _, id := range []string{"n1", "n2", "n3", "n4"} {
+		if !anc[id] {
+			t.Errorf("expected %s among ancestors of n3, got %v", id, anc)
+		}
+	}
+}
+
+func TestBackEdges(t *testing.T) {
+	tr := NewTree("test")
+	tr.Graph = true
+	tr.RootID = "n1"
+	for _, id := range []string{"n1", "n2", "n3", "n4"} {
+		tr.Nodes[id] = &Node{ID: id}
+	}
+	tr.Edges = []Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n4", Label: "no"},
+		{FromID: "n4", ToID: "n2", Label: "retry"},
+		{FromID: "n4", ToID: "n3"},
+	}
+	back := tr.BackEdges()
+	if len(back) != 1 || !back[EdgeKey{"n4", "n2"}] {
+		t.Errorf("BackEdges() = %v, want only n4->n2", back)
+	}
+}
+
+func TestBackEdgesTree(t *testing.T) {
+	tr := NewTree("test")
+	tr.RootID = "n1"
+	tr.Nodes["n1"] = &Node{ID: "n1"}
+	tr.Nodes["n2"] = &Node{ID: "n2"}
+	tr.Edges = []Edge{{FromID: "n1", ToID: "n2"}}
+	if back := tr.BackEdges(); len(back) != 0 {
+		t.Errorf("BackEdges() = %v, want none", back)
+	}
+}
+
+func TestGetEdge(t *testing.T) {
+	tr := NewTree("test")
+	tr.Edges = []Edge{{FromID: "a", ToID: "b", Label: "yes"}}
+	e := tr.GetEdge("a", "b")
+	if e == nil || e.Label != "yes" {
+		t.Fatalf("GetEdge(a, b) = %v", e)
+	}
+	e.Label = "no"
+	if tr.Edges[0].Label != "no" {
+		t.Error("GetEdge should return a pointer into the tree")
+	}
+	if tr.GetEdge("b", "a") != nil {
+		t.Error("expected nil for missing edge")
+	}
+}
+
+func TestTreeClone(t *testing.T) {
+	tr := NewTree("test")
+	tr.Nodes["n1"] = &Node{ID: "n1", Label: "a", Attrs: map[string]string{"owner": "alice"}}
+	tr.Edges = []Edge{{FromID: "n1", ToID: "n1", Attrs: map[string]string{"sla": "4h"}}}
+
+	c := tr.Clone()
+	c.Nodes["n1"].Label = "changed"
+	c.Nodes["n1"].Attrs["owner"] = "bob"
+	c.Edges[0].Attrs["sla"] = "1d"
+
+	if tr.Nodes["n1"].Label != "a" || tr.Nodes["n1"].Attrs["owner"] != "alice" {
+		t.Error("clone should not share nodes with the original")
+	}
+	if tr.Edges[0].Attrs["sla"] != "4h" {
+		t.Error("clone should not share edge attributes with the original")
+	}
+}
+
+func TestSortedAttrKeys(t *testing.T) {
+	keys := SortedAttrKeys(map[string]string{"sla": "", "owner": "", "ticket": ""})
+	if len(keys) != 3 || keys[0] != "owner" || keys[1] != "sla" || keys[2] != "ticket" {
+		t.Errorf("SortedAttrKeys() = %v", keys)
+	}
+}
@@ -1,6 +1,9 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 // NodeType represents the visual shape/type of a node in a decision tree.
 type NodeType int
@@ -45,7 +48,53 @@ func ParseNodeType(s string) (NodeType, error) {
 
 // Node represents a single node in a decision tree.
 type Node struct {
-	ID    string   `json:"id"`
-	Type  NodeType `json:"type"`
-	Label string   `json:"label"`
+	ID    string            `json:"id"`
+	Type  NodeType          `json:"type"`
+	Label string            `json:"label"`
+	Attrs map[string]string `json:"attrs,omitempty"` // free-form metadata (owner, ticket, SLA...)
+	// Payoff is the value (or, if negative, the cost) of reaching this node,
+	// used by expected-value analysis. Nil means none.
+	Payoff *float64 `json:"payoff,omitempty"`
+	// Condition is an expression over named input variables. Its value
+	// selects the outgoing edge whose outcome matches when the tree is
+	// evaluated against a record.
+	Condition string `json:"condition,omitempty"`
+}
+
+// Clone returns a deep copy of the node.
+func (n *Node) Clone() *Node {
+	c := *n
+	c.Attrs = CloneAttrs(n.Attrs)
+	c.Payoff = cloneFloat(n.Payoff)
+	return &c
+}
+
+func cloneFloat(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	v := *f
+	return &v
+}
+
+// CloneAttrs returns a copy of an attribute map, or nil if it is empty.
+func CloneAttrs(attrs map[string]string) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		c[k] = v
+	}
+	return c
+}
+
+// SortedAttrKeys returns the keys of an attribute map in sorted order.
+func SortedAttrKeys(attrs map[string]string) []string {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -3,6 +3,8 @@ package model
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 // Tree represents a decision tree with nodes and edges.
@@ -12,6 +14,9 @@ type Tree struct {
 	Nodes   map[string]*Node `json:"nodes"`
 	Edges   []Edge           `json:"edges"`
 	Counter int              `json:"counter"`
+	// Graph enables graph mode, in which a node may have several parents
+	// and edges may loop back to earlier nodes.
+	Graph bool `json:"graph,omitempty"`
 }
 
 // NewTree creates a new empty tree with the given name.
@@ -28,11 +33,47 @@ func (t *Tree) NextID() string {
 	return fmt.Sprintf("n%d", t.Counter)
 }
 
+// ReserveID moves the counter past id if it has the "nN" form NextID
+// uses, so NextID never hands it out again. Anything that puts a node
+// back under an ID it was given earlier must call it.
+func (t *Tree) ReserveID(id string) {
+	if !strings.HasPrefix(id, "n") {
+		return
+	}
+	if n, err := strconv.Atoi(id[1:]); err == nil && n > t.Counter {
+		t.Counter = n
+	}
+}
+
+// Clone returns a deep copy of the tree.
+func (t *Tree) Clone() *Tree {
+	c := *t
+	c.Nodes = make(map[string]*Node, len(t.Nodes))
+	for id, n := range t.Nodes {
+		c.Nodes[id] = n.Clone()
+	}
+	c.Edges = make([]Edge, len(t.Edges))
+	for i, e := range t.Edges {
+		c.Edges[i] = e.Clone()
+	}
+	return &c
+}
+
 // GetNode returns the node with the given ID, or nil if not found.
 func (t *Tree) GetNode(id string) *Node {
 	return t.Nodes[id]
 }
 
+// GetEdge returns the edge between two nodes, or nil if there is none.
+func (t *Tree) GetEdge(fromID, toID string) *Edge {
+	for i := range t.Edges {
+		if t.Edges[i].FromID == fromID && t.Edges[i].ToID == toID {
+			return &t.Edges[i]
+		}
+	}
+	return nil
+}
+
 // Children returns the IDs of all children of the given node, along with their edge labels.
 func (t *Tree) Children(nodeID string) []Edge {
 	var children []Edge
@@ -54,6 +95,18 @@ func (t *Tree) Parent(nodeID string) *Edge {
 	return nil
 }
 
+// Parents returns all edges pointing at the given node. Outside graph mode
+// there is at most one.
+func (t *Tree) Parents(nodeID string) []Edge {
+	var parents []Edge
+	for _, e := range t.Edges {
+		if e.ToID == nodeID {
+			parents = append(parents, e)
+		}
+	}
+	return parents
+}
+
 // HasEdge checks if an edge exists between two nodes.
 func (t *Tree) HasEdge(fromID, toID string) bool {
 	for _, e := range t.Edges {
@@ -97,19 +150,56 @@ func (t *Tree) NodeIDs() []string {
 }
 
 // Ancestors returns the set of ancestor node IDs for the given node by walking parent edges.
+// In graph mode every parent is followed; the node itself is included only if it lies on a cycle.
 func (t *Tree) Ancestors(nodeID string) map[string]bool {
 	ancestors := make(map[string]bool)
-	current := nodeID
-	for {
-		p := t.Parent(current)
-		if p == nil {
-			break
-		}
-		if ancestors[p.FromID] {
-			break // cycle detected, stop
+	queue := []string{nodeID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, p := range t.Parents(current) {
+			if ancestors[p.FromID] {
+				continue // already visited (shared ancestor or cycle)
+			}
+			ancestors[p.FromID] = true
+			queue = append(queue, p.FromID)
 		}
-		ancestors[p.FromID] = true
-		current = p.FromID
 	}
 	return ancestors
 }
+
+// BackEdges returns the edges that loop back to a node still being visited
+// during a depth-first walk from the root (and then from any unreachable
+// nodes, in ID order). Following every other edge never revisits a node on
+// the current path, so renderers and walkers use this to stop at loops.
+func (t *Tree) BackEdges() map[EdgeKey]bool {
+	back := make(map[EdgeKey]bool)
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int)
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = onPath
+		for _, e := range t.Children(id) {
+			switch state[e.ToID] {
+			case onPath:
+				back[e.Key()] = true
+			case unvisited:
+				visit(e.ToID)
+			}
+		}
+		state[id] = done
+	}
+	if _, ok := t.Nodes[t.RootID]; ok {
+		visit(t.RootID)
+	}
+	for _, id := range t.NodeIDs() {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return back
+}
@@ -8,6 +8,8 @@ import (
 )
 
 // Render produces an ASCII tree preview using box-drawing characters.
+// A node reached a second time (a shared node or a loop in graph mode) is
+// shown as a "↪" back-reference instead of being expanded again.
 func Render(t *model.Tree) string {
 	if t.RootID == "" {
 		return "(no root set)"
@@ -16,15 +18,17 @@ func Render(t *model.Tree) string {
 		return "(root node not found)"
 	}
 	var b strings.Builder
-	renderNode(&b, t, t.RootID, "", "", true, true)
+	renderNode(&b, t, make(map[string]bool), t.RootID, "", "", true, true)
 	return b.String()
 }
 
-func renderNode(b *strings.Builder, t *model.Tree, nodeID, edgeLabel, prefix string, isLast, isRoot bool) {
+func renderNode(b *strings.Builder, t *model.Tree, seen map[string]bool, nodeID, edgeLabel, prefix string, isLast, isRoot bool) {
 	n := t.GetNode(nodeID)
 	if n == nil {
 		return
 	}
+	ref := seen[nodeID]
+	seen[nodeID] = true
 
 	// Edge label prefix
 	edgePart := ""
@@ -32,14 +36,22 @@ func renderNode(b *strings.Builder, t *model.Tree, nodeID, edgeLabel, prefix str
 		edgePart = "[" + edgeLabel + "] "
 	}
 
+	nodePart := nodeDecorator(n)
+	if ref {
+		nodePart = refDecorator(n)
+	}
+
 	if isRoot {
-		b.WriteString(edgePart + nodeDecorator(n) + "\n")
+		b.WriteString(edgePart + nodePart + "\n")
 	} else {
 		connector := "├── "
 		if isLast {
 			connector = "└── "
 		}
-		b.WriteString(prefix + connector + edgePart + nodeDecorator(n) + "\n")
+		b.WriteString(prefix + connector + edgePart + nodePart + "\n")
+	}
+	if ref {
+		return
 	}
 
 	// Child prefix
@@ -55,7 +67,7 @@ func renderNode(b *strings.Builder, t *model.Tree, nodeID, edgeLabel, prefix str
 	children := t.Children(nodeID)
 	for i, e := range children {
 		last := i == len(children)-1
-		renderNode(b, t, e.ToID, e.Label, childPrefix, last, false)
+		renderNode(b, t, seen, e.ToID, e.Label, childPrefix, last, false)
 	}
 }
 
@@ -73,3 +85,8 @@ func nodeDecorator(n *model.Node) string {
 		return n.Label
 	}
 }
+
+// refDecorator marks a node that has already been shown elsewhere in the preview.
+func refDecorator(n *model.Node) string {
+	return fmt.Sprintf("↪ %s (%s)", nodeDecorator(n), n.ID)
+}
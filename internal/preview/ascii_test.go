@@ -73,3 +73,27 @@ func TestAsciiDeepTree(t *testing.T) {
 		t.Errorf("missing IO decorator in:\n%s", out)
 	}
 }
+
+func TestAsciiGraphBackReference(t *testing.T) {
+	tr := model.NewTree("test")
+	tr.Graph = true
+	tr.RootID = "n1"
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Decision, Label: "Authenticated?"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "Grant access"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Show login form"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2", Label: "yes"},
+		{FromID: "n1", ToID: "n3", Label: "no"},
+		{FromID: "n3", ToID: "n1", Label: "retry"},
+		{FromID: "n3", ToID: "n2"},
+	}
+
+	want := "<Authenticated?>\n" +
+		"├── [yes] [Grant access]\n" +
+		"└── [no] [Show login form]\n" +
+		"    ├── [retry] ↪ <Authenticated?> (n1)\n" +
+		"    └── ↪ [Grant access] (n2)\n"
+	if got := Render(tr); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
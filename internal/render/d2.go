@@ -0,0 +1,106 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// D2Renderer renders a tree as D2 source. Edges that loop back to an
+// earlier node are drawn dashed.
+type D2Renderer struct {
+	// TooltipAttrs lists metadata keys to pass through as node and edge tooltips.
+	TooltipAttrs []string
+	Options      Options
+}
+
+// d2Directions maps flow directions to D2's names for them.
+var d2Directions = map[string]string{"TB": "down", "LR": "right", "BT": "up", "RL": "left"}
+
+func (r *D2Renderer) Render(t *model.Tree) (string, error) {
+	v, err := r.Options.prepare(t)
+	if err != nil {
+		return "", err
+	}
+	t = v.Tree
+	var b strings.Builder
+	b.WriteString("direction: " + d2Directions[v.dir] + "\n")
+
+	for _, id := range t.NodeIDs() {
+		n := t.Nodes[id]
+		b.WriteString(fmt.Sprintf("\n%s: %s {\n", d2Key(id), d2String(n.Label)))
+		b.WriteString(fmt.Sprintf("  shape: %s\n", d2Shape(n.Type)))
+		if fill := r.Options.fill(n.Type, ""); fill != "" {
+			b.WriteString(fmt.Sprintf("  style.fill: %s\n", d2String(fill)))
+		}
+		if v.hl.node(id) {
+			b.WriteString(fmt.Sprintf("  style.stroke: %s\n  style.stroke-width: 3\n", d2String(highlightColor)))
+		}
+		if text := tooltipText(n.Attrs, r.TooltipAttrs); text != "" {
+			b.WriteString(fmt.Sprintf("  tooltip: %s\n", d2String(text)))
+		}
+		b.WriteString("}\n")
+	}
+
+	if len(t.Edges) > 0 {
+		b.WriteString("\n")
+	}
+	back := t.BackEdges()
+	for _, e := range t.Edges {
+		conn := d2Key(e.FromID) + " -> " + d2Key(e.ToID)
+		if e.Label != "" {
+			conn += ": " + d2String(e.Label)
+		}
+		var attrs []string
+		if back[e.Key()] {
+			attrs = append(attrs, "style.stroke-dash: 3")
+		}
+		if v.hl.edge(e) {
+			attrs = append(attrs, "style.stroke: "+d2String(highlightColor), "style.stroke-width: 3")
+		}
+		if text := tooltipText(e.Attrs, r.TooltipAttrs); text != "" {
+			attrs = append(attrs, "tooltip: "+d2String(text))
+		}
+		if len(attrs) == 0 {
+			b.WriteString(conn + "\n")
+			continue
+		}
+		b.WriteString(conn + " {\n")
+		for _, a := range attrs {
+			b.WriteString("  " + a + "\n")
+		}
+		b.WriteString("}\n")
+	}
+
+	return b.String(), nil
+}
+
+func d2Shape(t model.NodeType) string {
+	switch t {
+	case model.Decision:
+		return "diamond"
+	case model.StartEnd:
+		return "oval"
+	case model.IO:
+		return "parallelogram"
+	default:
+		return "rectangle"
+	}
+}
+
+// d2Key writes a node ID as a D2 key, quoting it unless it is a plain
+// identifier. A bare dot would otherwise nest the node in a container.
+func d2Key(id string) string {
+	if id != "" && dotID(id) == id {
+		return id
+	}
+	return d2String(id)
+}
+
+func d2String(s string) string {
+	escaped := strings.ReplaceAll(s, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+	escaped = strings.ReplaceAll(escaped, "\n", `\n`)
+	return `"` + escaped + `"`
+}
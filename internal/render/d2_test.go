@@ -0,0 +1,76 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func TestD2Renderer(t *testing.T) {
+	tr := model.NewTree("retry")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: `Run "job"`, Attrs: map[string]string{"owner": "ops"}}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Worked?"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.IO, Label: "Send\nreport"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n1", Label: "no", Attrs: map[string]string{"owner": "sre"}},
+	}
+	tr.RootID = "n1"
+
+	out, err := (&D2Renderer{TooltipAttrs: []string{"owner"}, Options: Options{Direction: "LR"}}).Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := `direction: right
+
+n1: "Run \"job\"" {
+  shape: rectangle
+  tooltip: "owner: ops"
+}
+
+n2: "Worked?" {
+  shape: diamond
+}
+
+n3: "Send\nreport" {
+  shape: parallelogram
+}
+
+n1 -> n2
+n2 -> n3: "yes"
+n2 -> n1: "no" {
+  style.stroke-dash: 3
+  tooltip: "owner: sre"
+}
+`
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestD2QuotesKeys(t *testing.T) {
+	tr := model.NewTree("")
+	tr.Nodes["a.b"] = &model.Node{ID: "a.b", Type: model.StartEnd, Label: "Start"}
+	tr.Nodes["c-d"] = &model.Node{ID: "c-d", Type: model.Action, Label: "Go"}
+	tr.Edges = []model.Edge{{FromID: "a.b", ToID: "c-d"}}
+	tr.RootID = "a.b"
+
+	out, err := (&D2Renderer{}).Render(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"a.b": "Start" {`, `"c-d": "Go" {`, `"a.b" -> "c-d"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestD2BadDirection(t *testing.T) {
+	_, err := (&D2Renderer{Options: Options{Direction: "sideways"}}).Render(model.NewTree(""))
+	if err == nil || !strings.Contains(err.Error(), `unknown direction "sideways"`) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -7,28 +7,66 @@ import (
 	"github.com/jllovet/decision-tree-cli/internal/model"
 )
 
-// DOTRenderer renders a tree as a Graphviz DOT diagram.
-type DOTRenderer struct{}
+// DOTRenderer renders a tree as a Graphviz DOT diagram. Edges that loop
+// back to an earlier node are drawn dashed.
+type DOTRenderer struct {
+	// TooltipAttrs lists metadata keys to pass through as node and edge tooltips.
+	TooltipAttrs []string
+	Options      Options
+}
 
 func (r *DOTRenderer) Render(t *model.Tree) (string, error) {
+	v, err := r.Options.prepare(t)
+	if err != nil {
+		return "", err
+	}
+	t = v.Tree
 	var b strings.Builder
 	b.WriteString("digraph ")
 	b.WriteString(dotID(t.Name))
 	b.WriteString(" {\n")
-	b.WriteString("  rankdir=TB;\n\n")
+	b.WriteString("  rankdir=" + v.dir + ";\n\n")
 
 	for _, id := range t.NodeIDs() {
 		n := t.Nodes[id]
 		shape := dotShape(n.Type)
-		b.WriteString(fmt.Sprintf("  %s [label=%s, shape=%s];\n", id, dotLabel(n.Label), shape))
+		extra := ""
+		if fill := r.Options.fill(n.Type, ""); fill != "" {
+			extra += ", style=filled, fillcolor=" + dotLabel(fill)
+		}
+		if c := r.Options.NodeColors[id]; c != "" {
+			extra += ", color=" + dotLabel(c) + ", penwidth=2"
+		} else if v.hl.node(id) {
+			extra += ", color=" + dotLabel(highlightColor) + ", penwidth=2"
+		}
+		if text := tooltipText(n.Attrs, r.TooltipAttrs); text != "" {
+			extra += ", tooltip=" + dotLabel(text)
+		}
+		b.WriteString(fmt.Sprintf("  %s [label=%s, shape=%s%s];\n", id, dotLabel(n.Label), shape, extra))
 	}
 
 	if len(t.Edges) > 0 {
 		b.WriteString("\n")
 	}
+	back := t.BackEdges()
 	for _, e := range t.Edges {
+		var attrs []string
 		if e.Label != "" {
-			b.WriteString(fmt.Sprintf("  %s -> %s [label=%s];\n", e.FromID, e.ToID, dotLabel(e.Label)))
+			attrs = append(attrs, "label="+dotLabel(e.Label))
+		}
+		if back[e.Key()] {
+			attrs = append(attrs, "style=dashed")
+		}
+		if c := r.Options.EdgeColors[e.Key()]; c != "" {
+			attrs = append(attrs, "color="+dotLabel(c), "fontcolor="+dotLabel(c), "penwidth=2")
+		} else if v.hl.edge(e) {
+			attrs = append(attrs, "color="+dotLabel(highlightColor), "penwidth=2")
+		}
+		if text := tooltipText(e.Attrs, r.TooltipAttrs); text != "" {
+			attrs = append(attrs, "tooltip="+dotLabel(text))
+		}
+		if len(attrs) > 0 {
+			b.WriteString(fmt.Sprintf("  %s -> %s [%s];\n", e.FromID, e.ToID, strings.Join(attrs, ", ")))
 		} else {
 			b.WriteString(fmt.Sprintf("  %s -> %s;\n", e.FromID, e.ToID))
 		}
@@ -56,6 +94,7 @@ func dotShape(t model.NodeType) string {
 func dotLabel(s string) string {
 	escaped := strings.ReplaceAll(s, `\`, `\\`)
 	escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+	escaped = strings.ReplaceAll(escaped, "\n", `\n`)
 	return `"` + escaped + `"`
 }
 
@@ -70,8 +70,57 @@ func TestDOTEscaping(t *testing.T) {
 	}
 }
 
+func TestDOTBackEdgeDashed(t *testing.T) {
+	tr := model.NewTree("loop")
+	tr.Graph = true
+	tr.RootID = "n1"
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Decision, Label: "Approved?"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "Revise"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2", Label: "no"},
+		{FromID: "n2", ToID: "n1"},
+	}
+
+	out, err := (&DOTRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, `n1 -> n2 [label="no"];`) {
+		t.Errorf("forward edge should be solid in:\n%s", out)
+	}
+	if !strings.Contains(out, "n2 -> n1 [style=dashed];") {
+		t.Errorf("back edge should be dashed in:\n%s", out)
+	}
+}
+
 func TestDotIDEmpty(t *testing.T) {
 	if got := dotID(""); got != "tree" {
 		t.Errorf("dotID('') = %q, want %q", got, "tree")
 	}
 }
+
+func TestDOTTooltipAttrs(t *testing.T) {
+	tr := model.NewTree("test")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "Deploy",
+		Attrs: map[string]string{"owner": "alice", "sla": "4h", "secret": "x"}}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "Plain"}
+	tr.Edges = []model.Edge{{FromID: "n1", ToID: "n2", Label: "ok", Attrs: map[string]string{"sla": "1d"}}}
+
+	r := &DOTRenderer{TooltipAttrs: []string{"owner", "sla"}}
+	out, err := r.Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, `n1 [label="Deploy", shape=box, tooltip="owner: alice\nsla: 4h"];`) {
+		t.Errorf("missing node tooltip in:\n%s", out)
+	}
+	if !strings.Contains(out, `n2 [label="Plain", shape=box];`) {
+		t.Errorf("node without attributes should have no tooltip in:\n%s", out)
+	}
+	if !strings.Contains(out, `n1 -> n2 [label="ok", tooltip="sla: 1d"];`) {
+		t.Errorf("missing edge tooltip in:\n%s", out)
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("unselected attribute leaked into:\n%s", out)
+	}
+}
@@ -63,3 +63,78 @@ func TestMermaidGolden(t *testing.T) {
 		t.Errorf("Mermaid output mismatch.\nGot:\n%s\nExpected:\n%s", got, string(expected))
 	}
 }
+
+func TestSVGGolden(t *testing.T) {
+	treePath := filepath.Join(testdataDir(), "sample-tree.json")
+	goldenPath := filepath.Join(testdataDir(), "expected-svg.txt")
+
+	tr, err := storage.Load(treePath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	r := &SVGRenderer{}
+	got, err := r.Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	if got != string(expected) {
+		t.Errorf("SVG output mismatch.\nGot:\n%s\nExpected:\n%s", got, string(expected))
+	}
+}
+
+func TestPlantUMLGolden(t *testing.T) {
+	treePath := filepath.Join(testdataDir(), "sample-tree.json")
+	goldenPath := filepath.Join(testdataDir(), "expected-plantuml.txt")
+
+	tr, err := storage.Load(treePath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	r := &PlantUMLRenderer{}
+	got, err := r.Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	if got != string(expected) {
+		t.Errorf("PlantUML output mismatch.\nGot:\n%s\nExpected:\n%s", got, string(expected))
+	}
+}
+
+func TestD2Golden(t *testing.T) {
+	treePath := filepath.Join(testdataDir(), "sample-tree.json")
+	goldenPath := filepath.Join(testdataDir(), "expected-d2.txt")
+
+	tr, err := storage.Load(treePath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	r := &D2Renderer{}
+	got, err := r.Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	if got != string(expected) {
+		t.Errorf("D2 output mismatch.\nGot:\n%s\nExpected:\n%s", got, string(expected))
+	}
+}
@@ -0,0 +1,306 @@
+package render
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// HTMLRenderer renders a tree as a single self-contained HTML page: a
+// collapsible outline, the SVG diagram, and a walk mode that steps through
+// the decisions from the root. Everything is inline, so the file works
+// offline.
+type HTMLRenderer struct {
+	// TooltipAttrs lists metadata keys to show as tooltips in the outline
+	// and the diagram.
+	TooltipAttrs []string
+	Options      Options
+}
+
+// htmlOutlineNode is one entry of the outline. In graph mode a node is
+// expanded once; later occurrences are references to it.
+type htmlOutlineNode struct {
+	ID, Type, Label string
+	EdgeLabel       string
+	Tooltip         string
+	Ref             bool
+	Highlight       bool
+	Children        []*htmlOutlineNode
+}
+
+// htmlWalkNode and htmlWalkEdge are the tree as the walk script sees it.
+type htmlWalkNode struct {
+	Type  string         `json:"type"`
+	Label string         `json:"label"`
+	Edges []htmlWalkEdge `json:"edges"`
+}
+
+type htmlWalkEdge struct {
+	To    string `json:"to"`
+	Label string `json:"label"`
+}
+
+type htmlPage struct {
+	Name    string
+	Outline []*htmlOutlineNode
+	SVG     template.HTML
+	Root    string
+	Nodes   map[string]htmlWalkNode
+}
+
+func (r *HTMLRenderer) Render(t *model.Tree) (string, error) {
+	svg, err := (&SVGRenderer{TooltipAttrs: r.TooltipAttrs, Options: r.Options}).Render(t)
+	if err != nil {
+		return "", err
+	}
+	v, err := r.Options.prepare(t)
+	if err != nil {
+		return "", err
+	}
+	t = v.Tree
+	page := htmlPage{
+		Name:    t.Name,
+		Outline: r.outline(t, v.hl),
+		SVG:     template.HTML(svg), // escaped by SVGRenderer
+		Nodes:   make(map[string]htmlWalkNode, len(t.Nodes)),
+	}
+	if t.GetNode(t.RootID) != nil {
+		page.Root = t.RootID
+	}
+	for id, n := range t.Nodes {
+		wn := htmlWalkNode{Type: n.Type.String(), Label: n.Label, Edges: []htmlWalkEdge{}}
+		for _, e := range t.Children(id) {
+			wn.Edges = append(wn.Edges, htmlWalkEdge{To: e.ToID, Label: e.Label})
+		}
+		page.Nodes[id] = wn
+	}
+	var b strings.Builder
+	if err := htmlTemplate.Execute(&b, page); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// outline builds the outline from the root, then from any nodes the root
+// does not reach, in ID order.
+func (r *HTMLRenderer) outline(t *model.Tree, hl highlight) []*htmlOutlineNode {
+	seen := make(map[string]bool)
+	var build func(id, edgeLabel string) *htmlOutlineNode
+	build = func(id, edgeLabel string) *htmlOutlineNode {
+		n := t.Nodes[id]
+		o := &htmlOutlineNode{
+			ID: id, Type: n.Type.String(), Label: n.Label, EdgeLabel: edgeLabel,
+			Tooltip:   tooltipText(n.Attrs, r.TooltipAttrs),
+			Highlight: hl.node(id),
+		}
+		if seen[id] {
+			o.Ref = true
+			return o
+		}
+		seen[id] = true
+		for _, e := range t.Children(id) {
+			o.Children = append(o.Children, build(e.ToID, e.Label))
+		}
+		return o
+	}
+	var roots []*htmlOutlineNode
+	if t.GetNode(t.RootID) != nil {
+		roots = append(roots, build(t.RootID, ""))
+	}
+	for _, id := range t.NodeIDs() {
+		if !seen[id] && len(t.Parents(id)) == 0 {
+			roots = append(roots, build(id, ""))
+		}
+	}
+	for _, id := range t.NodeIDs() {
+		if !seen[id] {
+			roots = append(roots, build(id, ""))
+		}
+	}
+	return roots
+}
+
+var htmlTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<style>
+  body { font-family: sans-serif; margin: 0 auto; max-width: 1100px; padding: 1em 2em; color: #111; }
+  h1 { font-size: 1.6em; }
+  h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #ddd; }
+  button { font: inherit; padding: .3em .8em; margin: .2em .3em .2em 0; cursor: pointer; }
+  #walk-node { font-size: 1.3em; margin: .5em 0; }
+  #walk-options button { display: block; }
+  #walk-path { color: #555; }
+  .outline, .outline ul { list-style: none; padding-left: 1.4em; }
+  .outline { padding-left: 0; }
+  .outline summary { cursor: pointer; }
+  .outline .leaf { padding-left: 1.1em; }
+  .edge-label { color: #555; }
+  .edge-label::before { content: "["; } .edge-label::after { content: "] "; }
+  .type { font-size: .75em; color: #777; margin-left: .4em; }
+  .ref { color: #555; }
+  .outline .highlight > .leaf, .outline .highlight > details > summary { font-weight: bold; }
+  .diagram { overflow: auto; border: 1px solid #ddd; }
+  .node.current > ellipse, .node.current > rect, .node.current > polygon { stroke: #d33; stroke-width: 3; }
+  .node.visited > ellipse, .node.visited > rect, .node.visited > polygon { stroke-width: 2; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+
+<section id="walk">
+<h2>Walk</h2>
+<div id="walk-node"></div>
+<div id="walk-options"></div>
+<p>
+  <button type="button" id="walk-back">Back</button>
+  <button type="button" id="walk-restart">Start over</button>
+</p>
+<p id="walk-path"></p>
+</section>
+
+<section>
+<h2>Outline</h2>
+<p>
+  <button type="button" id="expand-all">Expand all</button>
+  <button type="button" id="collapse-all">Collapse all</button>
+</p>
+<ul class="outline">
+{{range .Outline}}{{template "item" .}}{{end}}
+</ul>
+</section>
+
+<section>
+<h2>Diagram</h2>
+<div class="diagram">
+{{.SVG}}
+</div>
+</section>
+
+<script>
+(function () {
+  var root = {{.Root}};
+  var nodes = {{.Nodes}};
+  var path = [];
+
+  // text writes a node the way the shell's walk and preview do.
+  function text(n) {
+    switch (n.type) {
+    case "decision": return "<" + n.label + ">";
+    case "startend": return "([" + n.label + "])";
+    case "io": return "//" + n.label + "//";
+    }
+    return "[" + n.label + "]";
+  }
+
+  function stepText(step) {
+    return (step.edge ? "[" + step.edge + "] " : "") + text(nodes[step.id]);
+  }
+
+  // finished mirrors the shell's walk: stop at a leaf, or at a start/end
+  // node other than the first.
+  function finished(n) {
+    return n.edges.length === 0 || (n.type === "startend" && path.length > 1);
+  }
+
+  // loopsWithoutChoice reports whether following an edge automatically
+  // would revisit a node seen since the last choice.
+  function loopsWithoutChoice(id) {
+    for (var i = path.length - 1; i >= 0; i--) {
+      if (path[i].id === id) return true;
+      if (path[i].chosen) return false;
+    }
+    return false;
+  }
+
+  function advance() {
+    for (;;) {
+      var n = nodes[path[path.length - 1].id];
+      if (finished(n) || n.edges.length !== 1 || n.type === "decision") return "";
+      var e = n.edges[0];
+      if (loopsWithoutChoice(e.to)) return "Loop with no decision to make.";
+      path.push({id: e.to, edge: e.label, chosen: false});
+    }
+  }
+
+  function highlight() {
+    var els = document.querySelectorAll(".diagram .node");
+    for (var i = 0; i < els.length; i++) els[i].classList.remove("current", "visited");
+    path.forEach(function (step, i) {
+      var el = document.getElementById("node-" + step.id);
+      if (el) el.classList.add(i === path.length - 1 ? "current" : "visited");
+    });
+  }
+
+  function show(note) {
+    var nodeEl = document.getElementById("walk-node");
+    var opts = document.getElementById("walk-options");
+    opts.innerHTML = "";
+    if (!root) {
+      nodeEl.textContent = "This tree has no root.";
+      return;
+    }
+    var n = nodes[path[path.length - 1].id];
+    nodeEl.textContent = stepText(path[path.length - 1]);
+    if (note) {
+      opts.textContent = note;
+    } else if (finished(n)) {
+      opts.textContent = "Reached the end.";
+    } else {
+      n.edges.forEach(function (e) {
+        var b = document.createElement("button");
+        b.type = "button";
+        b.textContent = e.label || "→ " + text(nodes[e.to]);
+        b.onclick = function () {
+          path.push({id: e.to, edge: e.label, chosen: true});
+          show(advance());
+        };
+        opts.appendChild(b);
+      });
+    }
+    document.getElementById("walk-path").textContent = "Path: " + path.map(stepText).join(" → ");
+    highlight();
+  }
+
+  function restart() {
+    path = root ? [{id: root, edge: "", chosen: false}] : [];
+    show(root ? advance() : "");
+  }
+
+  document.getElementById("walk-back").onclick = function () {
+    for (var i = path.length - 1; i > 0; i--) {
+      if (path[i].chosen) {
+        path = path.slice(0, i);
+        break;
+      }
+    }
+    if (i === 0) path = path.slice(0, 1);
+    show("");
+  };
+  document.getElementById("walk-restart").onclick = restart;
+
+  function setOpen(open) {
+    var els = document.querySelectorAll(".outline details");
+    for (var i = 0; i < els.length; i++) els[i].open = open;
+  }
+  document.getElementById("expand-all").onclick = function () { setOpen(true); };
+  document.getElementById("collapse-all").onclick = function () { setOpen(false); };
+
+  restart();
+})();
+</script>
+</body>
+</html>
+{{- define "item"}}<li{{if not .Ref}} id="outline-{{.ID}}"{{end}}{{if .Highlight}} class="highlight"{{end}}{{if .Tooltip}} title="{{.Tooltip}}"{{end}}>
+{{- if .Ref}}<span class="leaf ref">{{template "label" .}} <a href="#outline-{{.ID}}">&#8618; {{.ID}}</a></span>
+{{- else if .Children}}<details open><summary>{{template "label" .}}</summary><ul>
+{{range .Children}}{{template "item" .}}{{end}}</ul></details>
+{{- else}}<span class="leaf">{{template "label" .}}</span>
+{{- end}}</li>
+{{end}}
+{{- define "label"}}{{if .EdgeLabel}}<span class="edge-label">{{.EdgeLabel}}</span>{{end}}{{.Label}}<span class="type">{{.Type}}</span>{{end}}
+`))
@@ -0,0 +1,125 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func TestHTMLRenderer(t *testing.T) {
+	tr := model.NewTree("auth-flow")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.StartEnd, Label: "Start"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Authenticated?",
+		Attrs: map[string]string{"owner": "alice"}}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Grant access"}
+	tr.Nodes["n4"] = &model.Node{ID: "n4", Type: model.IO, Label: "Show login"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n4", Label: "no"},
+	}
+	tr.RootID = "n1"
+
+	out, err := (&HTMLRenderer{TooltipAttrs: []string{"owner"}}).Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<title>auth-flow</title>",
+		// Outline
+		`<li id="outline-n2" title="owner: alice"><details open><summary>Authenticated?`,
+		`<span class="edge-label">yes</span>Grant access`,
+		// Diagram
+		`<svg xmlns="http://www.w3.org/2000/svg"`,
+		`<g class="node decision" id="node-n2">`,
+		// Walk
+		`var root = "n1";`,
+		`"n2":{"type":"decision","label":"Authenticated?","edges":[{"to":"n3","label":"yes"},{"to":"n4","label":"no"}]}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if !strings.HasSuffix(out, "</html>\n") {
+		t.Errorf("output does not end with </html>: %q", out[len(out)-40:])
+	}
+}
+
+func TestHTMLIsSelfContained(t *testing.T) {
+	tr := model.NewTree("t")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "A"}
+	tr.RootID = "n1"
+	out, err := (&HTMLRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, banned := range []string{"<script src", "<link", "@import", "https://", "url(http"} {
+		if strings.Contains(out, banned) {
+			t.Errorf("output references an external resource: %q", banned)
+		}
+	}
+}
+
+func TestHTMLEscaping(t *testing.T) {
+	tr := model.NewTree(`<b>"x"</b>`)
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "</script><script>alert(1)</script>"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "B & C"}
+	tr.Edges = []model.Edge{{FromID: "n1", ToID: "n2", Label: "<go>"}}
+	tr.RootID = "n1"
+	out, err := (&HTMLRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out, "<script>") != 1 || strings.Count(out, "</script>") != 1 {
+		t.Errorf("label broke out of the script or markup:\n%s", out)
+	}
+	for _, want := range []string{
+		"<title>&lt;b&gt;&#34;x&#34;&lt;/b&gt;</title>",
+		`<span class="edge-label">&lt;go&gt;</span>B &amp; C`,
+		`</script>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
+
+func TestHTMLOutlineGraphMode(t *testing.T) {
+	tr := model.NewTree("retry")
+	tr.Graph = true
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "Try"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Worked?"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Unused"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n1", Label: "no"},
+	}
+	tr.RootID = "n1"
+	out, err := (&HTMLRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out, `id="outline-n1"`); n != 1 {
+		t.Errorf("n1 has %d outline entries, want 1", n)
+	}
+	for _, want := range []string{
+		`<li><span class="leaf ref"><span class="edge-label">no</span>Try<span class="type">action</span> <a href="#outline-n1">`,
+		`<li id="outline-n3"><span class="leaf">Unused`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
+
+func TestHTMLNoRoot(t *testing.T) {
+	out, err := (&HTMLRenderer{}).Render(model.NewTree("empty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `var root = "";`) {
+		t.Error("empty tree should have an empty root")
+	}
+}
@@ -0,0 +1,243 @@
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// Layout metrics, in SVG user units (pixels).
+const (
+	fontSize   = 12.0
+	charWidth  = 7.0  // average glyph width at fontSize
+	lineHeight = 16.0 // height of one line of label text
+	hGap       = 24.0 // between neighboring subtrees
+	vGap       = 48.0 // between layers
+	margin     = 20.0
+	loopGap    = 10.0 // between loop-back edges in the right gutter
+	skew       = 12.0 // horizontal slant of a parallelogram
+)
+
+// nodeBox is a node's place in the layout: its center x, top y and size.
+type nodeBox struct {
+	x, y, w, h float64
+	layer      int
+}
+
+func (b *nodeBox) bottom() float64  { return b.y + b.h }
+func (b *nodeBox) right() float64   { return b.x + b.w/2 }
+func (b *nodeBox) middleY() float64 { return b.y + b.h/2 }
+
+// treeLayout places the nodes of a tree in layers. Boxes are always laid
+// out top down; point and box turn them to the drawing's direction.
+type treeLayout struct {
+	dir   string // TB, LR, BT or RL
+	boxes map[string]*nodeBox
+	// back marks the edges that loop back to a node on the path from the
+	// root; they are routed through a gutter on the right.
+	back   map[model.EdgeKey]bool
+	gutter float64 // x of the first loop-back lane
+	width  float64 // across the layers, in the top-down layout
+	height float64 // along the layers, in the top-down layout
+}
+
+func (l *treeLayout) horizontal() bool { return l.dir == "LR" || l.dir == "RL" }
+
+// point maps a point of the top-down layout to the drawing: LR and RL swap
+// the axes, and BT and RL mirror the layers.
+func (l *treeLayout) point(x, y float64) (float64, float64) {
+	switch l.dir {
+	case "LR":
+		return y, x
+	case "RL":
+		return l.height - y, x
+	case "BT":
+		return x, l.height - y
+	}
+	return x, y
+}
+
+// size returns the drawing's width and height.
+func (l *treeLayout) size() (float64, float64) {
+	if l.horizontal() {
+		return l.height, l.width
+	}
+	return l.width, l.height
+}
+
+// box returns a node's box in the drawing.
+func (l *treeLayout) box(id string) *nodeBox {
+	b := l.boxes[id]
+	x, y := l.point(b.x, b.middleY())
+	w, h := b.w, b.h
+	if l.horizontal() {
+		w, h = h, w
+	}
+	return &nodeBox{x: x, y: y - h/2, w: w, h: h, layer: b.layer}
+}
+
+// layoutTree computes a layered layout. A depth-first walk from the root
+// (then from unreached nodes) gives a spanning tree and classifies the
+// loop-back edges. Every other edge points down: a node's layer is one
+// more than its deepest parent's. Each spanning subtree gets a band as
+// wide as its children need, with the node centered over them, so nodes
+// in one layer never overlap. Other directions lay the tree out top down
+// with each box turned, then turn the result.
+func layoutTree(t *model.Tree, dir string) *treeLayout {
+	l := &treeLayout{dir: dir, boxes: make(map[string]*nodeBox), back: make(map[model.EdgeKey]bool)}
+	for id, n := range t.Nodes {
+		w, h := nodeSize(n)
+		if l.horizontal() {
+			w, h = h, w
+		}
+		l.boxes[id] = &nodeBox{w: w, h: h}
+	}
+
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int)
+	kids := make(map[string][]string)
+	var postorder []string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = onPath
+		for _, e := range t.Children(id) {
+			switch state[e.ToID] {
+			case onPath:
+				l.back[e.Key()] = true
+			case unvisited:
+				kids[id] = append(kids[id], e.ToID)
+				visit(e.ToID)
+			}
+		}
+		state[id] = done
+		postorder = append(postorder, id)
+	}
+	var roots []string
+	start := func(id string) {
+		if state[id] == unvisited {
+			roots = append(roots, id)
+			visit(id)
+		}
+	}
+	if _, ok := t.Nodes[t.RootID]; ok {
+		start(t.RootID)
+	}
+	for _, id := range t.NodeIDs() {
+		if len(t.Parents(id)) == 0 {
+			start(id)
+		}
+	}
+	for _, id := range t.NodeIDs() {
+		start(id)
+	}
+
+	// Reverse postorder is a topological order of the remaining edges.
+	for i := len(postorder) - 1; i >= 0; i-- {
+		from := postorder[i]
+		for _, e := range t.Children(from) {
+			if !l.back[e.Key()] && l.boxes[e.ToID].layer <= l.boxes[from].layer {
+				l.boxes[e.ToID].layer = l.boxes[from].layer + 1
+			}
+		}
+	}
+
+	span := make(map[string]float64)
+	for _, id := range postorder {
+		kidsWidth := -hGap
+		for _, k := range kids[id] {
+			kidsWidth += span[k] + hGap
+		}
+		span[id] = max(l.boxes[id].w, kidsWidth)
+	}
+	var place func(id string, left float64)
+	place = func(id string, left float64) {
+		b := l.boxes[id]
+		if len(kids[id]) == 0 {
+			b.x = left + span[id]/2
+			return
+		}
+		kidsWidth := -hGap
+		for _, k := range kids[id] {
+			kidsWidth += span[k] + hGap
+		}
+		x := left + (span[id]-kidsWidth)/2
+		for _, k := range kids[id] {
+			place(k, x)
+			x += span[k] + hGap
+		}
+		first, last := l.boxes[kids[id][0]], l.boxes[kids[id][len(kids[id])-1]]
+		b.x = min(max((first.x+last.x)/2, left+b.w/2), left+span[id]-b.w/2)
+	}
+	x := margin
+	for _, r := range roots {
+		place(r, x)
+		x += span[r] + hGap
+	}
+	l.width = max(x-hGap, margin) + margin
+
+	var layerHeights []float64
+	for _, b := range l.boxes {
+		for len(layerHeights) <= b.layer {
+			layerHeights = append(layerHeights, 0)
+		}
+		layerHeights[b.layer] = max(layerHeights[b.layer], b.h)
+	}
+	tops := make([]float64, len(layerHeights))
+	y := margin
+	for i, h := range layerHeights {
+		tops[i] = y
+		y += h + vGap
+	}
+	for _, b := range l.boxes {
+		b.y = tops[b.layer] + (layerHeights[b.layer]-b.h)/2
+	}
+	l.height = max(y-vGap, margin) + margin
+
+	if len(l.back) > 0 {
+		l.gutter = l.width
+		widest := 0.0
+		for _, e := range t.Edges {
+			if l.back[e.Key()] {
+				widest = max(widest, labelWidth(e.Label))
+			}
+		}
+		l.width += float64(len(l.back))*loopGap + widest/2 + margin
+	}
+	return l
+}
+
+// nodeSize returns the width and height of a node's shape, leaving room
+// for its label inside the shape.
+func nodeSize(n *model.Node) (float64, float64) {
+	lines := strings.Split(n.Label, "\n")
+	textW := 0.0
+	for _, line := range lines {
+		textW = max(textW, float64(utf8.RuneCountInString(line))*charWidth)
+	}
+	textH := float64(len(lines)) * lineHeight
+	var w, h float64
+	switch n.Type {
+	case model.Decision:
+		w, h = textW*1.4+36, textH*2+16
+	case model.StartEnd:
+		w, h = textW*1.2+30, textH+18
+	case model.IO:
+		w, h = textW+24+2*skew, textH+16
+	default:
+		w, h = textW+24, textH+16
+	}
+	return max(w, 48), h
+}
+
+// labelWidth returns the width of the background behind an edge label.
+func labelWidth(label string) float64 {
+	if label == "" {
+		return 0
+	}
+	return float64(utf8.RuneCountInString(label))*charWidth + 8
+}
@@ -7,12 +7,23 @@ import (
 	"github.com/jllovet/decision-tree-cli/internal/model"
 )
 
-// MermaidRenderer renders a tree as a Mermaid flowchart.
-type MermaidRenderer struct{}
+// MermaidRenderer renders a tree as a Mermaid flowchart. Edges that loop
+// back to an earlier node are drawn as dotted links.
+type MermaidRenderer struct {
+	// TooltipAttrs lists metadata keys to pass through as node tooltips
+	// (Mermaid has no edge tooltips).
+	TooltipAttrs []string
+	Options      Options
+}
 
 func (r *MermaidRenderer) Render(t *model.Tree) (string, error) {
+	v, err := r.Options.prepare(t)
+	if err != nil {
+		return "", err
+	}
+	t = v.Tree
 	var b strings.Builder
-	b.WriteString("flowchart TB\n")
+	b.WriteString("flowchart " + v.dir + "\n")
 
 	for _, id := range t.NodeIDs() {
 		n := t.Nodes[id]
@@ -22,14 +33,82 @@ func (r *MermaidRenderer) Render(t *model.Tree) (string, error) {
 	if len(t.Edges) > 0 {
 		b.WriteString("\n")
 	}
+	back := t.BackEdges()
 	for _, e := range t.Edges {
-		if e.Label != "" {
+		switch {
+		case back[e.Key()] && e.Label != "":
+			b.WriteString(fmt.Sprintf("  %s -. %s .-> %s\n", e.FromID, mermaidEscape(e.Label), e.ToID))
+		case back[e.Key()]:
+			b.WriteString(fmt.Sprintf("  %s -.-> %s\n", e.FromID, e.ToID))
+		case e.Label != "":
 			b.WriteString(fmt.Sprintf("  %s -- %s --> %s\n", e.FromID, mermaidEscape(e.Label), e.ToID))
-		} else {
+		default:
 			b.WriteString(fmt.Sprintf("  %s --> %s\n", e.FromID, e.ToID))
 		}
 	}
 
+	var tips []string
+	for _, id := range t.NodeIDs() {
+		if text := tooltipText(t.Nodes[id].Attrs, r.TooltipAttrs); text != "" {
+			text = strings.ReplaceAll(text, "\n", "; ")
+			tips = append(tips, fmt.Sprintf("  click %s \"#\" \"%s\"\n", id, mermaidEscape(text)))
+		}
+	}
+	if len(tips) > 0 {
+		b.WriteString("\n")
+		b.WriteString(strings.Join(tips, ""))
+	}
+
+	// Theme colors go on one class per node type, highlighting on the
+	// nodes and links themselves (linkStyle counts links from 0).
+	var styles []string
+	for _, typ := range []model.NodeType{model.Decision, model.Action, model.StartEnd, model.IO} {
+		fill := r.Options.fill(typ, "")
+		if fill == "" {
+			continue
+		}
+		var ids []string
+		for _, id := range t.NodeIDs() {
+			if t.Nodes[id].Type == typ {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > 0 {
+			styles = append(styles, fmt.Sprintf("  classDef %s fill:%s\n  class %s %s\n", typ, fill, strings.Join(ids, ","), typ))
+		}
+	}
+	for _, id := range t.NodeIDs() {
+		if c := r.Options.NodeColors[id]; c != "" {
+			styles = append(styles, fmt.Sprintf("  style %s stroke:%s,stroke-width:3px\n", id, c))
+		} else if v.hl.node(id) {
+			styles = append(styles, fmt.Sprintf("  style %s stroke:%s,stroke-width:3px\n", id, highlightColor))
+		}
+	}
+	// Links with the same color share one linkStyle line, in the order
+	// the colors first appear.
+	var colors []string
+	links := make(map[string][]string)
+	for i, e := range t.Edges {
+		c := r.Options.EdgeColors[e.Key()]
+		if c == "" && v.hl.edge(e) {
+			c = highlightColor
+		}
+		if c == "" {
+			continue
+		}
+		if links[c] == nil {
+			colors = append(colors, c)
+		}
+		links[c] = append(links[c], fmt.Sprint(i))
+	}
+	for _, c := range colors {
+		styles = append(styles, fmt.Sprintf("  linkStyle %s stroke:%s,stroke-width:3px\n", strings.Join(links[c], ","), c))
+	}
+	if len(styles) > 0 {
+		b.WriteString("\n")
+		b.WriteString(strings.Join(styles, ""))
+	}
+
 	return b.String(), nil
 }
 
@@ -62,3 +62,45 @@ func TestMermaidEmptyTree(t *testing.T) {
 		t.Error("should start with flowchart TB")
 	}
 }
+
+func TestMermaidBackEdgeDotted(t *testing.T) {
+	tr := model.NewTree("loop")
+	tr.Graph = true
+	tr.RootID = "n1"
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Decision, Label: "Approved?"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "Revise"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2", Label: "no"},
+		{FromID: "n2", ToID: "n1", Label: "resubmit"},
+	}
+
+	out, err := (&MermaidRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, "n1 -- no --> n2") {
+		t.Errorf("forward edge should be solid in:\n%s", out)
+	}
+	if !strings.Contains(out, "n2 -. resubmit .-> n1") {
+		t.Errorf("back edge should be dotted in:\n%s", out)
+	}
+}
+
+func TestMermaidTooltipAttrs(t *testing.T) {
+	tr := model.NewTree("test")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "Deploy",
+		Attrs: map[string]string{"owner": "alice", "ticket": `OPS-1 "urgent"`}}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "Plain"}
+
+	r := &MermaidRenderer{TooltipAttrs: []string{"owner", "ticket"}}
+	out, err := r.Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(out, `click n1 "#" "owner: alice; ticket: OPS-1 #quot;urgent#quot;"`) {
+		t.Errorf("missing tooltip in:\n%s", out)
+	}
+	if strings.Contains(out, "click n2") {
+		t.Errorf("node without attributes should have no tooltip in:\n%s", out)
+	}
+}
@@ -0,0 +1,178 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// Options control what a renderer draws and how. The zero value draws the
+// whole tree top to bottom in the renderer's default colors.
+type Options struct {
+	// Direction is the flow direction: TB (the default), LR, BT or RL.
+	Direction string
+	// Theme maps node types to fill colors, overriding the defaults.
+	// Colors are hex ("#fff4d6") or names ("gold").
+	Theme map[model.NodeType]string
+	// Subtree, if set, draws only the nodes reachable from this node.
+	Subtree string
+	// Depth, if positive, draws only the nodes at most this many edges
+	// below the root (or Subtree).
+	Depth int
+	// Highlight is a path of node IDs, each connected to the next, drawn
+	// emphasized along with the edges between them.
+	Highlight []string
+	// NodeColors and EdgeColors outline single nodes and edges in a color
+	// of their own, taking precedence over Highlight. Only the DOT and
+	// Mermaid renderers draw them; dt diff uses them to mark changes.
+	NodeColors map[string]string
+	EdgeColors map[model.EdgeKey]string
+}
+
+// highlightColor is the stroke color of highlighted nodes and edges.
+const highlightColor = "#dd3333"
+
+// ParseDirection normalizes a flow direction to TB, LR, BT or RL. It
+// also accepts TD and the D2 names down, right, up and left.
+func ParseDirection(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", "tb", "td", "down":
+		return "TB", nil
+	case "lr", "right":
+		return "LR", nil
+	case "bt", "up":
+		return "BT", nil
+	case "rl", "left":
+		return "RL", nil
+	}
+	return "", fmt.Errorf("unknown direction %q (use TB, LR, BT or RL)", s)
+}
+
+var colorPattern = regexp.MustCompile(`^#?[0-9A-Za-z]+$`)
+
+// ParseTheme parses a theme written as "type=color,...", for example
+// "decision=#fff4d6,io=plum".
+func ParseTheme(s string) (map[model.NodeType]string, error) {
+	theme := make(map[model.NodeType]string)
+	for _, part := range strings.Split(s, ",") {
+		name, color, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("theme entry %q is not type=color", part)
+		}
+		typ, err := model.ParseNodeType(name)
+		if err != nil {
+			return nil, err
+		}
+		if !colorPattern.MatchString(color) {
+			return nil, fmt.Errorf("invalid color %q for %s", color, name)
+		}
+		theme[typ] = color
+	}
+	return theme, nil
+}
+
+// ParseDepth parses a depth limit; 0 means no limit.
+func ParseDepth(s string) (int, error) {
+	d, err := strconv.Atoi(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid depth %q (use a number of levels, 0 for all)", s)
+	}
+	return d, nil
+}
+
+// highlight is the set of nodes and edges on a highlighted path.
+type highlight struct {
+	nodes map[string]bool
+	edges map[model.EdgeKey]bool
+}
+
+func (h highlight) node(id string) bool    { return h.nodes[id] }
+func (h highlight) edge(e model.Edge) bool { return h.edges[e.Key()] }
+
+// view is the part of a tree a renderer draws, after applying Options.
+type view struct {
+	*model.Tree
+	dir string
+	hl  highlight
+}
+
+// prepare validates the options and cuts the tree down to the subtree and
+// depth they select. Edges are kept when both ends are drawn.
+func (o Options) prepare(t *model.Tree) (*view, error) {
+	dir, err := ParseDirection(o.Direction)
+	if err != nil {
+		return nil, err
+	}
+	if o.Depth < 0 {
+		return nil, fmt.Errorf("invalid depth %d", o.Depth)
+	}
+	v := &view{Tree: t, dir: dir, hl: highlight{nodes: make(map[string]bool), edges: make(map[model.EdgeKey]bool)}}
+
+	for i, id := range o.Highlight {
+		if t.GetNode(id) == nil {
+			return nil, fmt.Errorf("highlight: node %s not found", id)
+		}
+		v.hl.nodes[id] = true
+		if i > 0 {
+			e := t.GetEdge(o.Highlight[i-1], id)
+			if e == nil {
+				return nil, fmt.Errorf("highlight: no edge from %s to %s", o.Highlight[i-1], id)
+			}
+			v.hl.edges[e.Key()] = true
+		}
+	}
+
+	if o.Subtree == "" && o.Depth == 0 {
+		return v, nil
+	}
+	start := o.Subtree
+	if start == "" {
+		start = t.RootID
+	}
+	if t.GetNode(start) == nil {
+		if o.Subtree == "" {
+			return nil, fmt.Errorf("a depth limit needs a root node")
+		}
+		return nil, fmt.Errorf("subtree: node %s not found", start)
+	}
+
+	// Breadth first, so each node is kept at its shortest distance.
+	dist := map[string]int{start: 0}
+	queue := []string{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if o.Depth > 0 && dist[id] >= o.Depth {
+			continue
+		}
+		for _, e := range t.Children(id) {
+			if _, ok := dist[e.ToID]; !ok {
+				dist[e.ToID] = dist[id] + 1
+				queue = append(queue, e.ToID)
+			}
+		}
+	}
+	sub := &model.Tree{Name: t.Name, RootID: start, Nodes: make(map[string]*model.Node, len(dist)), Counter: t.Counter, Graph: t.Graph}
+	for id := range dist {
+		sub.Nodes[id] = t.Nodes[id]
+	}
+	for _, e := range t.Edges {
+		if sub.Nodes[e.FromID] != nil && sub.Nodes[e.ToID] != nil {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	v.Tree = sub
+	return v, nil
+}
+
+// fill returns the fill color for a node type: the theme's, if it sets
+// one, or else def.
+func (o Options) fill(typ model.NodeType, def string) string {
+	if c, ok := o.Theme[typ]; ok {
+		return c
+	}
+	return def
+}
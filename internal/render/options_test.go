@@ -0,0 +1,256 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// optionsTree is Start -> Authenticated? -yes-> Grant access -> End, with
+// a "no" branch to Show login.
+func optionsTree() *model.Tree {
+	tr := model.NewTree("auth-flow")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.StartEnd, Label: "Start"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Authenticated?"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Grant access"}
+	tr.Nodes["n4"] = &model.Node{ID: "n4", Type: model.IO, Label: "Show login"}
+	tr.Nodes["n5"] = &model.Node{ID: "n5", Type: model.StartEnd, Label: "End"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n4", Label: "no"},
+		{FromID: "n3", ToID: "n5"},
+	}
+	tr.RootID = "n1"
+	return tr
+}
+
+func TestParseDirection(t *testing.T) {
+	tests := map[string]string{
+		"": "TB", "tb": "TB", "TD": "TB", "down": "TB",
+		"LR": "LR", "right": "LR", "bt": "BT", "up": "BT", "RL": "RL", "left": "RL",
+	}
+	for in, want := range tests {
+		if got, err := ParseDirection(in); err != nil || got != want {
+			t.Errorf("ParseDirection(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseDirection("sideways"); err == nil {
+		t.Error("expected error for sideways")
+	}
+}
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme("decision=#fff4d6, io=plum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme[model.Decision] != "#fff4d6" || theme[model.IO] != "plum" || len(theme) != 2 {
+		t.Errorf("theme = %v", theme)
+	}
+	for _, bad := range []string{"decision", "shape=red", `action=red"`, "io="} {
+		if _, err := ParseTheme(bad); err == nil {
+			t.Errorf("ParseTheme(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseDepth(t *testing.T) {
+	if d, err := ParseDepth("2"); err != nil || d != 2 {
+		t.Errorf("ParseDepth(2) = %d, %v", d, err)
+	}
+	for _, bad := range []string{"-1", "two", ""} {
+		if _, err := ParseDepth(bad); err == nil {
+			t.Errorf("ParseDepth(%q): expected error", bad)
+		}
+	}
+}
+
+func TestPrepareSubtreeAndDepth(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want []string
+	}{
+		{Options{}, []string{"n1", "n2", "n3", "n4", "n5"}},
+		{Options{Subtree: "n2"}, []string{"n2", "n3", "n4", "n5"}},
+		{Options{Depth: 1}, []string{"n1", "n2"}},
+		{Options{Subtree: "n2", Depth: 1}, []string{"n2", "n3", "n4"}},
+	}
+	for _, tt := range tests {
+		v, err := tt.opts.prepare(optionsTree())
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.opts, err)
+		}
+		if got := v.NodeIDs(); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%+v: nodes = %v, want %v", tt.opts, got, tt.want)
+		}
+		for _, e := range v.Edges {
+			if v.Nodes[e.FromID] == nil || v.Nodes[e.ToID] == nil {
+				t.Errorf("%+v: dangling edge %s -> %s", tt.opts, e.FromID, e.ToID)
+			}
+		}
+		if tt.opts.Subtree != "" && v.RootID != tt.opts.Subtree {
+			t.Errorf("%+v: root = %s", tt.opts, v.RootID)
+		}
+	}
+}
+
+func TestPrepareErrors(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Direction: "diagonal"}, `unknown direction "diagonal"`},
+		{Options{Subtree: "n9"}, "subtree: node n9 not found"},
+		{Options{Depth: -1}, "invalid depth"},
+		{Options{Highlight: []string{"n1", "n9"}}, "highlight: node n9 not found"},
+		{Options{Highlight: []string{"n1", "n3"}}, "highlight: no edge from n1 to n3"},
+	}
+	for _, tt := range tests {
+		_, err := tt.opts.prepare(optionsTree())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.opts, err, tt.want)
+		}
+	}
+}
+
+func TestRenderersApplyOptions(t *testing.T) {
+	opts := Options{
+		Direction: "LR",
+		Theme:     map[model.NodeType]string{model.Decision: "gold"},
+		Highlight: []string{"n1", "n2", "n3"},
+	}
+	tests := []struct {
+		name string
+		r    Renderer
+		want []string
+	}{
+		{"dot", &DOTRenderer{Options: opts}, []string{
+			"rankdir=LR;",
+			`n2 [label="Authenticated?", shape=diamond, style=filled, fillcolor="gold", color="#dd3333", penwidth=2];`,
+			`n2 -> n3 [label="yes", color="#dd3333", penwidth=2];`,
+			`n2 -> n4 [label="no"];`,
+		}},
+		{"mermaid", &MermaidRenderer{Options: opts}, []string{
+			"flowchart LR\n",
+			"classDef decision fill:gold\n  class n2 decision\n",
+			"style n3 stroke:#dd3333,stroke-width:3px\n",
+			"linkStyle 0,1 stroke:#dd3333,stroke-width:3px\n",
+		}},
+		{"d2", &D2Renderer{Options: opts}, []string{
+			"direction: right\n",
+			"shape: diamond\n  style.fill: \"gold\"\n  style.stroke: \"#dd3333\"\n",
+			"n2 -> n3: \"yes\" {\n  style.stroke: \"#dd3333\"\n",
+		}},
+		{"svg", &SVGRenderer{Options: opts}, []string{
+			`<g class="node decision highlight" id="node-n2">`,
+			`fill="gold" stroke="#dd3333" stroke-width="3"/>`,
+			`<g class="edge highlight" data-from="n2" data-to="n3">`,
+			`marker-end="url(#arrow-highlight)"`,
+		}},
+		{"html", &HTMLRenderer{Options: opts}, []string{
+			`<li id="outline-n2" class="highlight">`,
+			`<g class="node decision highlight" id="node-n2">`,
+		}},
+	}
+	for _, tt := range tests {
+		out, err := tt.r.Render(optionsTree())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: missing %q in:\n%s", tt.name, want, out)
+			}
+		}
+	}
+}
+
+func TestNodeAndEdgeColors(t *testing.T) {
+	opts := Options{
+		Highlight:  []string{"n2", "n3"},
+		NodeColors: map[string]string{"n3": "green", "n4": "red"},
+		EdgeColors: map[model.EdgeKey]string{{FromID: "n2", ToID: "n4"}: "red", {FromID: "n3", ToID: "n5"}: "red"},
+	}
+	dot, _ := (&DOTRenderer{Options: opts}).Render(optionsTree())
+	for _, want := range []string{
+		`n2 [label="Authenticated?", shape=diamond, color="#dd3333", penwidth=2];`,
+		`n3 [label="Grant access", shape=box, color="green", penwidth=2];`,
+		`n2 -> n4 [label="no", color="red", fontcolor="red", penwidth=2];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot: missing %q in:\n%s", want, dot)
+		}
+	}
+	mermaid, _ := (&MermaidRenderer{Options: opts}).Render(optionsTree())
+	for _, want := range []string{
+		"style n3 stroke:green,stroke-width:3px\n",
+		"linkStyle 1 stroke:#dd3333,stroke-width:3px\n  linkStyle 2,3 stroke:red,stroke-width:3px\n",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("mermaid: missing %q in:\n%s", want, mermaid)
+		}
+	}
+}
+
+func TestPlantUMLOptions(t *testing.T) {
+	opts := Options{
+		Theme:     map[model.NodeType]string{model.Action: "LightBlue"},
+		Highlight: []string{"n2", "n4"},
+		Subtree:   "n2",
+	}
+	out, err := (&PlantUMLRenderer{Options: opts}).Render(optionsTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"#ffd6d6:if (Authenticated?) then (yes)\n",
+		"  #LightBlue:Grant access;\n",
+		"else (no)\n  -[#dd3333,bold]->\n  #ffd6d6:Show login; <<input>>\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "start\n") {
+		t.Errorf("subtree output starts above n2:\n%s", out)
+	}
+
+	if _, err := (&PlantUMLRenderer{Options: Options{Direction: "LR"}}).Render(optionsTree()); err == nil {
+		t.Error("expected error for LR")
+	}
+}
+
+func TestSVGDirections(t *testing.T) {
+	tests := []struct {
+		dir string
+		// ok reports whether the child box c sits on the right side of
+		// the parent box p.
+		ok func(p, c *nodeBox) bool
+	}{
+		{"TB", func(p, c *nodeBox) bool { return c.y >= p.bottom() }},
+		{"BT", func(p, c *nodeBox) bool { return c.bottom() <= p.y }},
+		{"LR", func(p, c *nodeBox) bool { return c.x-c.w/2 >= p.right() }},
+		{"RL", func(p, c *nodeBox) bool { return c.right() <= p.x-p.w/2 }},
+	}
+	tr := optionsTree()
+	for _, tt := range tests {
+		l := layoutTree(tr, tt.dir)
+		w, h := l.size()
+		for _, e := range tr.Edges {
+			p, c := l.box(e.FromID), l.box(e.ToID)
+			if !tt.ok(p, c) {
+				t.Errorf("%s: %s %+v not past %s %+v", tt.dir, e.ToID, *c, e.FromID, *p)
+			}
+			if c.x-c.w/2 < 0 || c.right() > w || c.y < 0 || c.bottom() > h {
+				t.Errorf("%s: %s %+v outside %gx%g", tt.dir, e.ToID, *c, w, h)
+			}
+		}
+		out, err := (&SVGRenderer{Options: Options{Direction: tt.dir}}).Render(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkXML(t, out)
+	}
+}
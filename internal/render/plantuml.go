@@ -0,0 +1,209 @@
+package render
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// PlantUMLRenderer renders a tree as a PlantUML activity diagram. Decisions
+// become if/elseif/else blocks with the edge labels as guards, actions
+// become activities, I/O nodes activities with the <<input>> stereotype,
+// and start/end nodes start and stop. Activity diagrams are block
+// structured, so in graph mode a node reached a second time is drawn as a
+// connector circle named after its ID, matching one placed where the node
+// is first drawn.
+type PlantUMLRenderer struct {
+	// TooltipAttrs lists metadata keys to show as notes beside activities.
+	TooltipAttrs []string
+	Options      Options
+}
+
+// plantUMLHighlightFill is the background of highlighted activities;
+// PlantUML has no per-activity border color.
+const plantUMLHighlightFill = "#ffd6d6"
+
+func (r *PlantUMLRenderer) Render(t *model.Tree) (string, error) {
+	v, err := r.Options.prepare(t)
+	if err != nil {
+		return "", err
+	}
+	if v.dir != "TB" {
+		return "", errors.New("PlantUML activity diagrams only flow top to bottom (use direction TB)")
+	}
+	t = v.Tree
+	w := &plantUMLWriter{r: r, t: t, hl: v.hl, done: make(map[string]bool), shared: make(map[string]bool)}
+	back := t.BackEdges()
+	for _, e := range t.Edges {
+		if back[e.Key()] || len(t.Parents(e.ToID)) > 1 {
+			w.shared[e.ToID] = true
+		}
+	}
+
+	w.b.WriteString("@startuml\n")
+	if t.Name != "" {
+		w.line(0, "title "+plantUMLText(t.Name))
+	}
+	first := true
+	flow := func(id string) {
+		if w.done[id] {
+			return
+		}
+		if !first {
+			w.line(0, "")
+		}
+		first = false
+		w.node(id, 0)
+	}
+	if t.GetNode(t.RootID) != nil {
+		flow(t.RootID)
+	}
+	for _, id := range t.NodeIDs() {
+		if len(t.Parents(id)) == 0 {
+			flow(id)
+		}
+	}
+	for _, id := range t.NodeIDs() {
+		flow(id)
+	}
+	w.b.WriteString("@enduml\n")
+	return w.b.String(), nil
+}
+
+type plantUMLWriter struct {
+	r      *PlantUMLRenderer
+	t      *model.Tree
+	hl     highlight
+	b      strings.Builder
+	done   map[string]bool
+	shared map[string]bool // nodes reached more than once
+}
+
+func (w *plantUMLWriter) line(depth int, s string) {
+	if s != "" {
+		w.b.WriteString(strings.Repeat("  ", depth))
+		w.b.WriteString(s)
+	}
+	w.b.WriteByte('\n')
+}
+
+// node writes a node and everything that follows it.
+func (w *plantUMLWriter) node(id string, depth int) {
+	connector := "(" + dotID(id) + ")"
+	if w.done[id] {
+		w.line(depth, connector)
+		w.line(depth, "detach")
+		return
+	}
+	w.done[id] = true
+	if w.shared[id] {
+		w.line(depth, connector)
+	}
+
+	n := w.t.Nodes[id]
+	children := w.t.Children(id)
+	switch {
+	case n.Type == model.StartEnd && (id == w.t.RootID || len(w.t.Parents(id)) == 0):
+		w.line(depth, "start")
+	case n.Type == model.StartEnd && len(children) == 0:
+		w.line(depth, "stop")
+		return
+	case n.Type == model.Decision && len(children) > 0:
+		cond := "(" + plantUMLText(n.Label) + ")"
+		color := w.color(id)
+		if color != "" {
+			color += ":"
+		}
+		for i, e := range children {
+			guard := ""
+			if e.Label != "" {
+				guard = " (" + plantUMLText(e.Label) + ")"
+			}
+			switch {
+			case i == 0:
+				w.line(depth, color+"if "+cond+" then"+guard)
+			case i == len(children)-1:
+				w.line(depth, "else"+guard)
+			default:
+				w.line(depth, "elseif "+cond+" then"+guard)
+			}
+			if w.hl.edge(e) {
+				w.line(depth+1, plantUMLHighlightArrow)
+			}
+			w.node(e.ToID, depth+1)
+		}
+		w.line(depth, "endif")
+		return
+	case n.Type == model.IO:
+		w.line(depth, w.color(id)+":"+plantUMLText(n.Label)+"; <<input>>")
+		w.note(n, depth)
+	default:
+		w.line(depth, w.color(id)+":"+plantUMLText(n.Label)+";")
+		w.note(n, depth)
+	}
+
+	switch {
+	case len(children) == 1:
+		w.arrow(children[0], depth)
+		w.node(children[0].ToID, depth)
+	case len(children) > 1:
+		// Branches that are not decisions have no condition to show.
+		w.line(depth, "split")
+		for i, e := range children {
+			if i > 0 {
+				w.line(depth, "split again")
+			}
+			w.arrow(e, depth+1)
+			w.node(e.ToID, depth+1)
+		}
+		w.line(depth, "end split")
+	}
+}
+
+const plantUMLHighlightArrow = "-[" + highlightColor + ",bold]->"
+
+// arrow labels the arrow to the next activity with the edge label, and
+// colors it if it is highlighted.
+func (w *plantUMLWriter) arrow(e model.Edge, depth int) {
+	head := "->"
+	if w.hl.edge(e) {
+		head = plantUMLHighlightArrow
+	}
+	switch {
+	case e.Label != "":
+		w.line(depth, head+" "+plantUMLText(e.Label)+";")
+	case w.hl.edge(e):
+		w.line(depth, head)
+	}
+}
+
+// color returns the "#color" prefix that fills a node's activity or
+// diamond, or "" to keep PlantUML's default.
+func (w *plantUMLWriter) color(id string) string {
+	if w.hl.node(id) {
+		return plantUMLHighlightFill
+	}
+	c := w.r.Options.fill(w.t.Nodes[id].Type, "")
+	if c != "" && !strings.HasPrefix(c, "#") {
+		c = "#" + c
+	}
+	return c
+}
+
+func (w *plantUMLWriter) note(n *model.Node, depth int) {
+	text := tooltipText(n.Attrs, w.r.TooltipAttrs)
+	if text == "" {
+		return
+	}
+	w.line(depth, "note right")
+	for _, l := range strings.Split(text, "\n") {
+		w.line(depth+1, l)
+	}
+	w.line(depth, "end note")
+}
+
+// plantUMLText writes a label on one line; PlantUML reads \n as a break.
+func plantUMLText(s string) string {
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
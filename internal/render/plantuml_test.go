@@ -0,0 +1,111 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func TestPlantUMLRenderer(t *testing.T) {
+	tr := model.NewTree("triage")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.StartEnd, Label: "Start"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Severity?"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Page\non-call", Attrs: map[string]string{"owner": "ops"}}
+	tr.Nodes["n4"] = &model.Node{ID: "n4", Type: model.Action, Label: "Queue"}
+	tr.Nodes["n5"] = &model.Node{ID: "n5", Type: model.IO, Label: "Email reporter"}
+	tr.Nodes["n6"] = &model.Node{ID: "n6", Type: model.StartEnd, Label: "End"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "high"},
+		{FromID: "n2", ToID: "n4", Label: "medium"},
+		{FromID: "n2", ToID: "n5"},
+		{FromID: "n3", ToID: "n6", Label: "resolved"},
+	}
+	tr.RootID = "n1"
+
+	out, err := (&PlantUMLRenderer{TooltipAttrs: []string{"owner"}}).Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := `@startuml
+title triage
+start
+if (Severity?) then (high)
+  :Page\non-call;
+  note right
+    owner: ops
+  end note
+  -> resolved;
+  stop
+elseif (Severity?) then (medium)
+  :Queue;
+else
+  :Email reporter; <<input>>
+endif
+@enduml
+`
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestPlantUMLGraphMode(t *testing.T) {
+	tr := model.NewTree("retry")
+	tr.Graph = true
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "Try"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Worked?"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.StartEnd, Label: "Done"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n1", Label: "no"},
+	}
+	tr.RootID = "n1"
+
+	out, err := (&PlantUMLRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `@startuml
+title retry
+(n1)
+:Try;
+if (Worked?) then (yes)
+  stop
+else (no)
+  (n1)
+  detach
+endif
+@enduml
+`
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestPlantUMLSplitAndUnreached(t *testing.T) {
+	tr := model.NewTree("")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "Prepare"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "Build"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Test"}
+	tr.Nodes["n4"] = &model.Node{ID: "n4", Type: model.Action, Label: "Orphan"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2", Label: "code"},
+		{FromID: "n1", ToID: "n3"},
+	}
+	tr.RootID = "n1"
+
+	out, err := (&PlantUMLRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"split\n  -> code;\n  :Build;\nsplit again\n  :Test;\nend split\n", "\n\n:Orphan;\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "title") {
+		t.Errorf("unnamed tree has a title:\n%s", out)
+	}
+}
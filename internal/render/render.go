@@ -1,8 +1,24 @@
 package render
 
-import "github.com/jllovet/decision-tree-cli/internal/model"
+import (
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
 
 // Renderer converts a tree to a string representation.
 type Renderer interface {
 	Render(t *model.Tree) (string, error)
 }
+
+// tooltipText formats the selected metadata attributes as "key: value"
+// lines, in the order the keys were given. Missing keys are skipped.
+func tooltipText(attrs map[string]string, keys []string) string {
+	var lines []string
+	for _, k := range keys {
+		if v, ok := attrs[k]; ok {
+			lines = append(lines, k+": "+v)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,180 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// SVGRenderer renders a tree as a standalone SVG image. It lays the tree
+// out itself, so no Graphviz install is needed. Edges that loop back to an
+// earlier node are drawn dashed along the margin beside the layers.
+type SVGRenderer struct {
+	// TooltipAttrs lists metadata keys to pass through as node and edge
+	// tooltips (SVG <title> elements).
+	TooltipAttrs []string
+	Options      Options
+}
+
+// Node fill colors by type.
+var svgFills = map[model.NodeType]string{
+	model.Decision: "#fff4d6",
+	model.Action:   "#e8f0fe",
+	model.StartEnd: "#e6f4ea",
+	model.IO:       "#f3e8fd",
+}
+
+func (r *SVGRenderer) Render(t *model.Tree) (string, error) {
+	v, err := r.Options.prepare(t)
+	if err != nil {
+		return "", err
+	}
+	t = v.Tree
+	l := layoutTree(t, v.dir)
+	width, height := l.size()
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%s\" height=\"%s\" viewBox=\"0 0 %s %s\" font-family=\"sans-serif\" font-size=\"%s\">\n",
+		num(width), num(height), num(width), num(height), num(fontSize))
+	fmt.Fprintf(&b, "  <title>%s</title>\n", xmlEscape(t.Name))
+	b.WriteString("  <defs>\n")
+	b.WriteString("    <marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\">\n")
+	b.WriteString("      <path d=\"M0,0 L10,5 L0,10 z\" fill=\"#333\"/>\n")
+	b.WriteString("    </marker>\n")
+	if len(v.hl.edges) > 0 {
+		b.WriteString("    <marker id=\"arrow-highlight\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\">\n")
+		fmt.Fprintf(&b, "      <path d=\"M0,0 L10,5 L0,10 z\" fill=\"%s\"/>\n", highlightColor)
+		b.WriteString("    </marker>\n")
+	}
+	b.WriteString("  </defs>\n")
+	b.WriteString("  <rect width=\"100%\" height=\"100%\" fill=\"white\"/>\n")
+
+	b.WriteString("  <g class=\"edges\" fill=\"none\" stroke=\"#333\">\n")
+	lane := 0
+	for _, e := range t.Edges {
+		// Paths are worked out in the top-down layout, then turned.
+		from, to := l.boxes[e.FromID], l.boxes[e.ToID]
+		pt := func(x, y float64) string {
+			px, py := l.point(x, y)
+			return num(px) + "," + num(py)
+		}
+		var path string
+		var lx, ly float64
+		style := ""
+		if l.back[e.Key()] {
+			// Out of the source's far side, back along the gutter, into
+			// the target's far side.
+			gx := l.gutter + float64(lane)*loopGap
+			lane++
+			sy, ty := from.middleY(), to.middleY()
+			if e.FromID == e.ToID {
+				sy, ty = from.middleY()+from.h/4, from.middleY()-from.h/4
+			}
+			path = fmt.Sprintf("M%s L%s L%s L%s", pt(from.right(), sy), pt(gx, sy), pt(gx, ty), pt(to.right(), ty))
+			lx, ly = l.point(gx, (sy+ty)/2)
+			style = ` stroke-dasharray="5,4"`
+		} else {
+			sx, sy, tx, ty := from.x, from.bottom(), to.x, to.y
+			my := (sy + ty) / 2
+			path = fmt.Sprintf("M%s C%s %s %s", pt(sx, sy), pt(sx, my), pt(tx, my), pt(tx, ty))
+			lx, ly = l.point(cubicPoint(0.6, sx, sx, tx, tx), cubicPoint(0.6, sy, my, my, ty))
+		}
+		class, marker := "edge", "arrow"
+		if v.hl.edge(e) {
+			class, marker = "edge highlight", "arrow-highlight"
+			style += fmt.Sprintf(` stroke="%s" stroke-width="3"`, highlightColor)
+		}
+		fmt.Fprintf(&b, "    <g class=\"%s\" data-from=\"%s\" data-to=\"%s\">\n", class, xmlEscape(e.FromID), xmlEscape(e.ToID))
+		if tip := tooltipText(e.Attrs, r.TooltipAttrs); tip != "" {
+			fmt.Fprintf(&b, "      <title>%s</title>\n", xmlEscape(tip))
+		}
+		fmt.Fprintf(&b, "      <path d=\"%s\"%s marker-end=\"url(#%s)\"/>\n", path, style, marker)
+		if e.Label != "" {
+			w := labelWidth(e.Label)
+			fmt.Fprintf(&b, "      <rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"white\" stroke=\"none\"/>\n",
+				num(lx-w/2), num(ly-lineHeight/2), num(w), num(lineHeight))
+			fmt.Fprintf(&b, "      <text x=\"%s\" y=\"%s\" text-anchor=\"middle\" dominant-baseline=\"central\" fill=\"#333\" stroke=\"none\">%s</text>\n",
+				num(lx), num(ly), xmlEscape(e.Label))
+		}
+		b.WriteString("    </g>\n")
+	}
+	b.WriteString("  </g>\n")
+
+	b.WriteString("  <g class=\"nodes\" stroke=\"#333\">\n")
+	for _, id := range t.NodeIDs() {
+		n := t.Nodes[id]
+		box := l.box(id)
+		class := "node " + n.Type.String()
+		stroke := ""
+		if v.hl.node(id) {
+			class += " highlight"
+			stroke = fmt.Sprintf(` stroke="%s" stroke-width="3"`, highlightColor)
+		}
+		fmt.Fprintf(&b, "    <g class=\"%s\" id=\"node-%s\">\n", class, xmlEscape(id))
+		if tip := tooltipText(n.Attrs, r.TooltipAttrs); tip != "" {
+			fmt.Fprintf(&b, "      <title>%s</title>\n", xmlEscape(tip))
+		}
+		fmt.Fprintf(&b, "      %s\n", svgShape(n.Type, box, r.Options.fill(n.Type, svgFills[n.Type]), stroke))
+		b.WriteString(svgLabel(n.Label, box))
+		b.WriteString("    </g>\n")
+	}
+	b.WriteString("  </g>\n")
+	b.WriteString("</svg>\n")
+	return b.String(), nil
+}
+
+// svgShape draws the shape for a node type: diamond, rectangle, oval or
+// parallelogram. stroke holds any extra stroke attributes.
+func svgShape(typ model.NodeType, b *nodeBox, fill, stroke string) string {
+	left, top, right, bottom := b.x-b.w/2, b.y, b.x+b.w/2, b.bottom()
+	switch typ {
+	case model.Decision:
+		return fmt.Sprintf("<polygon points=\"%s,%s %s,%s %s,%s %s,%s\" fill=\"%s\"%s/>",
+			num(b.x), num(top), num(right), num(b.middleY()), num(b.x), num(bottom), num(left), num(b.middleY()), fill, stroke)
+	case model.StartEnd:
+		return fmt.Sprintf("<ellipse cx=\"%s\" cy=\"%s\" rx=\"%s\" ry=\"%s\" fill=\"%s\"%s/>",
+			num(b.x), num(b.middleY()), num(b.w/2), num(b.h/2), fill, stroke)
+	case model.IO:
+		return fmt.Sprintf("<polygon points=\"%s,%s %s,%s %s,%s %s,%s\" fill=\"%s\"%s/>",
+			num(left+skew), num(top), num(right), num(top), num(right-skew), num(bottom), num(left), num(bottom), fill, stroke)
+	default:
+		return fmt.Sprintf("<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" rx=\"3\" fill=\"%s\"%s/>",
+			num(left), num(top), num(b.w), num(b.h), fill, stroke)
+	}
+}
+
+// svgLabel centers a possibly multi-line label in a node's box.
+func svgLabel(label string, b *nodeBox) string {
+	lines := strings.Split(label, "\n")
+	y := b.middleY() - float64(len(lines)-1)*lineHeight/2
+	var s strings.Builder
+	fmt.Fprintf(&s, "      <text x=\"%s\" y=\"%s\" text-anchor=\"middle\" dominant-baseline=\"central\" fill=\"#111\" stroke=\"none\">", num(b.x), num(y))
+	for i, line := range lines {
+		if i == 0 {
+			s.WriteString(xmlEscape(line))
+			continue
+		}
+		fmt.Fprintf(&s, "<tspan x=\"%s\" dy=\"%s\">%s</tspan>", num(b.x), num(lineHeight), xmlEscape(line))
+	}
+	s.WriteString("</text>\n")
+	return s.String()
+}
+
+// cubicPoint evaluates one coordinate of a cubic Bézier curve at t.
+func cubicPoint(t, p0, p1, p2, p3 float64) float64 {
+	u := 1 - t
+	return u*u*u*p0 + 3*u*u*t*p1 + 3*u*t*t*p2 + t*t*t*p3
+}
+
+// num formats a coordinate with at most one decimal place.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10)/10, 'f', -1, 64)
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#39;")
+
+func xmlEscape(s string) string {
+	return xmlEscaper.Replace(s)
+}
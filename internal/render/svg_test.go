@@ -0,0 +1,178 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// checkXML fails the test if out is not well-formed XML.
+func checkXML(t *testing.T, out string) {
+	t.Helper()
+	d := xml.NewDecoder(strings.NewReader(out))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, out)
+		}
+	}
+}
+
+func TestSVGRenderer(t *testing.T) {
+	tr := model.NewTree("auth-flow")
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.StartEnd, Label: "Start"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Authenticated?"}
+	tr.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Grant access"}
+	tr.Nodes["n4"] = &model.Node{ID: "n4", Type: model.IO, Label: "Show login"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "yes"},
+		{FromID: "n2", ToID: "n4", Label: "no"},
+	}
+	tr.RootID = "n1"
+
+	out, err := (&SVGRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	checkXML(t, out)
+	if !strings.HasPrefix(out, `<svg xmlns="http://www.w3.org/2000/svg"`) {
+		t.Error("missing svg root element")
+	}
+	for _, want := range []string{
+		"<title>auth-flow</title>",
+		`<g class="node startend" id="node-n1">`,
+		`<g class="node decision" id="node-n2">`,
+		`<g class="node action" id="node-n3">`,
+		`<g class="node io" id="node-n4">`,
+		`<g class="edge" data-from="n2" data-to="n3">`,
+		">yes</text>",
+		">no</text>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	// One shape element per node type.
+	for id, shape := range map[string]string{"n1": "<ellipse", "n2": "<polygon", "n3": "<rect", "n4": "<polygon"} {
+		i := strings.Index(out, `id="node-`+id+`"`)
+		if i < 0 || !strings.HasPrefix(strings.TrimSpace(out[i+strings.Index(out[i:], "\n"):]), shape) {
+			t.Errorf("node %s is not drawn with %s", id, shape)
+		}
+	}
+}
+
+func TestSVGEscaping(t *testing.T) {
+	tr := model.NewTree(`a <b> & "c"`)
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "x < y & \"z\"\nsecond line",
+		Attrs: map[string]string{"owner": "<ops>"}}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "B"}
+	tr.Edges = []model.Edge{{FromID: "n1", ToID: "n2", Label: "<next>"}}
+	tr.RootID = "n1"
+
+	out, err := (&SVGRenderer{TooltipAttrs: []string{"owner"}}).Render(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkXML(t, out)
+	for _, want := range []string{
+		"x &lt; y &amp; &quot;z&quot;<tspan",
+		">second line</tspan>",
+		"<title>owner: &lt;ops&gt;</title>",
+		">&lt;next&gt;</text>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestSVGBackEdges(t *testing.T) {
+	tr := model.NewTree("retry")
+	tr.Graph = true
+	tr.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "Try"}
+	tr.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: "Worked?"}
+	tr.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n1", Label: "no"},
+		{FromID: "n2", ToID: "n2", Label: "again"},
+	}
+	tr.RootID = "n1"
+
+	out, err := (&SVGRenderer{}).Render(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkXML(t, out)
+	if n := strings.Count(out, "stroke-dasharray"); n != 2 {
+		t.Errorf("got %d dashed edges, want 2:\n%s", n, out)
+	}
+	if l := layoutTree(tr, "TB"); l.boxes["n2"].layer != 1 || l.gutter <= l.boxes["n2"].right() {
+		t.Errorf("n2 layer %d, gutter %v", l.boxes["n2"].layer, l.gutter)
+	}
+}
+
+func TestSVGEmptyTree(t *testing.T) {
+	out, err := (&SVGRenderer{}).Render(model.NewTree("empty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkXML(t, out)
+}
+
+func TestLayoutNoOverlaps(t *testing.T) {
+	// A wide graph with shared nodes, an edge that skips a layer, a long
+	// label, and an unconnected node.
+	tr := model.NewTree("wide")
+	tr.Graph = true
+	add := func(id string, typ model.NodeType, label string) {
+		tr.Nodes[id] = &model.Node{ID: id, Type: typ, Label: label}
+	}
+	add("n1", model.Decision, "Root question?")
+	for i := 2; i <= 7; i++ {
+		add(fmt.Sprintf("n%d", i), model.Action, strings.Repeat("step ", i))
+	}
+	add("n8", model.StartEnd, "Shared end")
+	add("n9", model.IO, "Orphan")
+	connect := func(from, to string) {
+		tr.Edges = append(tr.Edges, model.Edge{FromID: from, ToID: to, Label: from + "-" + to})
+	}
+	connect("n1", "n2")
+	connect("n1", "n3")
+	connect("n1", "n4")
+	connect("n2", "n5")
+	connect("n2", "n6")
+	connect("n3", "n7")
+	connect("n5", "n8")
+	connect("n7", "n8")
+	connect("n1", "n8")
+	tr.RootID = "n1"
+
+	l := layoutTree(tr, "TB")
+	ids := tr.NodeIDs()
+	for i, a := range ids {
+		ba := l.boxes[a]
+		if ba.x-ba.w/2 < 0 || ba.right() > l.width || ba.y < 0 || ba.bottom() > l.height {
+			t.Errorf("%s is outside the %vx%v canvas: %+v", a, l.width, l.height, *ba)
+		}
+		for _, b := range ids[i+1:] {
+			bb := l.boxes[b]
+			if ba.x-ba.w/2 < bb.right() && bb.x-bb.w/2 < ba.right() && ba.y < bb.bottom() && bb.y < ba.bottom() {
+				t.Errorf("%s %+v overlaps %s %+v", a, *ba, b, *bb)
+			}
+		}
+	}
+	for _, e := range tr.Edges {
+		if l.boxes[e.ToID].layer <= l.boxes[e.FromID].layer {
+			t.Errorf("edge %s->%s does not point down", e.FromID, e.ToID)
+		}
+	}
+	if l.boxes["n8"].layer != 3 {
+		t.Errorf("n8 layer = %d, want 3 (below its deepest parent)", l.boxes["n8"].layer)
+	}
+}
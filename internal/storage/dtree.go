@@ -0,0 +1,598 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// The .dtree format describes a tree as indented lines, one node per line.
+// A child is indented under its parent and may start with the label of the
+// edge that leads to it:
+//
+//	tree "auth-flow"
+//
+//	startend "Start" #n1
+//	  decision "Authenticated?" #n2 [condition="user.ok"]
+//	    yes -> action "Grant access" #n3
+//	    no [p=0.2] -> io "Show login form" #n4
+//	      retry -> @n2
+//
+// A node is its type, a quoted label, an optional #id and optional
+// [key=value ...] properties (condition, payoff, and anything else as
+// metadata). An @id line is an edge to a node declared elsewhere, which is
+// how shared nodes and loops are written in graph mode. IDs with spaces or
+// punctuation are quoted, as in #"start here". Edge properties
+// (p, outcome, and anything else as metadata) go before the arrow. The
+// remaining lines are "tree <name>", "graph", "root @id" or "root none",
+// and comments, which start with "# ".
+
+// dtreeIndent is the indentation FormatDTree uses per level.
+const dtreeIndent = "  "
+
+// Property names with a meaning of their own. Metadata attributes with
+// these names, or names that need quoting, are written as "meta.<name>".
+var (
+	reservedNodeProps = map[string]bool{"condition": true, "payoff": true}
+	reservedEdgeProps = map[string]bool{"p": true, "probability": true, "outcome": true}
+)
+
+// FormatDTree writes a tree in the canonical .dtree form: the root first,
+// children in edge order, every node with its ID, and nodes that are not
+// reachable from the root as further top-level blocks.
+func FormatDTree(t *model.Tree) string {
+	f := &dtreeFormatter{t: t, emitted: make(map[string]bool)}
+	if t.Name != "" {
+		f.line(0, "tree "+strconv.Quote(t.Name))
+	}
+	if t.Graph {
+		f.line(0, "graph")
+	}
+	if t.RootID == "" && len(t.Nodes) > 0 {
+		f.line(0, "root none")
+	}
+	var tops []string
+	if t.GetNode(t.RootID) != nil {
+		tops = append(tops, t.RootID)
+	}
+	for _, id := range t.NodeIDs() {
+		if len(t.Parents(id)) == 0 {
+			tops = append(tops, id)
+		}
+	}
+	tops = append(tops, t.NodeIDs()...) // whatever is left sits on unreachable cycles
+	for _, id := range tops {
+		if f.emitted[id] {
+			continue
+		}
+		if f.sb.Len() > 0 {
+			f.sb.WriteString("\n")
+		}
+		f.node(id, 0, nil)
+	}
+	return f.sb.String()
+}
+
+type dtreeFormatter struct {
+	t       *model.Tree
+	emitted map[string]bool
+	sb      strings.Builder
+}
+
+func (f *dtreeFormatter) line(depth int, text string) {
+	f.sb.WriteString(strings.Repeat(dtreeIndent, depth))
+	f.sb.WriteString(text)
+	f.sb.WriteString("\n")
+}
+
+func (f *dtreeFormatter) node(id string, depth int, via *model.Edge) {
+	prefix := ""
+	if via != nil {
+		prefix = edgePrefixText(*via)
+	}
+	if f.emitted[id] {
+		f.line(depth, prefix+"@"+dtreeValue(id))
+		return
+	}
+	f.emitted[id] = true
+	n := f.t.Nodes[id]
+	f.line(depth, prefix+n.Type.String()+" "+strconv.Quote(n.Label)+" #"+dtreeValue(id)+nodePropsText(n))
+	for _, e := range f.t.Children(id) {
+		f.node(e.ToID, depth+1, &e)
+	}
+}
+
+// edgePrefixText renders the "label [props] -> " part of a child line.
+func edgePrefixText(e model.Edge) string {
+	var props []string
+	if e.Probability != nil {
+		props = append(props, "p="+formatNumber(*e.Probability))
+	}
+	if e.Outcome != "" {
+		props = append(props, "outcome="+dtreeValue(e.Outcome))
+	}
+	props = append(props, attrProps(e.Attrs, reservedEdgeProps)...)
+	var parts []string
+	if e.Label != "" {
+		parts = append(parts, dtreeValue(e.Label))
+	}
+	if len(props) > 0 {
+		parts = append(parts, "["+strings.Join(props, " ")+"]")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, " ") + " -> "
+}
+
+func nodePropsText(n *model.Node) string {
+	var props []string
+	if n.Condition != "" {
+		props = append(props, "condition="+strconv.Quote(n.Condition))
+	}
+	if n.Payoff != nil {
+		props = append(props, "payoff="+formatNumber(*n.Payoff))
+	}
+	props = append(props, attrProps(n.Attrs, reservedNodeProps)...)
+	if len(props) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(props, " ") + "]"
+}
+
+func attrProps(attrs map[string]string, reserved map[string]bool) []string {
+	var props []string
+	for _, k := range model.SortedAttrKeys(attrs) {
+		key := k
+		if reserved[k] || strings.HasPrefix(k, "meta.") {
+			key = "meta." + k
+		}
+		props = append(props, dtreeValue(key)+"="+dtreeValue(attrs[k]))
+	}
+	return props
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// dtreeValue writes s bare when it reads back unchanged, quoted otherwise.
+func dtreeValue(s string) string {
+	if isBare(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+func isBare(s string) bool {
+	if s == "" || strings.Contains(s, "->") {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || strings.ContainsRune(`"[]=#@`, r) || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseDTree parses a tree in the .dtree format. Nodes without an explicit
+// ID get the next free "nN" ID. Unless a "root" line says otherwise, the
+// first top-level node is the root. Errors name the offending line.
+func ParseDTree(src string) (*model.Tree, error) {
+	p := &dtreeParser{t: model.NewTree(""), byID: make(map[string]*dtreeNode)}
+	for i, raw := range strings.Split(src, "\n") {
+		if err := p.parseLine(i+1, raw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return p.finish()
+}
+
+type dtreeNode struct {
+	node *model.Node
+	line int
+}
+
+type dtreeEdge struct {
+	from *dtreeNode
+	to   *dtreeNode // nil for an @id reference
+	ref  string
+	edge model.Edge
+	line int
+}
+
+// dtreeFrame is an open line that deeper-indented lines become children of.
+type dtreeFrame struct {
+	indent int
+	node   *dtreeNode // nil for a reference, which cannot have children
+	ref    string
+}
+
+type dtreeParser struct {
+	t        *model.Tree
+	nodes    []*dtreeNode
+	edges    []*dtreeEdge
+	byID     map[string]*dtreeNode
+	stack    []dtreeFrame
+	firstTop *dtreeNode
+	root     string // from a "root" line
+	rootLine int    // line of the "root" line, 0 if none
+}
+
+func (p *dtreeParser) parseLine(lineNo int, raw string) error {
+	body := strings.TrimLeft(raw, " \t")
+	indent := len(raw) - len(body)
+	toks, err := lexDTree(body)
+	if err != nil || len(toks) == 0 {
+		return err
+	}
+	if indent == 0 && toks[0].kind == dtWord {
+		switch toks[0].text {
+		case "tree", "graph", "root":
+			p.stack = nil
+			return p.directive(lineNo, toks)
+		}
+	}
+
+	for len(p.stack) > 0 && p.stack[len(p.stack)-1].indent >= indent {
+		p.stack = p.stack[:len(p.stack)-1]
+	}
+	var parent *dtreeNode
+	if len(p.stack) > 0 {
+		top := p.stack[len(p.stack)-1]
+		if top.node == nil {
+			return fmt.Errorf("@%s is a reference and cannot have children", top.ref)
+		}
+		parent = top.node
+	}
+
+	var edge model.Edge
+	nodeToks := toks
+	for i, tok := range toks {
+		if tok.kind != dtArrow {
+			continue
+		}
+		if parent == nil {
+			return errors.New("a top-level node cannot have an incoming branch")
+		}
+		if edge, err = parseEdgePart(toks[:i]); err != nil {
+			return err
+		}
+		nodeToks = toks[i+1:]
+		break
+	}
+	if len(nodeToks) == 0 {
+		return errors.New("missing node after ->")
+	}
+
+	if nodeToks[0].kind == dtRef {
+		if len(nodeToks) > 1 {
+			return fmt.Errorf("unexpected %s after @%s", nodeToks[1], nodeToks[0].text)
+		}
+		if parent == nil {
+			return fmt.Errorf("@%s must be indented under the node it is a branch of", nodeToks[0].text)
+		}
+		p.edges = append(p.edges, &dtreeEdge{from: parent, ref: nodeToks[0].text, edge: edge, line: lineNo})
+		p.stack = append(p.stack, dtreeFrame{indent: indent, ref: nodeToks[0].text})
+		return nil
+	}
+
+	n, err := parseNodePart(nodeToks)
+	if err != nil {
+		return err
+	}
+	dn := &dtreeNode{node: n, line: lineNo}
+	if n.ID != "" {
+		if prev, ok := p.byID[n.ID]; ok {
+			return fmt.Errorf("duplicate ID #%s (first used on line %d)", n.ID, prev.line)
+		}
+		p.byID[n.ID] = dn
+	}
+	p.nodes = append(p.nodes, dn)
+	if parent != nil {
+		p.edges = append(p.edges, &dtreeEdge{from: parent, to: dn, edge: edge, line: lineNo})
+	} else if p.firstTop == nil {
+		p.firstTop = dn
+	}
+	p.stack = append(p.stack, dtreeFrame{indent: indent, node: dn})
+	return nil
+}
+
+func (p *dtreeParser) directive(lineNo int, toks []dtreeToken) error {
+	name := toks[0].text
+	args := toks[1:]
+	switch {
+	case name == "tree" && len(args) == 1 && (args[0].kind == dtString || args[0].kind == dtWord):
+		p.t.Name = args[0].text
+	case name == "graph" && len(args) == 0:
+		p.t.Graph = true
+	case name == "root" && len(args) == 1 && args[0].kind == dtRef:
+		p.root, p.rootLine = args[0].text, lineNo
+	case name == "root" && len(args) == 1 && args[0].kind == dtWord && args[0].text == "none":
+		p.root, p.rootLine = "", lineNo
+	case name == "tree":
+		return errors.New(`expected tree "<name>"`)
+	case name == "root":
+		return errors.New("expected root @<id> or root none")
+	default:
+		return fmt.Errorf("unexpected %s after %s", args[0], name)
+	}
+	return nil
+}
+
+// parseEdgePart parses the "[label] [props]" before an arrow.
+func parseEdgePart(toks []dtreeToken) (model.Edge, error) {
+	var e model.Edge
+	if len(toks) > 0 && (toks[0].kind == dtWord || toks[0].kind == dtString) {
+		e.Label = toks[0].text
+		toks = toks[1:]
+	}
+	props, rest, err := parseProps(toks)
+	if err != nil {
+		return e, err
+	}
+	if len(rest) > 0 {
+		return e, fmt.Errorf("unexpected %s before ->", rest[0])
+	}
+	for _, kv := range props {
+		switch kv[0] {
+		case "p", "probability":
+			v, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil || !(v >= 0 && v <= 1) {
+				return e, fmt.Errorf("probability %q must be a number between 0 and 1", kv[1])
+			}
+			e.Probability = &v
+		case "outcome":
+			e.Outcome = kv[1]
+		default:
+			e.Attrs = setAttr(e.Attrs, kv[0], kv[1])
+		}
+	}
+	return e, nil
+}
+
+// parseNodePart parses "type label [#id] [props]".
+func parseNodePart(toks []dtreeToken) (*model.Node, error) {
+	if toks[0].kind != dtWord {
+		return nil, fmt.Errorf("expected a node type, got %s", toks[0])
+	}
+	nt, err := model.ParseNodeType(toks[0].text)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) < 2 || (toks[1].kind != dtString && toks[1].kind != dtWord) {
+		return nil, fmt.Errorf("expected a label after %s", toks[0].text)
+	}
+	n := &model.Node{Type: nt, Label: toks[1].text}
+	toks = toks[2:]
+	if len(toks) > 0 && toks[0].kind == dtID {
+		n.ID = toks[0].text
+		toks = toks[1:]
+	}
+	props, rest, err := parseProps(toks)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected %s", rest[0])
+	}
+	for _, kv := range props {
+		switch kv[0] {
+		case "condition":
+			n.Condition = kv[1]
+		case "payoff":
+			v, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("payoff %q is not a number", kv[1])
+			}
+			n.Payoff = &v
+		default:
+			n.Attrs = setAttr(n.Attrs, kv[0], kv[1])
+		}
+	}
+	return n, nil
+}
+
+// setAttr stores a metadata property, removing the "meta." escape prefix.
+func setAttr(attrs map[string]string, key, value string) map[string]string {
+	if attrs == nil {
+		attrs = make(map[string]string)
+	}
+	attrs[strings.TrimPrefix(key, "meta.")] = value
+	return attrs
+}
+
+// parseProps parses an optional "[key=value ...]" group and returns the
+// pairs in order along with the tokens after it.
+func parseProps(toks []dtreeToken) ([][2]string, []dtreeToken, error) {
+	if len(toks) == 0 || toks[0].kind != dtOpen {
+		return nil, toks, nil
+	}
+	var props [][2]string
+	i := 1
+	for {
+		if i >= len(toks) {
+			return nil, nil, errors.New("missing ]")
+		}
+		if toks[i].kind == dtClose {
+			return props, toks[i+1:], nil
+		}
+		if i+2 >= len(toks) || (toks[i].kind != dtWord && toks[i].kind != dtString) ||
+			toks[i+1].kind != dtEquals || (toks[i+2].kind != dtWord && toks[i+2].kind != dtString) {
+			return nil, nil, fmt.Errorf("expected key=value, got %s", toks[i])
+		}
+		props = append(props, [2]string{toks[i].text, toks[i+2].text})
+		i += 3
+	}
+}
+
+// finish assigns missing IDs, resolves references and builds the tree.
+func (p *dtreeParser) finish() (*model.Tree, error) {
+	t := p.t
+	for id := range p.byID {
+		t.ReserveID(id)
+	}
+	for _, dn := range p.nodes {
+		if dn.node.ID == "" {
+			id := t.NextID()
+			for p.byID[id] != nil {
+				id = t.NextID()
+			}
+			dn.node.ID = id
+			p.byID[id] = dn
+		}
+		t.Nodes[dn.node.ID] = dn.node
+	}
+	for _, de := range p.edges {
+		to := de.to
+		if to == nil {
+			if to = p.byID[de.ref]; to == nil {
+				return nil, fmt.Errorf("line %d: unknown node @%s", de.line, de.ref)
+			}
+		}
+		e := de.edge
+		e.FromID, e.ToID = de.from.node.ID, to.node.ID
+		if t.HasEdge(e.FromID, e.ToID) {
+			return nil, fmt.Errorf("line %d: duplicate branch from #%s to #%s", de.line, e.FromID, e.ToID)
+		}
+		t.Edges = append(t.Edges, e)
+	}
+	switch {
+	case p.rootLine > 0 && p.root != "" && p.byID[p.root] == nil:
+		return nil, fmt.Errorf("line %d: unknown root @%s", p.rootLine, p.root)
+	case p.rootLine > 0:
+		t.RootID = p.root
+	case p.firstTop != nil:
+		t.RootID = p.firstTop.node.ID
+	}
+	if !t.Graph {
+		for _, id := range t.NodeIDs() {
+			if parents := t.Parents(id); len(parents) > 1 {
+				return nil, fmt.Errorf("line %d: #%s has %d parents; add a \"graph\" line to allow shared nodes", p.byID[id].line, id, len(parents))
+			}
+			if t.Ancestors(id)[id] {
+				return nil, fmt.Errorf("line %d: #%s is part of a loop; add a \"graph\" line to allow loops", p.byID[id].line, id)
+			}
+		}
+	}
+	return t, nil
+}
+
+type dtreeTokenKind int
+
+const (
+	dtWord   dtreeTokenKind = iota // bare word
+	dtString                       // quoted string
+	dtID                           // #id
+	dtRef                          // @id
+	dtArrow                        // ->
+	dtOpen                         // [
+	dtClose                        // ]
+	dtEquals                       // =
+)
+
+type dtreeToken struct {
+	kind dtreeTokenKind
+	text string
+}
+
+func (t dtreeToken) String() string {
+	switch t.kind {
+	case dtString:
+		return strconv.Quote(t.text)
+	case dtID:
+		return fmt.Sprintf("%q", "#"+t.text)
+	case dtRef:
+		return fmt.Sprintf("%q", "@"+t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// lexDTree splits one line into tokens, stopping at a comment: a "#" that
+// is followed by a space or ends the line.
+func lexDTree(line string) ([]dtreeToken, error) {
+	var toks []dtreeToken
+	i := 0
+	for i < len(line) {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' && (i+1 == len(line) || line[i+1] == ' ' || line[i+1] == '\t'):
+			return toks, nil
+		case c == '"':
+			s, next, err := lexString(line, i)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, dtreeToken{kind: dtString, text: s})
+			i = next
+		case strings.HasPrefix(line[i:], "->"):
+			toks = append(toks, dtreeToken{kind: dtArrow, text: "->"})
+			i += 2
+		case c == '[':
+			toks = append(toks, dtreeToken{kind: dtOpen, text: "["})
+			i++
+		case c == ']':
+			toks = append(toks, dtreeToken{kind: dtClose, text: "]"})
+			i++
+		case c == '=':
+			toks = append(toks, dtreeToken{kind: dtEquals, text: "="})
+			i++
+		default:
+			kind, start := dtWord, i
+			if c == '#' || c == '@' {
+				kind, start = dtID, i+1
+				if c == '@' {
+					kind = dtRef
+				}
+				if start < len(line) && line[start] == '"' {
+					s, next, err := lexString(line, start)
+					if err != nil {
+						return nil, err
+					}
+					toks = append(toks, dtreeToken{kind: kind, text: s})
+					i = next
+					continue
+				}
+			}
+			j := start
+			for j < len(line) && !strings.ContainsRune(" \t\r\"[]=#@", rune(line[j])) && !strings.HasPrefix(line[j:], "->") {
+				j++
+			}
+			if j == start {
+				return nil, fmt.Errorf("expected a name after %q", c)
+			}
+			toks = append(toks, dtreeToken{kind: kind, text: line[start:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// lexString reads the quoted string starting at line[i] and returns its
+// text and the index just past the closing quote.
+func lexString(line string, i int) (string, int, error) {
+	j := i + 1
+	for j < len(line) && line[j] != '"' {
+		if line[j] == '\\' {
+			j++
+		}
+		j++
+	}
+	if j >= len(line) {
+		return "", 0, errors.New("unterminated string")
+	}
+	s, err := strconv.Unquote(line[i : j+1])
+	if err != nil {
+		return "", 0, fmt.Errorf("bad string %s", line[i:j+1])
+	}
+	return s, j + 1, nil
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+func testdataDir() string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(filename), "..", "..", "testdata")
+}
+
+// assertSameTree checks that two trees have the same name, root, mode,
+// nodes and edges. Edge order may differ.
+func assertSameTree(t *testing.T, got, want *model.Tree) {
+	t.Helper()
+	if got.Name != want.Name || got.RootID != want.RootID || got.Graph != want.Graph {
+		t.Errorf("header = (%q, %q, %v), want (%q, %q, %v)", got.Name, got.RootID, got.Graph, want.Name, want.RootID, want.Graph)
+	}
+	if !reflect.DeepEqual(got.Nodes, want.Nodes) {
+		for id, n := range want.Nodes {
+			if !reflect.DeepEqual(got.Nodes[id], n) {
+				t.Errorf("node %s = %+v, want %+v", id, got.Nodes[id], n)
+			}
+		}
+		if len(got.Nodes) != len(want.Nodes) {
+			t.Errorf("got %d nodes, want %d", len(got.Nodes), len(want.Nodes))
+		}
+	}
+	sortEdges := func(edges []model.Edge) []model.Edge {
+		c := append([]model.Edge(nil), edges...)
+		sort.Slice(c, func(i, j int) bool {
+			if c[i].FromID != c[j].FromID {
+				return c[i].FromID < c[j].FromID
+			}
+			return c[i].ToID < c[j].ToID
+		})
+		return c
+	}
+	if g, w := sortEdges(got.Edges), sortEdges(want.Edges); !reflect.DeepEqual(g, w) {
+		t.Errorf("edges =\n%+v\nwant\n%+v", g, w)
+	}
+}
+
+func TestFormatDTreeGolden(t *testing.T) {
+	tree, err := Load(filepath.Join(testdataDir(), "sample-tree.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(filepath.Join(testdataDir(), "sample-tree.dtree"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatDTree(tree); got != string(golden) {
+		t.Errorf("FormatDTree mismatch.\nGot:\n%s\nWant:\n%s", got, golden)
+	}
+}
+
+func TestDTreeRoundTripSample(t *testing.T) {
+	want, err := Load(filepath.Join(testdataDir(), "sample-tree.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(filepath.Join(testdataDir(), "sample-tree.dtree"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameTree(t, got, want)
+	if got.Counter != want.Counter {
+		t.Errorf("Counter = %d, want %d", got.Counter, want.Counter)
+	}
+}
+
+func TestDTreeRoundTripGraph(t *testing.T) {
+	p, payoff := 0.25, -10.0
+	want := model.NewTree("retry flow")
+	want.Graph = true
+	want.RootID = "n1"
+	want.Nodes["n1"] = &model.Node{ID: "n1", Type: model.StartEnd, Label: "Start"}
+	want.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Decision, Label: `Say "hi"?`,
+		Condition: `user.name == "bob"`, Attrs: map[string]string{"owner": "ops team", "payoff": "x", "meta.k": "v"}}
+	want.Nodes["n3"] = &model.Node{ID: "n3", Type: model.Action, Label: "Retry", Payoff: &payoff}
+	want.Nodes["n4"] = &model.Node{ID: "n4", Type: model.IO, Label: "Shared"}
+	want.Nodes["orphan"] = &model.Node{ID: "orphan", Type: model.Action, Label: "Unused"}
+	want.Edges = []model.Edge{
+		{FromID: "n1", ToID: "n2"},
+		{FromID: "n2", ToID: "n3", Label: "no way", Probability: &p, Outcome: "> 3"},
+		{FromID: "n2", ToID: "n4", Label: "yes", Attrs: map[string]string{"p": "attr", "sla": "4h"}},
+		{FromID: "n3", ToID: "n2", Label: "again"},
+		{FromID: "n3", ToID: "n4"},
+	}
+	want.Counter = 4
+
+	text := FormatDTree(want)
+	got, err := ParseDTree(text)
+	if err != nil {
+		t.Fatalf("ParseDTree: %v\n%s", err, text)
+	}
+	assertSameTree(t, got, want)
+	if again := FormatDTree(got); again != text {
+		t.Errorf("formatting is not stable.\nFirst:\n%s\nSecond:\n%s", text, again)
+	}
+	for _, want := range []string{"graph\n", "-> @n2", "-> @n4", `[condition="user.name == \"bob\""`, "meta.payoff=x", `action "Unused" #orphan`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestParseDTreeImplicitIDs(t *testing.T) {
+	src := `# a hand-written tree
+decision "Raining?"
+  yes -> action "Umbrella" #n2
+  no -> "maybe later" -> action "Walk"   # arrow inside a label is fine when quoted
+`
+	if _, err := ParseDTree(src); err == nil {
+		t.Fatal("expected error for two arrows")
+	}
+	src = `decision Raining?
+  yes -> action "Umbrella" #n2
+  "not really" -> action "Walk"
+    -> startend "Done"
+`
+	tr, err := ParseDTree(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Implicit IDs are numbered after the highest explicit one.
+	if tr.RootID != "n3" || tr.Nodes["n3"].Label != "Raining?" {
+		t.Errorf("root = %q", tr.RootID)
+	}
+	if n := tr.Nodes["n4"]; n == nil || n.Label != "Walk" {
+		t.Errorf("n4 = %+v, want Walk", n)
+	}
+	if e := tr.GetEdge("n3", "n4"); e == nil || e.Label != "not really" {
+		t.Errorf("edge n3->n4 = %+v", e)
+	}
+	if e := tr.GetEdge("n4", "n5"); e == nil || e.Label != "" {
+		t.Errorf("edge n4->n5 = %+v", e)
+	}
+	if tr.Counter != 5 {
+		t.Errorf("Counter = %d, want 5", tr.Counter)
+	}
+}
+
+func TestParseDTreeRootDirective(t *testing.T) {
+	tr, err := ParseDTree("root @b\naction A #a\naction B #b\n")
+	if err != nil || tr.RootID != "b" {
+		t.Errorf("root = %q, %v", tr.RootID, err)
+	}
+	tr, err = ParseDTree("root none\naction A #a\n")
+	if err != nil || tr.RootID != "" {
+		t.Errorf("root = %q, %v", tr.RootID, err)
+	}
+}
+
+func TestDTreeRoundTripQuotedIDs(t *testing.T) {
+	// Imported diagrams can have IDs that are not bare words.
+	want := model.NewTree("odd ids")
+	want.Graph = true
+	want.RootID = "start here"
+	ids := []string{"start here", "a#b", "x@y", `say "hi"`, "[1]", "k=v", "a->b"}
+	for _, id := range ids {
+		want.Nodes[id] = &model.Node{ID: id, Type: model.Action, Label: id}
+	}
+	for _, id := range ids[1:] {
+		want.Edges = append(want.Edges, model.Edge{FromID: "start here", ToID: id})
+	}
+	want.Edges = append(want.Edges, model.Edge{FromID: "a#b", ToID: "x@y"})
+
+	text := FormatDTree(want)
+	got, err := ParseDTree(text)
+	if err != nil {
+		t.Fatalf("ParseDTree: %v\n%s", err, text)
+	}
+	got.Counter = want.Counter
+	assertSameTree(t, got, want)
+	for _, want := range []string{`#"start here"`, "\n  @\"x@y\"\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestParseDTreeErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"action A\n  yes -> @n9\n", "line 2: unknown node @n9"},
+		{"action A #a\naction B #a\n", "line 2: duplicate ID #a (first used on line 1)"},
+		{"yes -> action A\n", "line 1: a top-level node cannot have an incoming branch"},
+		{"action A #a\n  -> @a\n", "line 1: #a is part of a loop"},
+		{"action A #a\n  action B #b\naction C\n  -> @b\n", "line 2: #b has 2 parents"},
+		{"action A\n  -> @n1\n    action B\n", "line 3: @n1 is a reference"},
+		{"shape A\n", `line 1: unknown node type: "shape"`},
+		{"action\n", "line 1: expected a label after action"},
+		{"action A [payoff=lots]\n", `line 1: payoff "lots" is not a number`},
+		{"action A [payoff=NaN]\n", `line 1: payoff "NaN" is not a number`},
+		{"action A\n  [p=nan] -> action B\n", "line 2: probability"},
+		{"action A\n  [p=2] -> action B\n", "line 2: probability"},
+		{"action \"A\n", "line 1: unterminated string"},
+		{"action A [x=1\n", "line 1: missing ]"},
+		{"root n1\n", "line 1: expected root @<id> or root none"},
+		{"root @zz\naction A\n", "line 1: unknown root @zz"},
+	}
+	for _, tt := range tests {
+		_, err := ParseDTree(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseDTree(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestSaveAndLoadDTree(t *testing.T) {
+	dir := t.TempDir()
+	tree, err := Load(filepath.Join(testdataDir(), "sample-tree.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "auth.dtree")
+	if err := Save(tree, path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), `tree "auth-flow"`) {
+		t.Errorf("saved file is not .dtree text:\n%s", data)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameTree(t, loaded, tree)
+
+	unnamed := filepath.Join(dir, "my-flow.dtree")
+	os.WriteFile(unnamed, []byte("action Go\n"), 0644)
+	if loaded, err := Load(unnamed); err != nil || loaded.Name != "my-flow" {
+		t.Errorf("name = %v, %v; want my-flow", loaded, err)
+	}
+	os.WriteFile(unnamed, []byte("action\n"), 0644)
+	if _, err := Load(unnamed); err == nil || !strings.Contains(err.Error(), "my-flow.dtree: line 1") {
+		t.Errorf("error = %v", err)
+	}
+}
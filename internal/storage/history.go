@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+// ErrStaleHistory is returned by LoadHistory when the tree file has changed
+// since its history was saved, so the history no longer applies to it.
+var ErrStaleHistory = errors.New("history is out of date with the tree file")
+
+// historyFile is the undo journal kept next to a tree file. TreeSum is the
+// checksum of the tree file the history was saved with.
+type historyFile struct {
+	TreeSum string        `json:"tree_sha256"`
+	History *tree.History `json:"history"`
+}
+
+// HistoryPath returns the path of the undo journal for a tree file.
+func HistoryPath(path string) string {
+	return path + ".history"
+}
+
+// SaveHistory writes h as the journal of the tree file at path, which must
+// already be saved. An empty history removes the journal instead.
+func SaveHistory(h *tree.History, path string) error {
+	if !h.CanUndo() && !h.CanRedo() {
+		if err := os.Remove(HistoryPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove history: %w", err)
+		}
+		return nil
+	}
+	sum, err := fileSum(path)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(historyFile{TreeSum: sum, History: h}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal history: %w", err)
+	}
+	if err := os.WriteFile(HistoryPath(path), data, 0644); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory reads the journal of the tree file at path. It returns nil
+// and no error when there is no journal, and ErrStaleHistory when the tree
+// file was changed without it.
+func LoadHistory(path string) (*tree.History, error) {
+	data, err := os.ReadFile(HistoryPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	f := historyFile{History: tree.NewHistory()}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("unmarshal history: %w", err)
+	}
+	sum, err := fileSum(path)
+	if err != nil {
+		return nil, err
+	}
+	if sum != f.TreeSum {
+		return nil, ErrStaleHistory
+	}
+	return f.History, nil
+}
+
+func fileSum(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+	"github.com/jllovet/decision-tree-cli/internal/tree"
+)
+
+func TestSaveAndLoadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.dtree")
+	tr := model.NewTree("flow")
+	h := tree.NewHistory()
+	h.Execute(tr, tree.NewAddNodeCmd(model.Decision, "q1"))
+	h.Execute(tr, tree.NewSetRootCmd("n1"))
+	h.Execute(tr, tree.NewAddNodeCmd(model.Action, "a1"))
+	h.Undo(tr)
+
+	if err := Save(tr, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveHistory(h, path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, undone := restored.Entries(); len(entries) != 3 || undone != 1 {
+		t.Fatalf("restored %d entries, %d undone", len(entries), undone)
+	}
+	restored.Undo(loaded)
+	restored.Undo(loaded)
+	if len(loaded.Nodes) != 0 || loaded.RootID != "" {
+		t.Errorf("undo after reload left %d nodes, root %q", len(loaded.Nodes), loaded.RootID)
+	}
+}
+
+func TestLoadHistoryMissingOrStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.json")
+	tr := model.NewTree("flow")
+	h := tree.NewHistory()
+	h.Execute(tr, tree.NewAddNodeCmd(model.Decision, "q1"))
+	Save(tr, path)
+
+	if h, err := LoadHistory(path); h != nil || err != nil {
+		t.Errorf("no journal: got %v, %v", h, err)
+	}
+
+	SaveHistory(h, path)
+	tr.Name = "edited elsewhere"
+	Save(tr, path)
+	if _, err := LoadHistory(path); !errors.Is(err, ErrStaleHistory) {
+		t.Errorf("err = %v, want ErrStaleHistory", err)
+	}
+}
+
+func TestSaveEmptyHistoryRemovesJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.json")
+	tr := model.NewTree("flow")
+	h := tree.NewHistory()
+	h.Execute(tr, tree.NewAddNodeCmd(model.Decision, "q1"))
+	Save(tr, path)
+	SaveHistory(h, path)
+	if _, err := os.Stat(HistoryPath(path)); err != nil {
+		t.Fatalf("journal not written: %v", err)
+	}
+
+	if err := SaveHistory(tree.NewHistory(), path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(HistoryPath(path)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("journal should be removed, stat err = %v", err)
+	}
+}
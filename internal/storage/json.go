@@ -4,15 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/jllovet/decision-tree-cli/internal/model"
 )
 
-// Save writes the tree to a JSON file.
+// Save writes the tree to a file: .dtree text for paths ending in .dtree,
+// JSON otherwise.
 func Save(tree *model.Tree, path string) error {
-	data, err := json.MarshalIndent(tree, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
+	var data []byte
+	if IsDTree(path) {
+		data = []byte(FormatDTree(tree))
+	} else {
+		var err error
+		if data, err = json.MarshalIndent(tree, "", "  "); err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
 	}
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("write: %w", err)
@@ -20,12 +28,23 @@ func Save(tree *model.Tree, path string) error {
 	return nil
 }
 
-// Load reads a tree from a JSON file and validates it.
+// Load reads a tree from a .dtree or JSON file and validates it. A .dtree
+// file without a "tree" line is named after the file.
 func Load(path string) (*model.Tree, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read: %w", err)
 	}
+	if IsDTree(path) {
+		tree, err := ParseDTree(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+		}
+		if tree.Name == "" {
+			tree.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		return tree, nil
+	}
 	var tree model.Tree
 	if err := json.Unmarshal(data, &tree); err != nil {
 		return nil, fmt.Errorf("unmarshal: %w", err)
@@ -38,3 +57,8 @@ func Load(path string) (*model.Tree, error) {
 	}
 	return &tree, nil
 }
+
+// IsDTree reports whether a path names a .dtree file.
+func IsDTree(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".dtree")
+}
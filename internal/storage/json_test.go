@@ -45,6 +45,33 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestSaveAndLoadAttrs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attrs.json")
+
+	tree := model.NewTree("attrs")
+	tree.Nodes["n1"] = &model.Node{ID: "n1", Type: model.Action, Label: "a",
+		Attrs: map[string]string{"owner": "alice"}}
+	tree.Nodes["n2"] = &model.Node{ID: "n2", Type: model.Action, Label: "b"}
+	tree.Edges = []model.Edge{{FromID: "n1", ToID: "n2", Attrs: map[string]string{"sla": "4h"}}}
+
+	if err := Save(tree, path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := loaded.Nodes["n1"].Attrs["owner"]; got != "alice" {
+		t.Errorf("owner = %q, want alice", got)
+	}
+	if loaded.Nodes["n2"].Attrs != nil {
+		t.Errorf("n2 attrs = %v, want nil", loaded.Nodes["n2"].Attrs)
+	}
+	if got := loaded.Edges[0].Attrs["sla"]; got != "4h" {
+		t.Errorf("sla = %q, want 4h", got)
+	}
+}
+
 func TestLoadInvalidJSON(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "bad.json")
@@ -10,6 +10,8 @@ type Clipboard struct {
 }
 
 // CopySubtree performs a DFS deep-copy of a subtree rooted at nodeID.
+// Loop-back edges are copied but not followed, so in graph mode an edge
+// leaving the subtree is kept as a reference to the original node.
 func CopySubtree(t *model.Tree, nodeID string) (*Clipboard, error) {
 	node := t.GetNode(nodeID)
 	if node == nil {
@@ -17,6 +19,7 @@ func CopySubtree(t *model.Tree, nodeID string) (*Clipboard, error) {
 	}
 
 	cb := &Clipboard{Root: nodeID}
+	back := t.BackEdges()
 	visited := make(map[string]bool)
 	var dfs func(id string)
 	dfs = func(id string) {
@@ -28,10 +31,12 @@ func CopySubtree(t *model.Tree, nodeID string) (*Clipboard, error) {
 		if n == nil {
 			return
 		}
-		cb.Nodes = append(cb.Nodes, *n)
+		cb.Nodes = append(cb.Nodes, *n.Clone())
 		for _, e := range t.Children(id) {
-			cb.Edges = append(cb.Edges, e)
-			dfs(e.ToID)
+			cb.Edges = append(cb.Edges, e.Clone())
+			if !back[e.Key()] {
+				dfs(e.ToID)
+			}
 		}
 	}
 	dfs(nodeID)
@@ -39,31 +44,43 @@ func CopySubtree(t *model.Tree, nodeID string) (*Clipboard, error) {
 }
 
 // PasteSubtree pastes the clipboard contents into the tree, remapping IDs.
-// Returns a map from old IDs to new IDs.
+// Edges to nodes outside the copied subtree keep pointing at the original
+// node when it still exists and the tree is in graph mode; otherwise they
+// are dropped. Returns a map from old IDs to new IDs.
 func PasteSubtree(t *model.Tree, cb *Clipboard) map[string]string {
 	idMap := make(map[string]string)
+	for _, n := range cb.Nodes {
+		idMap[n.ID] = t.NextID()
+	}
+	pasteSubtreeAs(t, cb, idMap)
+	return idMap
+}
 
+// pasteSubtreeAs pastes the clipboard using the given old-to-new ID mapping.
+func pasteSubtreeAs(t *model.Tree, cb *Clipboard, idMap map[string]string) {
 	// Create new nodes with remapped IDs
 	for _, n := range cb.Nodes {
-		newID := t.NextID()
-		idMap[n.ID] = newID
-		t.Nodes[newID] = &model.Node{
-			ID:    newID,
-			Type:  n.Type,
-			Label: n.Label,
-		}
+		newID := idMap[n.ID]
+		pasted := n.Clone()
+		pasted.ID = newID
+		t.Nodes[newID] = pasted
+		t.ReserveID(newID)
 	}
 
 	// Create new edges with remapped IDs
 	for _, e := range cb.Edges {
-		t.Edges = append(t.Edges, model.Edge{
-			FromID: idMap[e.FromID],
-			ToID:   idMap[e.ToID],
-			Label:  e.Label,
-		})
+		toID, ok := idMap[e.ToID]
+		if !ok {
+			if !t.Graph || t.GetNode(e.ToID) == nil {
+				continue
+			}
+			toID = e.ToID
+		}
+		pasted := e.Clone()
+		pasted.FromID = idMap[e.FromID]
+		pasted.ToID = toID
+		t.Edges = append(t.Edges, pasted)
 	}
-
-	return idMap
 }
 
 func errNodeNotFound(id string) error {
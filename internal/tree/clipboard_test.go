@@ -75,3 +75,73 @@ func TestPasteSubtree(t *testing.T) {
 		t.Error("pasted edge not found")
 	}
 }
+
+func TestCopySubtreeWithLoop(t *testing.T) {
+	tr := model.NewTree("test")
+	tr.Graph = true
+	AddNode(tr, model.Decision, "q")   // n1
+	AddNode(tr, model.Action, "ok")    // n2
+	AddNode(tr, model.Action, "retry") // n3
+	tr.RootID = "n1"
+	ConnectNodes(tr, "n1", "n2", "yes")
+	ConnectNodes(tr, "n1", "n3", "no")
+	ConnectNodes(tr, "n3", "n1", "")
+
+	// The whole loop is inside the copied subtree.
+	cb, err := CopySubtree(tr, "n1")
+	if err != nil {
+		t.Fatalf("CopySubtree: %v", err)
+	}
+	if len(cb.Nodes) != 3 || len(cb.Edges) != 3 {
+		t.Fatalf("got %d nodes, %d edges; want 3, 3", len(cb.Nodes), len(cb.Edges))
+	}
+	idMap := PasteSubtree(tr, cb)
+	if !tr.HasEdge(idMap["n3"], idMap["n1"]) {
+		t.Error("loop should be remapped onto the pasted nodes")
+	}
+
+	// Copying only the retry step keeps its loop edge as a reference.
+	cb, _ = CopySubtree(tr, "n3")
+	if len(cb.Nodes) != 1 {
+		t.Fatalf("expected only the retry node to be copied, got %d nodes", len(cb.Nodes))
+	}
+	idMap = PasteSubtree(tr, cb)
+	if !tr.HasEdge(idMap["n3"], "n1") {
+		t.Error("pasted node should point back at the original n1")
+	}
+}
+
+func TestPasteSubtreeDropsExternalEdgesInTreeMode(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Action, "a")
+	cb := &Clipboard{
+		Root:  "x1",
+		Nodes: []model.Node{{ID: "x1", Type: model.Action, Label: "copy"}},
+		Edges: []model.Edge{{FromID: "x1", ToID: "n1"}},
+	}
+	idMap := PasteSubtree(tr, cb)
+	if tr.HasEdge(idMap["x1"], "n1") {
+		t.Error("external edge should be dropped outside graph mode")
+	}
+}
+
+func TestCopyPasteKeepsAttrs(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "q")
+	AddNode(tr, model.Action, "a")
+	ConnectNodes(tr, "n1", "n2", "yes")
+	SetNodeAttr(tr, "n1", "owner", "alice")
+	SetEdgeAttr(tr, "n1", "n2", "sla", "4h")
+
+	cb, _ := CopySubtree(tr, "n1")
+	// Later edits must not leak into the clipboard.
+	SetNodeAttr(tr, "n1", "owner", "bob")
+
+	idMap := PasteSubtree(tr, cb)
+	if got := tr.GetNode(idMap["n1"]).Attrs["owner"]; got != "alice" {
+		t.Errorf("pasted owner = %q, want alice", got)
+	}
+	if got := tr.GetEdge(idMap["n1"], idMap["n2"]).Attrs["sla"]; got != "4h" {
+		t.Errorf("pasted sla = %q, want 4h", got)
+	}
+}
@@ -1,6 +1,11 @@
 package tree
 
-import "github.com/jllovet/decision-tree-cli/internal/model"
+import (
+	"fmt"
+	"time"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
 
 // Command represents an undoable operation.
 type Command interface {
@@ -8,10 +13,21 @@ type Command interface {
 	Undo(t *model.Tree) error
 }
 
-// History manages undo/redo stacks.
+// History manages undo/redo stacks. Between Begin and Commit, executed
+// commands are collected into a single Batch instead of being pushed one by
+// one.
 type History struct {
-	undoStack []Command
-	redoStack []Command
+	undoStack []Entry
+	redoStack []Entry
+	pending   *Batch           // open transaction, nil outside Begin/Commit
+	now       func() time.Time // clock for entry times, time.Now when nil
+}
+
+// Entry is a command on the undo or redo stack and when it was last
+// applied.
+type Entry struct {
+	Cmd  Command
+	Time time.Time
 }
 
 // NewHistory creates a new History manager.
@@ -19,41 +35,129 @@ func NewHistory() *History {
 	return &History{}
 }
 
-// Execute runs a command and pushes it onto the undo stack.
+// Execute runs a command and pushes it onto the undo stack, or adds it to
+// the open transaction.
 func (h *History) Execute(t *model.Tree, cmd Command) error {
 	if err := cmd.Execute(t); err != nil {
 		return err
 	}
-	h.undoStack = append(h.undoStack, cmd)
+	if h.pending != nil {
+		h.pending.Cmds = append(h.pending.Cmds, cmd)
+		return nil
+	}
+	h.push(cmd)
+	return nil
+}
+
+func (h *History) push(cmd Command) {
+	h.undoStack = append(h.undoStack, Entry{Cmd: cmd, Time: h.clock()})
 	h.redoStack = nil // clear redo on new action
+}
+
+func (h *History) clock() time.Time {
+	if h.now != nil {
+		return h.now()
+	}
+	return time.Now()
+}
+
+// Begin opens a transaction. Commands executed until Commit or Rollback
+// still change the tree immediately, but are undone and redone as one step.
+func (h *History) Begin() error {
+	if h.pending != nil {
+		return errTransactionOpen
+	}
+	h.pending = &Batch{}
+	return nil
+}
+
+// Commit closes the open transaction, pushing its commands onto the undo
+// stack as one Batch. It returns the number of commands committed; an empty
+// transaction leaves the stacks alone.
+func (h *History) Commit() (int, error) {
+	if h.pending == nil {
+		return 0, errNoTransaction
+	}
+	b := h.pending
+	h.pending = nil
+	if len(b.Cmds) > 0 {
+		h.push(b)
+	}
+	return len(b.Cmds), nil
+}
+
+// Rollback closes the open transaction, undoing its commands in reverse
+// order. It returns the number of commands undone.
+func (h *History) Rollback(t *model.Tree) (int, error) {
+	if h.pending == nil {
+		return 0, errNoTransaction
+	}
+	b := h.pending
+	h.pending = nil
+	return len(b.Cmds), b.Undo(t)
+}
+
+// InTransaction reports whether a transaction is open.
+func (h *History) InTransaction() bool {
+	return h.pending != nil
+}
+
+// Atomic runs fn, which executes commands through h, as a single undoable
+// step. If fn returns an error the commands it ran are undone and the tree
+// is left as it was. Inside an open transaction the step joins it.
+func (h *History) Atomic(t *model.Tree, fn func() error) error {
+	outer := h.pending
+	h.pending = &Batch{}
+	err := fn()
+	b := h.pending
+	h.pending = outer
+	if err != nil {
+		if uerr := b.Undo(t); uerr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, uerr)
+		}
+		return err
+	}
+	switch {
+	case len(b.Cmds) == 0:
+	case outer != nil:
+		outer.Cmds = append(outer.Cmds, b)
+	default:
+		h.push(b)
+	}
 	return nil
 }
 
 // Undo undoes the last command.
 func (h *History) Undo(t *model.Tree) error {
+	if h.pending != nil {
+		return errTransactionOpen
+	}
 	if len(h.undoStack) == 0 {
 		return errNothingToUndo
 	}
-	cmd := h.undoStack[len(h.undoStack)-1]
+	e := h.undoStack[len(h.undoStack)-1]
 	h.undoStack = h.undoStack[:len(h.undoStack)-1]
-	if err := cmd.Undo(t); err != nil {
+	if err := e.Cmd.Undo(t); err != nil {
 		return err
 	}
-	h.redoStack = append(h.redoStack, cmd)
+	h.redoStack = append(h.redoStack, e)
 	return nil
 }
 
 // Redo re-applies the last undone command.
 func (h *History) Redo(t *model.Tree) error {
+	if h.pending != nil {
+		return errTransactionOpen
+	}
 	if len(h.redoStack) == 0 {
 		return errNothingToRedo
 	}
-	cmd := h.redoStack[len(h.redoStack)-1]
+	e := h.redoStack[len(h.redoStack)-1]
 	h.redoStack = h.redoStack[:len(h.redoStack)-1]
-	if err := cmd.Execute(t); err != nil {
+	if err := e.Cmd.Execute(t); err != nil {
 		return err
 	}
-	h.undoStack = append(h.undoStack, cmd)
+	h.undoStack = append(h.undoStack, Entry{Cmd: e.Cmd, Time: h.clock()})
 	return nil
 }
 
@@ -67,8 +171,52 @@ func (h *History) CanRedo() bool {
 	return len(h.redoStack) > 0
 }
 
+// Entries returns the commands that can be undone, oldest first, followed
+// by those that can be redone in the order they were first applied.
+// undone is the number of entries at the end that have been undone.
+func (h *History) Entries() (entries []Entry, undone int) {
+	entries = append(entries, h.undoStack...)
+	for i := len(h.redoStack) - 1; i >= 0; i-- {
+		entries = append(entries, h.redoStack[i])
+	}
+	return entries, len(h.redoStack)
+}
+
 // --- Concrete Commands ---
 
+// Batch is a compound command: its commands are executed in order and
+// undone in reverse, as one step. If a command fails, Execute undoes the
+// ones before it so the tree is left unchanged.
+type Batch struct {
+	Cmds []Command
+}
+
+// NewBatch returns a Batch of the given commands.
+func NewBatch(cmds ...Command) *Batch {
+	return &Batch{Cmds: cmds}
+}
+
+func (b *Batch) Execute(t *model.Tree) error {
+	for i, cmd := range b.Cmds {
+		if err := cmd.Execute(t); err != nil {
+			if uerr := (&Batch{Cmds: b.Cmds[:i]}).Undo(t); uerr != nil {
+				return fmt.Errorf("%w (rollback failed: %v)", err, uerr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Batch) Undo(t *model.Tree) error {
+	for i := len(b.Cmds) - 1; i >= 0; i-- {
+		if err := b.Cmds[i].Undo(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type addNodeCmd struct {
 	nodeType model.NodeType
 	label    string
@@ -80,6 +228,13 @@ func NewAddNodeCmd(nodeType model.NodeType, label string) Command {
 }
 
 func (c *addNodeCmd) Execute(t *model.Tree) error {
+	// Redo restores the same ID so later commands that refer to it
+	// (in a Batch, say) still apply.
+	if c.id != "" {
+		t.Nodes[c.id] = &model.Node{ID: c.id, Type: c.nodeType, Label: c.label}
+		t.ReserveID(c.id) // the counter may be lower after a reload
+		return nil
+	}
 	c.id = AddNode(t, c.nodeType, c.label)
 	return nil
 }
@@ -109,26 +264,25 @@ func (c *removeNodeCmd) Execute(t *model.Tree) error {
 	if n == nil {
 		return errNodeNotFound(c.id)
 	}
-	c.removedNode = *n
+	c.removedNode = *n.Clone()
 	c.wasRoot = t.RootID == c.id
 
 	// Save edges that will be removed
 	c.removedEdges = nil
 	for _, e := range t.Edges {
 		if e.FromID == c.id || e.ToID == c.id {
-			c.removedEdges = append(c.removedEdges, e)
+			c.removedEdges = append(c.removedEdges, e.Clone())
 		}
 	}
 	return RemoveNode(t, c.id)
 }
 
 func (c *removeNodeCmd) Undo(t *model.Tree) error {
-	t.Nodes[c.id] = &model.Node{
-		ID:    c.removedNode.ID,
-		Type:  c.removedNode.Type,
-		Label: c.removedNode.Label,
+	t.Nodes[c.id] = c.removedNode.Clone()
+	t.ReserveID(c.id)
+	for _, e := range c.removedEdges {
+		t.Edges = append(t.Edges, e.Clone())
 	}
-	t.Edges = append(t.Edges, c.removedEdges...)
 	if c.wasRoot {
 		t.RootID = c.id
 	}
@@ -153,7 +307,7 @@ func (c *connectCmd) Undo(t *model.Tree) error {
 
 type disconnectCmd struct {
 	fromID, toID string
-	label        string // saved for undo
+	removed      model.Edge // saved for undo
 }
 
 func NewDisconnectCmd(fromID, toID string) Command {
@@ -161,18 +315,56 @@ func NewDisconnectCmd(fromID, toID string) Command {
 }
 
 func (c *disconnectCmd) Execute(t *model.Tree) error {
-	// Save the label before removing
-	for _, e := range t.Edges {
-		if e.FromID == c.fromID && e.ToID == c.toID {
-			c.label = e.Label
-			break
-		}
+	// Save the edge (label and attributes) before removing
+	if e := t.GetEdge(c.fromID, c.toID); e != nil {
+		c.removed = e.Clone()
 	}
 	return DisconnectNodes(t, c.fromID, c.toID)
 }
 
 func (c *disconnectCmd) Undo(t *model.Tree) error {
-	return ConnectNodes(t, c.fromID, c.toID, c.label)
+	if err := ConnectNodes(t, c.fromID, c.toID, c.removed.Label); err != nil {
+		return err
+	}
+	t.Edges[len(t.Edges)-1] = c.removed.Clone()
+	return nil
+}
+
+type moveCmd struct {
+	id, newParentID string
+	label           *string
+	oldIndex        int        // index of the old parent edge, or -1
+	oldEdge         model.Edge // saved for undo
+}
+
+// NewMoveCmd returns a command that moves a node and its subtree under a
+// new parent. A nil label keeps the existing edge label.
+func NewMoveCmd(id, newParentID string, label *string) Command {
+	return &moveCmd{id: id, newParentID: newParentID, label: label}
+}
+
+func (c *moveCmd) Execute(t *model.Tree) error {
+	c.oldIndex = -1
+	for i, e := range t.Edges {
+		if e.ToID == c.id {
+			c.oldIndex = i
+			c.oldEdge = e.Clone()
+			break
+		}
+	}
+	return MoveNode(t, c.id, c.newParentID, c.label)
+}
+
+func (c *moveCmd) Undo(t *model.Tree) error {
+	if err := DisconnectNodes(t, c.newParentID, c.id); err != nil {
+		return err
+	}
+	if c.oldIndex >= 0 {
+		t.Edges = append(t.Edges, model.Edge{})
+		copy(t.Edges[c.oldIndex+1:], t.Edges[c.oldIndex:])
+		t.Edges[c.oldIndex] = c.oldEdge.Clone()
+	}
+	return nil
 }
 
 type editLabelCmd struct {
@@ -221,6 +413,121 @@ func (c *editTypeCmd) Undo(t *model.Tree) error {
 	return EditNodeType(t, c.id, c.oldType)
 }
 
+type setPayoffCmd struct {
+	id        string
+	newPayoff *float64
+	oldPayoff *float64
+}
+
+func NewSetPayoffCmd(id string, payoff *float64) Command {
+	return &setPayoffCmd{id: id, newPayoff: payoff}
+}
+
+func (c *setPayoffCmd) Execute(t *model.Tree) error {
+	n := t.GetNode(c.id)
+	if n == nil {
+		return errNodeNotFound(c.id)
+	}
+	c.oldPayoff = n.Payoff
+	return SetPayoff(t, c.id, c.newPayoff)
+}
+
+func (c *setPayoffCmd) Undo(t *model.Tree) error {
+	return SetPayoff(t, c.id, c.oldPayoff)
+}
+
+type setConditionCmd struct {
+	id      string
+	newCond string
+	oldCond string
+}
+
+func NewSetConditionCmd(id, cond string) Command {
+	return &setConditionCmd{id: id, newCond: cond}
+}
+
+func (c *setConditionCmd) Execute(t *model.Tree) error {
+	n := t.GetNode(c.id)
+	if n == nil {
+		return errNodeNotFound(c.id)
+	}
+	c.oldCond = n.Condition
+	return SetCondition(t, c.id, c.newCond)
+}
+
+func (c *setConditionCmd) Undo(t *model.Tree) error {
+	return SetCondition(t, c.id, c.oldCond)
+}
+
+type editEdgeLabelCmd struct {
+	fromID, toID string
+	newLabel     string
+	oldLabel     string
+}
+
+func NewEditEdgeLabelCmd(fromID, toID, label string) Command {
+	return &editEdgeLabelCmd{fromID: fromID, toID: toID, newLabel: label}
+}
+
+func (c *editEdgeLabelCmd) Execute(t *model.Tree) error {
+	e := t.GetEdge(c.fromID, c.toID)
+	if e == nil {
+		return fmt.Errorf("no edge from %s to %s", c.fromID, c.toID)
+	}
+	c.oldLabel = e.Label
+	return EditEdgeLabel(t, c.fromID, c.toID, c.newLabel)
+}
+
+func (c *editEdgeLabelCmd) Undo(t *model.Tree) error {
+	return EditEdgeLabel(t, c.fromID, c.toID, c.oldLabel)
+}
+
+type setProbabilityCmd struct {
+	fromID, toID string
+	newP         *float64
+	oldP         *float64
+}
+
+func NewSetProbabilityCmd(fromID, toID string, p *float64) Command {
+	return &setProbabilityCmd{fromID: fromID, toID: toID, newP: p}
+}
+
+func (c *setProbabilityCmd) Execute(t *model.Tree) error {
+	e := t.GetEdge(c.fromID, c.toID)
+	if e == nil {
+		return fmt.Errorf("no edge from %s to %s", c.fromID, c.toID)
+	}
+	c.oldP = e.Probability
+	return SetProbability(t, c.fromID, c.toID, c.newP)
+}
+
+func (c *setProbabilityCmd) Undo(t *model.Tree) error {
+	return SetProbability(t, c.fromID, c.toID, c.oldP)
+}
+
+type setOutcomeCmd struct {
+	fromID, toID string
+	newOutcome   string
+	oldOutcome   string
+}
+
+func NewSetOutcomeCmd(fromID, toID, outcome string) Command {
+	return &setOutcomeCmd{fromID: fromID, toID: toID, newOutcome: outcome}
+}
+
+func (c *setOutcomeCmd) Execute(t *model.Tree) error {
+	e := t.GetEdge(c.fromID, c.toID)
+	if e == nil {
+		return fmt.Errorf("no edge from %s to %s", c.fromID, c.toID)
+	}
+	c.oldOutcome = e.Outcome
+	return SetOutcome(t, c.fromID, c.toID, c.newOutcome)
+}
+
+func (c *setOutcomeCmd) Undo(t *model.Tree) error {
+	return SetOutcome(t, c.fromID, c.toID, c.oldOutcome)
+}
+
 type setRootCmd struct {
 	newRoot string
 	oldRoot string
@@ -240,6 +547,100 @@ func (c *setRootCmd) Undo(t *model.Tree) error {
 	return nil
 }
 
+// attrCmd sets or removes a metadata attribute on a node, or on an edge
+// when nodeID is empty.
+type attrCmd struct {
+	nodeID       string
+	fromID, toID string
+	key, value   string
+	remove       bool
+	oldValue     string // saved for undo
+	hadOld       bool
+}
+
+func NewSetNodeAttrCmd(id, key, value string) Command {
+	return &attrCmd{nodeID: id, key: key, value: value}
+}
+
+func NewRemoveNodeAttrCmd(id, key string) Command {
+	return &attrCmd{nodeID: id, key: key, remove: true}
+}
+
+func NewSetEdgeAttrCmd(fromID, toID, key, value string) Command {
+	return &attrCmd{fromID: fromID, toID: toID, key: key, value: value}
+}
+
+func NewRemoveEdgeAttrCmd(fromID, toID, key string) Command {
+	return &attrCmd{fromID: fromID, toID: toID, key: key, remove: true}
+}
+
+func (c *attrCmd) attrs(t *model.Tree) (map[string]string, error) {
+	if c.nodeID != "" {
+		n := t.GetNode(c.nodeID)
+		if n == nil {
+			return nil, errNodeNotFound(c.nodeID)
+		}
+		return n.Attrs, nil
+	}
+	e := t.GetEdge(c.fromID, c.toID)
+	if e == nil {
+		return nil, fmt.Errorf("no edge from %s to %s", c.fromID, c.toID)
+	}
+	return e.Attrs, nil
+}
+
+func (c *attrCmd) set(t *model.Tree, value string) error {
+	if c.nodeID != "" {
+		return SetNodeAttr(t, c.nodeID, c.key, value)
+	}
+	return SetEdgeAttr(t, c.fromID, c.toID, c.key, value)
+}
+
+func (c *attrCmd) unset(t *model.Tree) error {
+	if c.nodeID != "" {
+		return RemoveNodeAttr(t, c.nodeID, c.key)
+	}
+	return RemoveEdgeAttr(t, c.fromID, c.toID, c.key)
+}
+
+func (c *attrCmd) Execute(t *model.Tree) error {
+	attrs, err := c.attrs(t)
+	if err != nil {
+		return err
+	}
+	c.oldValue, c.hadOld = attrs[c.key]
+	if c.remove {
+		return c.unset(t)
+	}
+	return c.set(t, c.value)
+}
+
+func (c *attrCmd) Undo(t *model.Tree) error {
+	if c.hadOld {
+		return c.set(t, c.oldValue)
+	}
+	return c.unset(t)
+}
+
+type setModeCmd struct {
+	graph    bool
+	oldGraph bool
+}
+
+func NewSetModeCmd(graph bool) Command {
+	return &setModeCmd{graph: graph}
+}
+
+func (c *setModeCmd) Execute(t *model.Tree) error {
+	c.oldGraph = t.Graph
+	return SetGraphMode(t, c.graph)
+}
+
+func (c *setModeCmd) Undo(t *model.Tree) error {
+	t.Graph = c.oldGraph
+	return nil
+}
+
 type pasteSubtreeCmd struct {
 	clipboard *Clipboard
 	idMap     map[string]string
@@ -250,6 +651,11 @@ func NewPasteSubtreeCmd(cb *Clipboard) Command {
 }
 
 func (c *pasteSubtreeCmd) Execute(t *model.Tree) error {
+	// Redo reuses the IDs of the first paste.
+	if c.idMap != nil {
+		pasteSubtreeAs(t, c.clipboard, c.idMap)
+		return nil
+	}
 	c.idMap = PasteSubtree(t, c.clipboard)
 	return nil
 }
@@ -275,4 +681,7 @@ func (e sentinelError) Error() string { return string(e) }
 const (
 	errNothingToUndo sentinelError = "nothing to undo"
 	errNothingToRedo sentinelError = "nothing to redo"
+
+	errTransactionOpen sentinelError = "a transaction is open (commit or rollback it first)"
+	errNoTransaction   sentinelError = "no transaction is open"
 )
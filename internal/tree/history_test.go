@@ -117,6 +117,63 @@ func TestDisconnectCommand(t *testing.T) {
 	}
 }
 
+func TestMoveCommand(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "a"))
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "b"))
+	h.Execute(tr, NewAddNodeCmd(model.Action, "c"))
+	h.Execute(tr, NewAddNodeCmd(model.Action, "d"))
+	h.Execute(tr, NewConnectCmd("n1", "n2", "left"))
+	h.Execute(tr, NewConnectCmd("n1", "n3", "right"))
+	h.Execute(tr, NewConnectCmd("n2", "n4", "only"))
+
+	label := "moved"
+	if err := h.Execute(tr, NewMoveCmd("n2", "n3", &label)); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if e := tr.GetEdge("n3", "n2"); e == nil || e.Label != "moved" {
+		t.Fatalf("moved edge = %+v", e)
+	}
+	if !tr.HasEdge("n2", "n4") {
+		t.Error("subtree should move with the node")
+	}
+
+	h.Undo(tr)
+	if tr.HasEdge("n3", "n2") {
+		t.Error("new edge should be removed on undo")
+	}
+	if tr.Edges[0].FromID != "n1" || tr.Edges[0].ToID != "n2" || tr.Edges[0].Label != "left" {
+		t.Errorf("edge order not restored: %+v", tr.Edges)
+	}
+
+	h.Redo(tr)
+	if e := tr.GetEdge("n3", "n2"); e == nil || e.Label != "moved" {
+		t.Errorf("redo edge = %+v", e)
+	}
+}
+
+func TestMoveCommandRejectsCycle(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "a"))
+	h.Execute(tr, NewAddNodeCmd(model.Action, "b"))
+	h.Execute(tr, NewConnectCmd("n1", "n2", ""))
+
+	if err := h.Execute(tr, NewMoveCmd("n1", "n2", nil)); err == nil {
+		t.Fatal("expected cycle error")
+	}
+	if !tr.HasEdge("n1", "n2") || len(tr.Edges) != 1 {
+		t.Errorf("tree changed by failed move: %+v", tr.Edges)
+	}
+	h.Undo(tr)
+	if tr.HasEdge("n1", "n2") {
+		t.Error("undo should revert the connect, not the failed move")
+	}
+}
+
 func TestEditLabelCommand(t *testing.T) {
 	tr := model.NewTree("test")
 	h := NewHistory()
@@ -196,6 +253,36 @@ func TestPasteSubtreeCommand(t *testing.T) {
 	}
 }
 
+func TestRedoReservesIDs(t *testing.T) {
+	// Reloading a tree rebuilds the counter from the IDs it holds, so
+	// redone nodes may be above it.
+	tr := model.NewTree("test")
+	h := NewHistory()
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "root"))
+	h.Execute(tr, NewAddNodeCmd(model.Action, "child"))
+	cb, _ := CopySubtree(tr, "n2")
+	h.Execute(tr, NewPasteSubtreeCmd(cb))
+	h.Undo(tr)
+	h.Undo(tr)
+
+	tr.Counter = 1
+	h.Redo(tr)
+	h.Redo(tr)
+	if tr.Counter != 3 {
+		t.Errorf("Counter = %d after redo, want 3", tr.Counter)
+	}
+	if id := AddNode(tr, model.Action, "new"); id != "n4" {
+		t.Errorf("AddNode = %s, want n4", id)
+	}
+
+	h.Execute(tr, NewRemoveNodeCmd("n4"))
+	tr.Counter = 3
+	h.Undo(tr)
+	if tr.Counter != 4 {
+		t.Errorf("Counter = %d after undoing a remove, want 4", tr.Counter)
+	}
+}
+
 func TestRemoveNodeCommand(t *testing.T) {
 	tr := model.NewTree("test")
 	h := NewHistory()
@@ -224,3 +311,254 @@ func TestRemoveNodeCommand(t *testing.T) {
 		t.Error("edge should be restored")
 	}
 }
+
+func TestSetModeCommand(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+
+	if err := h.Execute(tr, NewSetModeCmd(true)); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !tr.Graph {
+		t.Fatal("tree should be in graph mode")
+	}
+	h.Undo(tr)
+	if tr.Graph {
+		t.Error("undo should restore tree mode")
+	}
+	h.Redo(tr)
+	if !tr.Graph {
+		t.Error("redo should restore graph mode")
+	}
+}
+
+func TestAttrCommandsUndoRedo(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "q"))
+	h.Execute(tr, NewAddNodeCmd(model.Action, "a"))
+	h.Execute(tr, NewConnectCmd("n1", "n2", "yes"))
+
+	h.Execute(tr, NewSetNodeAttrCmd("n1", "owner", "alice"))
+	h.Execute(tr, NewSetNodeAttrCmd("n1", "owner", "bob"))
+	if got := tr.GetNode("n1").Attrs["owner"]; got != "bob" {
+		t.Fatalf("owner = %q, want bob", got)
+	}
+	h.Undo(tr)
+	if got := tr.GetNode("n1").Attrs["owner"]; got != "alice" {
+		t.Errorf("owner after undo = %q, want alice", got)
+	}
+
+	h.Execute(tr, NewRemoveNodeAttrCmd("n1", "owner"))
+	if _, ok := tr.GetNode("n1").Attrs["owner"]; ok {
+		t.Error("owner should be removed")
+	}
+	h.Undo(tr)
+	if got := tr.GetNode("n1").Attrs["owner"]; got != "alice" {
+		t.Errorf("owner after undoing remove = %q, want alice", got)
+	}
+
+	h.Execute(tr, NewSetEdgeAttrCmd("n1", "n2", "sla", "4h"))
+	h.Undo(tr)
+	if _, ok := tr.GetEdge("n1", "n2").Attrs["sla"]; ok {
+		t.Error("undo should remove newly set edge attribute")
+	}
+	h.Redo(tr)
+	if got := tr.GetEdge("n1", "n2").Attrs["sla"]; got != "4h" {
+		t.Errorf("sla after redo = %q, want 4h", got)
+	}
+
+	if err := h.Execute(tr, NewRemoveEdgeAttrCmd("n1", "n2", "missing")); err == nil {
+		t.Error("expected error removing missing attribute")
+	}
+}
+
+func TestRemoveAndDisconnectRestoreAttrs(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "q"))
+	h.Execute(tr, NewAddNodeCmd(model.Action, "a"))
+	h.Execute(tr, NewConnectCmd("n1", "n2", "yes"))
+	SetNodeAttr(tr, "n2", "owner", "alice")
+	SetEdgeAttr(tr, "n1", "n2", "sla", "4h")
+
+	h.Execute(tr, NewDisconnectCmd("n1", "n2"))
+	h.Undo(tr)
+	if e := tr.GetEdge("n1", "n2"); e == nil || e.Label != "yes" || e.Attrs["sla"] != "4h" {
+		t.Errorf("edge after undoing disconnect = %+v", e)
+	}
+
+	h.Execute(tr, NewRemoveNodeCmd("n2"))
+	h.Undo(tr)
+	if got := tr.GetNode("n2").Attrs["owner"]; got != "alice" {
+		t.Errorf("owner after undoing remove = %q, want alice", got)
+	}
+	if got := tr.GetEdge("n1", "n2").Attrs["sla"]; got != "4h" {
+		t.Errorf("sla after undoing remove = %q, want 4h", got)
+	}
+}
+
+func TestEdgeAndPayoffCommandsUndo(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "q"))
+	h.Execute(tr, NewAddNodeCmd(model.StartEnd, "end"))
+	h.Execute(tr, NewConnectCmd("n1", "n2", "Yes"))
+
+	v := -5.0
+	h.Execute(tr, NewSetPayoffCmd("n2", &v))
+	h.Undo(tr)
+	if tr.GetNode("n2").Payoff != nil {
+		t.Error("undo should clear payoff")
+	}
+
+	p := 0.5
+	h.Execute(tr, NewSetProbabilityCmd("n1", "n2", &p))
+	h.Undo(tr)
+	if tr.GetEdge("n1", "n2").Probability != nil {
+		t.Error("undo should clear probability")
+	}
+
+	h.Execute(tr, NewEditEdgeLabelCmd("n1", "n2", "yes"))
+	h.Undo(tr)
+	if got := tr.GetEdge("n1", "n2").Label; got != "Yes" {
+		t.Errorf("label after undo = %q, want Yes", got)
+	}
+	h.Redo(tr)
+	if got := tr.GetEdge("n1", "n2").Label; got != "yes" {
+		t.Errorf("label after redo = %q, want yes", got)
+	}
+}
+
+func TestConditionAndOutcomeCommandsUndo(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "q"))
+	h.Execute(tr, NewAddNodeCmd(model.StartEnd, "end"))
+	h.Execute(tr, NewConnectCmd("n1", "n2", "Yes"))
+
+	h.Execute(tr, NewSetConditionCmd("n1", "score > 3"))
+	h.Execute(tr, NewSetOutcomeCmd("n1", "n2", "true"))
+	h.Undo(tr)
+	if tr.GetEdge("n1", "n2").Outcome != "" {
+		t.Error("undo should clear outcome")
+	}
+	h.Undo(tr)
+	if tr.GetNode("n1").Condition != "" {
+		t.Error("undo should clear condition")
+	}
+	h.Redo(tr)
+	if got := tr.GetNode("n1").Condition; got != "score > 3" {
+		t.Errorf("condition after redo = %q", got)
+	}
+	if err := h.Execute(tr, NewSetConditionCmd("n1", "(")); err == nil {
+		t.Error("expected error for unparseable condition")
+	}
+}
+
+func TestBatchRollsBackOnFailure(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "a")
+	AddNode(tr, model.Action, "b")
+
+	b := NewBatch(
+		NewConnectCmd("n1", "n2", "yes"),
+		NewEditLabelCmd("n2", "renamed"),
+		NewConnectCmd("n1", "n9", ""),
+	)
+	if err := NewHistory().Execute(tr, b); err == nil {
+		t.Fatal("expected error from the failing step")
+	}
+	if tr.HasEdge("n1", "n2") || tr.Nodes["n2"].Label != "b" {
+		t.Errorf("partial batch not rolled back: edges %+v, label %q", tr.Edges, tr.Nodes["n2"].Label)
+	}
+}
+
+func TestHistoryTransaction(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+
+	if err := h.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Begin(); err == nil {
+		t.Error("expected error for nested Begin")
+	}
+	add := NewAddNodeCmd(model.Decision, "a")
+	h.Execute(tr, add)
+	h.Execute(tr, NewAddNodeCmd(model.Action, "b"))
+	h.Execute(tr, NewConnectCmd("n1", "n2", ""))
+	if err := h.Undo(tr); err == nil {
+		t.Error("undo should fail while a transaction is open")
+	}
+	if n, err := h.Commit(); err != nil || n != 3 {
+		t.Fatalf("Commit = %d, %v", n, err)
+	}
+
+	if err := h.Undo(tr); err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Nodes) != 0 || len(tr.Edges) != 0 {
+		t.Fatalf("one undo should revert the whole transaction: %d nodes", len(tr.Nodes))
+	}
+	if h.CanUndo() {
+		t.Error("undo stack should be empty")
+	}
+	if err := h.Redo(tr); err != nil {
+		t.Fatalf("Redo: %v", err)
+	}
+	if !tr.HasEdge("n1", "n2") {
+		t.Error("redo should reuse the node IDs")
+	}
+
+	h.Begin()
+	h.Execute(tr, NewEditLabelCmd("n1", "changed"))
+	h.Execute(tr, NewDisconnectCmd("n1", "n2"))
+	if n, err := h.Rollback(tr); err != nil || n != 2 {
+		t.Fatalf("Rollback = %d, %v", n, err)
+	}
+	if tr.Nodes["n1"].Label != "a" || !tr.HasEdge("n1", "n2") || h.InTransaction() {
+		t.Error("rollback should restore the tree and close the transaction")
+	}
+	if _, err := h.Commit(); err == nil {
+		t.Error("expected error committing without a transaction")
+	}
+}
+
+func TestHistoryAtomic(t *testing.T) {
+	tr := model.NewTree("test")
+	h := NewHistory()
+	h.Execute(tr, NewAddNodeCmd(model.Decision, "root"))
+
+	err := h.Atomic(tr, func() error {
+		add := NewAddNodeCmd(model.Action, "child")
+		if err := h.Execute(tr, add); err != nil {
+			return err
+		}
+		return h.Execute(tr, NewConnectCmd("n1", add.(*addNodeCmd).ID(), ""))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Undo(tr)
+	if len(tr.Nodes) != 1 {
+		t.Errorf("one undo should remove the child and its edge, %d nodes left", len(tr.Nodes))
+	}
+
+	err = h.Atomic(tr, func() error {
+		if err := h.Execute(tr, NewAddNodeCmd(model.Action, "orphan")); err != nil {
+			return err
+		}
+		return h.Execute(tr, NewConnectCmd("n1", "n9", ""))
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(tr.Nodes) != 1 {
+		t.Errorf("failed step left an orphan: %d nodes", len(tr.Nodes))
+	}
+	h.Undo(tr)
+	if len(tr.Nodes) != 0 {
+		t.Error("the failed step should not be on the undo stack")
+	}
+}
@@ -0,0 +1,306 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// journalVersion is the version of the JSON form of a History. Journals
+// with another version are rejected rather than misread.
+const journalVersion = 1
+
+// journal is the JSON form of a History. Both stacks are stored bottom
+// first, so the last redo entry is the next one to redo.
+type journal struct {
+	Version int            `json:"version"`
+	Undo    []journalEntry `json:"undo"`
+	Redo    []journalEntry `json:"redo,omitempty"`
+}
+
+type journalEntry struct {
+	Time time.Time     `json:"time"`
+	Cmd  commandRecord `json:"cmd"`
+}
+
+// commandRecord is the JSON form of a command: the operation, the
+// arguments it was created with and the state it saved for Undo. Each
+// operation uses only some of the fields.
+type commandRecord struct {
+	Op        string            `json:"op"`
+	ID        string            `json:"id,omitempty"`
+	From      string            `json:"from,omitempty"`
+	To        string            `json:"to,omitempty"`
+	Type      model.NodeType    `json:"type,omitempty"`
+	OldType   model.NodeType    `json:"old_type,omitempty"`
+	Label     *string           `json:"label,omitempty"`
+	OldLabel  string            `json:"old_label,omitempty"`
+	Key       string            `json:"key,omitempty"`
+	Value     string            `json:"value,omitempty"`
+	OldValue  string            `json:"old_value,omitempty"`
+	HadOld    bool              `json:"had_old,omitempty"`
+	Remove    bool              `json:"remove,omitempty"`
+	Number    *float64          `json:"number,omitempty"`
+	OldNumber *float64          `json:"old_number,omitempty"`
+	Graph     bool              `json:"graph,omitempty"`
+	OldGraph  bool              `json:"old_graph,omitempty"`
+	WasRoot   bool              `json:"was_root,omitempty"`
+	Index     int               `json:"index,omitempty"`
+	Node      *model.Node       `json:"node,omitempty"`
+	Edges     []model.Edge      `json:"edges,omitempty"`
+	Clipboard *Clipboard        `json:"clipboard,omitempty"`
+	IDMap     map[string]string `json:"id_map,omitempty"`
+	Cmds      []commandRecord   `json:"cmds,omitempty"`
+}
+
+// MarshalJSON encodes the undo and redo stacks. A History with an open
+// transaction cannot be encoded.
+func (h *History) MarshalJSON() ([]byte, error) {
+	if h.pending != nil {
+		return nil, errTransactionOpen
+	}
+	j := journal{Version: journalVersion, Undo: []journalEntry{}}
+	for _, stack := range []struct {
+		entries []Entry
+		out     *[]journalEntry
+	}{{h.undoStack, &j.Undo}, {h.redoStack, &j.Redo}} {
+		for _, e := range stack.entries {
+			rec, err := encodeCommand(e.Cmd)
+			if err != nil {
+				return nil, err
+			}
+			*stack.out = append(*stack.out, journalEntry{Time: e.Time, Cmd: rec})
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON replaces the stacks with those of an encoded History.
+func (h *History) UnmarshalJSON(data []byte) error {
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Version != journalVersion {
+		return fmt.Errorf("unsupported history version %d", j.Version)
+	}
+	decode := func(in []journalEntry) ([]Entry, error) {
+		var out []Entry
+		for i, je := range in {
+			cmd, err := decodeCommand(je.Cmd)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i+1, err)
+			}
+			out = append(out, Entry{Cmd: cmd, Time: je.Time})
+		}
+		return out, nil
+	}
+	undo, err := decode(j.Undo)
+	if err != nil {
+		return err
+	}
+	redo, err := decode(j.Redo)
+	if err != nil {
+		return err
+	}
+	h.undoStack, h.redoStack, h.pending = undo, redo, nil
+	return nil
+}
+
+func encodeCommand(cmd Command) (commandRecord, error) {
+	switch c := cmd.(type) {
+	case *addNodeCmd:
+		return commandRecord{Op: "add", ID: c.id, Type: c.nodeType, Label: &c.label}, nil
+	case *removeNodeCmd:
+		n := c.removedNode.Clone()
+		return commandRecord{Op: "remove", ID: c.id, Node: n, Edges: c.removedEdges, WasRoot: c.wasRoot}, nil
+	case *connectCmd:
+		return commandRecord{Op: "connect", From: c.fromID, To: c.toID, Label: &c.label}, nil
+	case *disconnectCmd:
+		return commandRecord{Op: "disconnect", From: c.fromID, To: c.toID, Edges: []model.Edge{c.removed}}, nil
+	case *moveCmd:
+		return commandRecord{Op: "move", ID: c.id, To: c.newParentID, Label: c.label,
+			Index: c.oldIndex, Edges: []model.Edge{c.oldEdge}}, nil
+	case *editLabelCmd:
+		return commandRecord{Op: "edit-label", ID: c.id, Label: &c.newLabel, OldLabel: c.oldLabel}, nil
+	case *editTypeCmd:
+		return commandRecord{Op: "edit-type", ID: c.id, Type: c.newType, OldType: c.oldType}, nil
+	case *setPayoffCmd:
+		return commandRecord{Op: "payoff", ID: c.id, Number: c.newPayoff, OldNumber: c.oldPayoff}, nil
+	case *editEdgeLabelCmd:
+		return commandRecord{Op: "edit-edge-label", From: c.fromID, To: c.toID, Label: &c.newLabel, OldLabel: c.oldLabel}, nil
+	case *setProbabilityCmd:
+		return commandRecord{Op: "probability", From: c.fromID, To: c.toID, Number: c.newP, OldNumber: c.oldP}, nil
+	case *setRootCmd:
+		return commandRecord{Op: "set-root", ID: c.newRoot, OldValue: c.oldRoot}, nil
+	case *attrCmd:
+		return commandRecord{Op: "attr", ID: c.nodeID, From: c.fromID, To: c.toID, Key: c.key, Value: c.value,
+			Remove: c.remove, OldValue: c.oldValue, HadOld: c.hadOld}, nil
+	case *setModeCmd:
+		return commandRecord{Op: "mode", Graph: c.graph, OldGraph: c.oldGraph}, nil
+	case *pasteSubtreeCmd:
+		return commandRecord{Op: "paste", Clipboard: c.clipboard, IDMap: c.idMap}, nil
+	case *setConditionCmd:
+		return commandRecord{Op: "condition", ID: c.id, Value: c.newCond, OldValue: c.oldCond}, nil
+	case *setOutcomeCmd:
+		return commandRecord{Op: "outcome", From: c.fromID, To: c.toID, Value: c.newOutcome, OldValue: c.oldOutcome}, nil
+	case *Batch:
+		rec := commandRecord{Op: "batch"}
+		for _, sub := range c.Cmds {
+			r, err := encodeCommand(sub)
+			if err != nil {
+				return commandRecord{}, err
+			}
+			rec.Cmds = append(rec.Cmds, r)
+		}
+		return rec, nil
+	}
+	return commandRecord{}, fmt.Errorf("cannot save command of type %T", cmd)
+}
+
+func decodeCommand(r commandRecord) (Command, error) {
+	label := ""
+	if r.Label != nil {
+		label = *r.Label
+	}
+	edge := model.Edge{}
+	if len(r.Edges) > 0 {
+		edge = r.Edges[0]
+	}
+	switch r.Op {
+	case "add":
+		return &addNodeCmd{id: r.ID, nodeType: r.Type, label: label}, nil
+	case "remove":
+		if r.Node == nil {
+			return nil, fmt.Errorf("remove %s: missing node", r.ID)
+		}
+		return &removeNodeCmd{id: r.ID, removedNode: *r.Node, removedEdges: r.Edges, wasRoot: r.WasRoot}, nil
+	case "connect":
+		return &connectCmd{fromID: r.From, toID: r.To, label: label}, nil
+	case "disconnect":
+		return &disconnectCmd{fromID: r.From, toID: r.To, removed: edge}, nil
+	case "move":
+		return &moveCmd{id: r.ID, newParentID: r.To, label: r.Label, oldIndex: r.Index, oldEdge: edge}, nil
+	case "edit-label":
+		return &editLabelCmd{id: r.ID, newLabel: label, oldLabel: r.OldLabel}, nil
+	case "edit-type":
+		return &editTypeCmd{id: r.ID, newType: r.Type, oldType: r.OldType}, nil
+	case "payoff":
+		return &setPayoffCmd{id: r.ID, newPayoff: r.Number, oldPayoff: r.OldNumber}, nil
+	case "edit-edge-label":
+		return &editEdgeLabelCmd{fromID: r.From, toID: r.To, newLabel: label, oldLabel: r.OldLabel}, nil
+	case "probability":
+		return &setProbabilityCmd{fromID: r.From, toID: r.To, newP: r.Number, oldP: r.OldNumber}, nil
+	case "set-root":
+		return &setRootCmd{newRoot: r.ID, oldRoot: r.OldValue}, nil
+	case "attr":
+		return &attrCmd{nodeID: r.ID, fromID: r.From, toID: r.To, key: r.Key, value: r.Value,
+			remove: r.Remove, oldValue: r.OldValue, hadOld: r.HadOld}, nil
+	case "mode":
+		return &setModeCmd{graph: r.Graph, oldGraph: r.OldGraph}, nil
+	case "paste":
+		if r.Clipboard == nil {
+			return nil, fmt.Errorf("paste: missing clipboard")
+		}
+		return &pasteSubtreeCmd{clipboard: r.Clipboard, idMap: r.IDMap}, nil
+	case "condition":
+		return &setConditionCmd{id: r.ID, newCond: r.Value, oldCond: r.OldValue}, nil
+	case "outcome":
+		return &setOutcomeCmd{fromID: r.From, toID: r.To, newOutcome: r.Value, oldOutcome: r.OldValue}, nil
+	case "batch":
+		b := &Batch{}
+		for _, sub := range r.Cmds {
+			cmd, err := decodeCommand(sub)
+			if err != nil {
+				return nil, err
+			}
+			b.Cmds = append(b.Cmds, cmd)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", r.Op)
+}
+
+// Describe returns a one-line summary of a command, in the words of the
+// REPL command that makes it.
+func Describe(cmd Command) string {
+	switch c := cmd.(type) {
+	case *addNodeCmd:
+		return fmt.Sprintf("add %s %q as %s", c.nodeType, c.label, c.id)
+	case *removeNodeCmd:
+		return "remove " + c.id
+	case *connectCmd:
+		return withLabel("connect "+c.fromID+" -> "+c.toID, c.label)
+	case *disconnectCmd:
+		return "disconnect " + c.fromID + " -> " + c.toID
+	case *moveCmd:
+		s := "move " + c.id + " under " + c.newParentID
+		if c.label != nil {
+			s = withLabel(s, *c.label)
+		}
+		return s
+	case *editLabelCmd:
+		return fmt.Sprintf("edit %s label %q", c.id, c.newLabel)
+	case *editTypeCmd:
+		return fmt.Sprintf("edit %s type %s", c.id, c.newType)
+	case *setPayoffCmd:
+		return fmt.Sprintf("edit %s payoff %s", c.id, formatOptional(c.newPayoff))
+	case *editEdgeLabelCmd:
+		return fmt.Sprintf("edit %s->%s label %q", c.fromID, c.toID, c.newLabel)
+	case *setProbabilityCmd:
+		return fmt.Sprintf("edit %s->%s prob %s", c.fromID, c.toID, formatOptional(c.newP))
+	case *setRootCmd:
+		return "set-root " + c.newRoot
+	case *attrCmd:
+		ref := c.nodeID
+		if ref == "" {
+			ref = c.fromID + "->" + c.toID
+		}
+		if c.remove {
+			return fmt.Sprintf("meta %s rm %s", ref, c.key)
+		}
+		return fmt.Sprintf("meta %s set %s %q", ref, c.key, c.value)
+	case *setModeCmd:
+		if c.graph {
+			return "mode graph"
+		}
+		return "mode tree"
+	case *pasteSubtreeCmd:
+		return fmt.Sprintf("paste %d nodes (root: %s -> %s)", len(c.clipboard.Nodes), c.clipboard.Root, c.idMap[c.clipboard.Root])
+	case *setConditionCmd:
+		return fmt.Sprintf("edit %s condition %s", c.id, orNone(c.newCond))
+	case *setOutcomeCmd:
+		return fmt.Sprintf("edit %s->%s outcome %s", c.fromID, c.toID, orNone(c.newOutcome))
+	case *Batch:
+		parts := make([]string, len(c.Cmds))
+		for i, sub := range c.Cmds {
+			parts[i] = Describe(sub)
+		}
+		return strings.Join(parts, "; ")
+	}
+	return fmt.Sprintf("%T", cmd)
+}
+
+func withLabel(s, label string) string {
+	if label == "" {
+		return s
+	}
+	return fmt.Sprintf("%s %q", s, label)
+}
+
+func formatOptional(f *float64) string {
+	if f == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%g", *f)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
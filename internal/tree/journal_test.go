@@ -0,0 +1,152 @@
+package tree
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jllovet/decision-tree-cli/internal/model"
+)
+
+// journalSession runs one command of every kind through a History and
+// returns the tree before and after.
+func journalSession(t *testing.T) (h *History, tr, before, after *model.Tree) {
+	t.Helper()
+	tr = model.NewTree("journal")
+	AddNode(tr, model.Decision, "q1")
+	AddNode(tr, model.Action, "a1")
+	AddNode(tr, model.Action, "a2")
+	ConnectNodes(tr, "n1", "n2", "yes")
+	SetEdgeAttr(tr, "n1", "n2", "sla", "4h")
+	before = tr.Clone()
+
+	h = NewHistory()
+	p := 0.25
+	label := "maybe"
+	cb, _ := CopySubtree(tr, "n1")
+	cmds := []Command{
+		NewAddNodeCmd(model.IO, "form"),
+		NewConnectCmd("n1", "n4", "no"),
+		NewEditLabelCmd("n2", "renamed"),
+		NewEditTypeCmd("n3", model.StartEnd),
+		NewSetPayoffCmd("n2", &p),
+		NewEditEdgeLabelCmd("n1", "n4", "nope"),
+		NewSetProbabilityCmd("n1", "n2", &p),
+		NewSetRootCmd("n1"),
+		NewSetNodeAttrCmd("n1", "owner", "ops"),
+		NewRemoveEdgeAttrCmd("n1", "n2", "sla"),
+		NewSetConditionCmd("n1", "x > 1"),
+		NewSetOutcomeCmd("n1", "n2", "true"),
+		NewMoveCmd("n3", "n4", &label),
+		NewDisconnectCmd("n1", "n2"),
+		NewSetModeCmd(true),
+		NewPasteSubtreeCmd(cb),
+		NewRemoveNodeCmd("n2"),
+		NewBatch(NewAddNodeCmd(model.Action, "b"), NewSetRootCmd("n1")),
+	}
+	for _, cmd := range cmds {
+		if err := h.Execute(tr, cmd); err != nil {
+			t.Fatalf("%s: %v", Describe(cmd), err)
+		}
+	}
+	h.Undo(tr) // leave one entry on the redo stack
+	after = tr.Clone()
+	return h, tr, before, after
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	h, tr, before, after := journalSession(t)
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewHistory()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	entries, undone := restored.Entries()
+	if len(entries) != 18 || undone != 1 {
+		t.Fatalf("restored %d entries, %d undone", len(entries), undone)
+	}
+
+	// Undo everything with the restored history and check that the tree
+	// goes back to where it started, then redo it all again.
+	for restored.CanUndo() {
+		if err := restored.Undo(tr); err != nil {
+			t.Fatalf("Undo: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(tr.Nodes, before.Nodes) || tr.RootID != before.RootID || tr.Graph != before.Graph {
+		t.Errorf("after undo: nodes %+v", tr.Nodes)
+	}
+	if len(tr.Edges) != 1 || !reflect.DeepEqual(tr.Edges[0], before.Edges[0]) {
+		t.Errorf("after undo: edges %+v", tr.Edges)
+	}
+	for i := 0; i < 17; i++ {
+		if err := restored.Redo(tr); err != nil {
+			t.Fatalf("Redo %d: %v", i+1, err)
+		}
+	}
+	if !reflect.DeepEqual(tr.Nodes, after.Nodes) || len(tr.Edges) != len(after.Edges) {
+		t.Errorf("after redo: nodes %+v, edges %+v", tr.Nodes, tr.Edges)
+	}
+}
+
+func TestJournalTimes(t *testing.T) {
+	at := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	h := &History{now: func() time.Time { return at }}
+	tr := model.NewTree("test")
+	h.Execute(tr, NewAddNodeCmd(model.Action, "a"))
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"time":"2026-10-15T09:30:00Z"`) {
+		t.Errorf("journal = %s", data)
+	}
+	restored := NewHistory()
+	json.Unmarshal(data, restored)
+	if entries, _ := restored.Entries(); !entries[0].Time.Equal(at) {
+		t.Errorf("time = %v", entries[0].Time)
+	}
+}
+
+func TestJournalErrors(t *testing.T) {
+	h := NewHistory()
+	h.Begin()
+	if _, err := json.Marshal(h); err == nil {
+		t.Error("expected error with a transaction open")
+	}
+	for _, bad := range []string{
+		`{"version": 2, "undo": []}`,
+		`{"version": 1, "undo": [{"cmd": {"op": "frobnicate"}}]}`,
+		`{"version": 1, "undo": [{"cmd": {"op": "remove", "id": "n1"}}]}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), NewHistory()); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	label := "later"
+	tests := []struct {
+		cmd  Command
+		want string
+	}{
+		{NewConnectCmd("n1", "n2", "yes"), `connect n1 -> n2 "yes"`},
+		{NewConnectCmd("n1", "n2", ""), "connect n1 -> n2"},
+		{NewMoveCmd("n3", "n2", &label), `move n3 under n2 "later"`},
+		{NewSetProbabilityCmd("n1", "n2", nil), "edit n1->n2 prob none"},
+		{NewRemoveEdgeAttrCmd("n1", "n2", "sla"), "meta n1->n2 rm sla"},
+		{NewBatch(NewSetRootCmd("n1"), NewSetModeCmd(true)), "set-root n1; mode graph"},
+	}
+	for _, tt := range tests {
+		if got := Describe(tt.cmd); got != tt.want {
+			t.Errorf("Describe = %q, want %q", got, tt.want)
+		}
+	}
+}
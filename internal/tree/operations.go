@@ -3,6 +3,7 @@ package tree
 import (
 	"fmt"
 
+	"github.com/jllovet/decision-tree-cli/internal/expr"
 	"github.com/jllovet/decision-tree-cli/internal/model"
 )
 
@@ -47,13 +48,16 @@ func ConnectNodes(t *model.Tree, fromID, toID, label string) error {
 	if t.HasEdge(fromID, toID) {
 		return fmt.Errorf("edge %s -> %s already exists", fromID, toID)
 	}
-	// Enforce single parent: check if target already has a parent
-	if p := t.Parent(toID); p != nil {
-		return fmt.Errorf("node %q already has parent %q", toID, p.FromID)
-	}
-	// Cycle detection: fromID must not be a descendant of toID
-	if wouldCreateCycle(t, fromID, toID) {
-		return fmt.Errorf("connecting %s -> %s would create a cycle", fromID, toID)
+	// Graph mode allows multiple parents and loop-back edges
+	if !t.Graph {
+		// Enforce single parent: check if target already has a parent
+		if p := t.Parent(toID); p != nil {
+			return fmt.Errorf("node %q already has parent %q", toID, p.FromID)
+		}
+		// Cycle detection: fromID must not be a descendant of toID
+		if wouldCreateCycle(t, fromID, toID) {
+			return fmt.Errorf("connecting %s -> %s would create a cycle", fromID, toID)
+		}
 	}
 
 	t.Edges = append(t.Edges, model.Edge{FromID: fromID, ToID: toID, Label: label})
@@ -78,6 +82,45 @@ func DisconnectNodes(t *model.Tree, fromID, toID string) error {
 	return fmt.Errorf("no edge from %s to %s", fromID, toID)
 }
 
+// MoveNode reparents a node, with everything below it, under newParentID.
+// The edge from the old parent moves with it, keeping its label,
+// probability and metadata; a non-nil label replaces the label. A node
+// without a parent is connected with an empty label. The edge becomes the
+// new parent's last. Moving a node under itself or its own subtree fails,
+// as would the cycle it creates.
+func MoveNode(t *model.Tree, id, newParentID string, label *string) error {
+	if _, ok := t.Nodes[id]; !ok {
+		return fmt.Errorf("node %q not found", id)
+	}
+	if _, ok := t.Nodes[newParentID]; !ok {
+		return fmt.Errorf("new parent %q not found", newParentID)
+	}
+	parents := t.Parents(id)
+	if len(parents) > 1 {
+		return fmt.Errorf("node %q has %d parents; disconnect all but one first", id, len(parents))
+	}
+	if id == newParentID {
+		return fmt.Errorf("cannot move %s under itself", id)
+	}
+	if t.Ancestors(newParentID)[id] {
+		return fmt.Errorf("cannot move %s under %s: %s is in its subtree", id, newParentID, newParentID)
+	}
+
+	e := model.Edge{ToID: id}
+	if len(parents) == 1 {
+		e = parents[0]
+		if err := DisconnectNodes(t, e.FromID, id); err != nil {
+			return err
+		}
+	}
+	e.FromID = newParentID
+	if label != nil {
+		e.Label = *label
+	}
+	t.Edges = append(t.Edges, e)
+	return nil
+}
+
 // EditNodeLabel changes the label of a node.
 func EditNodeLabel(t *model.Tree, id, label string) error {
 	n := t.GetNode(id)
@@ -98,6 +141,125 @@ func EditNodeType(t *model.Tree, id string, nodeType model.NodeType) error {
 	return nil
 }
 
+// SetPayoff sets or, when payoff is nil, clears the payoff of a node.
+func SetPayoff(t *model.Tree, id string, payoff *float64) error {
+	n := t.GetNode(id)
+	if n == nil {
+		return fmt.Errorf("node %q not found", id)
+	}
+	n.Payoff = payoff
+	return nil
+}
+
+// EditEdgeLabel changes the label of the edge between two nodes.
+func EditEdgeLabel(t *model.Tree, fromID, toID, label string) error {
+	e := t.GetEdge(fromID, toID)
+	if e == nil {
+		return fmt.Errorf("no edge from %s to %s", fromID, toID)
+	}
+	e.Label = label
+	return nil
+}
+
+// SetProbability sets or, when p is nil, clears the probability of the edge
+// between two nodes.
+func SetProbability(t *model.Tree, fromID, toID string, p *float64) error {
+	e := t.GetEdge(fromID, toID)
+	if e == nil {
+		return fmt.Errorf("no edge from %s to %s", fromID, toID)
+	}
+	if p != nil && !(*p >= 0 && *p <= 1) { // also catches NaN
+		return fmt.Errorf("probability %g is outside [0, 1]", *p)
+	}
+	e.Probability = p
+	return nil
+}
+
+// SetCondition sets or, when cond is empty, clears a node's condition. The
+// condition must parse as an expression.
+func SetCondition(t *model.Tree, id, cond string) error {
+	n := t.GetNode(id)
+	if n == nil {
+		return fmt.Errorf("node %q not found", id)
+	}
+	if cond != "" {
+		if _, err := expr.Parse(cond); err != nil {
+			return err
+		}
+	}
+	n.Condition = cond
+	return nil
+}
+
+// SetOutcome sets or, when outcome is empty, clears the outcome of the edge
+// between two nodes.
+func SetOutcome(t *model.Tree, fromID, toID, outcome string) error {
+	e := t.GetEdge(fromID, toID)
+	if e == nil {
+		return fmt.Errorf("no edge from %s to %s", fromID, toID)
+	}
+	e.Outcome = outcome
+	return nil
+}
+
+// SetNodeAttr sets a metadata attribute on a node.
+func SetNodeAttr(t *model.Tree, id, key, value string) error {
+	n := t.GetNode(id)
+	if n == nil {
+		return fmt.Errorf("node %q not found", id)
+	}
+	if n.Attrs == nil {
+		n.Attrs = make(map[string]string)
+	}
+	n.Attrs[key] = value
+	return nil
+}
+
+// RemoveNodeAttr deletes a metadata attribute from a node.
+func RemoveNodeAttr(t *model.Tree, id, key string) error {
+	n := t.GetNode(id)
+	if n == nil {
+		return fmt.Errorf("node %q not found", id)
+	}
+	if _, ok := n.Attrs[key]; !ok {
+		return fmt.Errorf("node %q has no attribute %q", id, key)
+	}
+	delete(n.Attrs, key)
+	if len(n.Attrs) == 0 {
+		n.Attrs = nil
+	}
+	return nil
+}
+
+// SetEdgeAttr sets a metadata attribute on the edge between two nodes.
+func SetEdgeAttr(t *model.Tree, fromID, toID, key, value string) error {
+	e := t.GetEdge(fromID, toID)
+	if e == nil {
+		return fmt.Errorf("no edge from %s to %s", fromID, toID)
+	}
+	if e.Attrs == nil {
+		e.Attrs = make(map[string]string)
+	}
+	e.Attrs[key] = value
+	return nil
+}
+
+// RemoveEdgeAttr deletes a metadata attribute from the edge between two nodes.
+func RemoveEdgeAttr(t *model.Tree, fromID, toID, key string) error {
+	e := t.GetEdge(fromID, toID)
+	if e == nil {
+		return fmt.Errorf("no edge from %s to %s", fromID, toID)
+	}
+	if _, ok := e.Attrs[key]; !ok {
+		return fmt.Errorf("edge %s -> %s has no attribute %q", fromID, toID, key)
+	}
+	delete(e.Attrs, key)
+	if len(e.Attrs) == 0 {
+		e.Attrs = nil
+	}
+	return nil
+}
+
 // SetRoot sets the root node of the tree.
 func SetRoot(t *model.Tree, id string) error {
 	if _, ok := t.Nodes[id]; !ok {
@@ -107,6 +269,32 @@ func SetRoot(t *model.Tree, id string) error {
 	return nil
 }
 
+// SetGraphMode switches the tree between tree mode and graph mode. Leaving
+// graph mode fails if any node has several parents or lies on a cycle.
+func SetGraphMode(t *model.Tree, graph bool) error {
+	if !graph && t.Graph {
+		if err := CheckTreeMode(t); err != nil {
+			return err
+		}
+	}
+	t.Graph = graph
+	return nil
+}
+
+// CheckTreeMode reports the first node that breaks the tree-mode rules:
+// at most one parent and no cycles.
+func CheckTreeMode(t *model.Tree) error {
+	for _, id := range t.NodeIDs() {
+		if parents := t.Parents(id); len(parents) > 1 {
+			return fmt.Errorf("node %q has %d parents", id, len(parents))
+		}
+		if t.Ancestors(id)[id] {
+			return fmt.Errorf("node %q is part of a cycle", id)
+		}
+	}
+	return nil
+}
+
 // ListNodes returns a formatted list of all nodes in the tree.
 func ListNodes(t *model.Tree) []string {
 	ids := t.NodeIDs()
@@ -1,6 +1,7 @@
 package tree
 
 import (
+	"math"
 	"testing"
 
 	"github.com/jllovet/decision-tree-cli/internal/model"
@@ -145,6 +146,79 @@ func TestDisconnectNodesNotFound(t *testing.T) {
 	}
 }
 
+func TestMoveNode(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "a")
+	AddNode(tr, model.Decision, "b")
+	AddNode(tr, model.Action, "c")
+	ConnectNodes(tr, "n1", "n2", "")
+	ConnectNodes(tr, "n2", "n3", "yes")
+	SetEdgeAttr(tr, "n2", "n3", "owner", "ops")
+
+	if err := MoveNode(tr, "n3", "n1", nil); err != nil {
+		t.Fatalf("MoveNode: %v", err)
+	}
+	if tr.HasEdge("n2", "n3") {
+		t.Error("old edge should be removed")
+	}
+	e := tr.GetEdge("n1", "n3")
+	if e == nil || e.Label != "yes" || e.Attrs["owner"] != "ops" {
+		t.Fatalf("moved edge = %+v", e)
+	}
+
+	label := "no"
+	if err := MoveNode(tr, "n3", "n2", &label); err != nil {
+		t.Fatalf("MoveNode: %v", err)
+	}
+	if e := tr.GetEdge("n2", "n3"); e == nil || e.Label != "no" {
+		t.Errorf("relabelled edge = %+v", e)
+	}
+}
+
+func TestMoveNodeWithoutParent(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "a")
+	AddNode(tr, model.Action, "b")
+	if err := MoveNode(tr, "n2", "n1", nil); err != nil {
+		t.Fatalf("MoveNode: %v", err)
+	}
+	if !tr.HasEdge("n1", "n2") {
+		t.Error("edge should be created")
+	}
+}
+
+func TestMoveNodeErrors(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "a")
+	AddNode(tr, model.Decision, "b")
+	AddNode(tr, model.Action, "c")
+	ConnectNodes(tr, "n1", "n2", "")
+	ConnectNodes(tr, "n2", "n3", "")
+
+	tests := []struct {
+		id, parent, want string
+	}{
+		{"n9", "n1", `node "n9" not found`},
+		{"n2", "n9", `new parent "n9" not found`},
+		{"n2", "n2", "cannot move n2 under itself"},
+		{"n2", "n3", "cannot move n2 under n3: n3 is in its subtree"},
+		{"n1", "n3", "cannot move n1 under n3: n3 is in its subtree"},
+	}
+	for _, tt := range tests {
+		err := MoveNode(tr, tt.id, tt.parent, nil)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("MoveNode(%s, %s) = %v, want %q", tt.id, tt.parent, err, tt.want)
+		}
+	}
+
+	tr.Graph = true
+	AddNode(tr, model.Action, "d")
+	ConnectNodes(tr, "n1", "n3", "")
+	if err := MoveNode(tr, "n3", "n4", nil); err == nil {
+		t.Error("expected error for node with two parents")
+	}
+}
+
 func TestEditNodeLabel(t *testing.T) {
 	tr := model.NewTree("test")
 	AddNode(tr, model.Decision, "old")
@@ -209,3 +283,167 @@ func TestListNodes(t *testing.T) {
 		t.Errorf("line[1] = %q", lines[1])
 	}
 }
+
+func TestConnectNodesGraphMode(t *testing.T) {
+	tr := model.NewTree("test")
+	tr.Graph = true
+	AddNode(tr, model.Decision, "q")
+	AddNode(tr, model.Action, "a")
+	AddNode(tr, model.Action, "b")
+	ConnectNodes(tr, "n1", "n2", "yes")
+	ConnectNodes(tr, "n1", "n3", "no")
+
+	if err := ConnectNodes(tr, "n3", "n2", ""); err != nil {
+		t.Errorf("second parent should be allowed in graph mode: %v", err)
+	}
+	if err := ConnectNodes(tr, "n3", "n1", "retry"); err != nil {
+		t.Errorf("loop-back edge should be allowed in graph mode: %v", err)
+	}
+	if err := ConnectNodes(tr, "n3", "n1", ""); err == nil {
+		t.Error("duplicate edge should still be rejected")
+	}
+}
+
+func TestSetGraphMode(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "q")
+	AddNode(tr, model.Action, "a")
+	ConnectNodes(tr, "n1", "n2", "")
+
+	if err := SetGraphMode(tr, true); err != nil {
+		t.Fatalf("SetGraphMode(true): %v", err)
+	}
+	if err := SetGraphMode(tr, false); err != nil {
+		t.Fatalf("SetGraphMode(false) on a plain tree: %v", err)
+	}
+
+	SetGraphMode(tr, true)
+	ConnectNodes(tr, "n2", "n1", "retry")
+	if err := SetGraphMode(tr, false); err == nil {
+		t.Error("expected error leaving graph mode with a cycle")
+	}
+	if !tr.Graph {
+		t.Error("tree should stay in graph mode after failed switch")
+	}
+
+	DisconnectNodes(tr, "n2", "n1")
+	AddNode(tr, model.Action, "b")
+	ConnectNodes(tr, "n3", "n2", "")
+	if err := SetGraphMode(tr, false); err == nil {
+		t.Error("expected error leaving graph mode with multiple parents")
+	}
+}
+
+func TestNodeAttrs(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Action, "step")
+
+	if err := SetNodeAttr(tr, "n1", "owner", "alice"); err != nil {
+		t.Fatalf("SetNodeAttr: %v", err)
+	}
+	if got := tr.GetNode("n1").Attrs["owner"]; got != "alice" {
+		t.Errorf("owner = %q, want alice", got)
+	}
+	if err := RemoveNodeAttr(tr, "n1", "owner"); err != nil {
+		t.Fatalf("RemoveNodeAttr: %v", err)
+	}
+	if tr.GetNode("n1").Attrs != nil {
+		t.Error("empty attribute map should be cleared")
+	}
+	if err := RemoveNodeAttr(tr, "n1", "owner"); err == nil {
+		t.Error("expected error removing a missing attribute")
+	}
+	if err := SetNodeAttr(tr, "missing", "k", "v"); err == nil {
+		t.Error("expected error for missing node")
+	}
+}
+
+func TestEdgeAttrs(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "q")
+	AddNode(tr, model.Action, "a")
+	ConnectNodes(tr, "n1", "n2", "yes")
+
+	if err := SetEdgeAttr(tr, "n1", "n2", "sla", "4h"); err != nil {
+		t.Fatalf("SetEdgeAttr: %v", err)
+	}
+	if got := tr.GetEdge("n1", "n2").Attrs["sla"]; got != "4h" {
+		t.Errorf("sla = %q, want 4h", got)
+	}
+	if err := RemoveEdgeAttr(tr, "n1", "n2", "sla"); err != nil {
+		t.Fatalf("RemoveEdgeAttr: %v", err)
+	}
+	if err := SetEdgeAttr(tr, "n2", "n1", "k", "v"); err == nil {
+		t.Error("expected error for missing edge")
+	}
+}
+
+func TestSetPayoffAndProbability(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "q")
+	AddNode(tr, model.StartEnd, "win")
+	ConnectNodes(tr, "n1", "n2", "good")
+
+	v := 100.0
+	if err := SetPayoff(tr, "n2", &v); err != nil {
+		t.Fatalf("SetPayoff: %v", err)
+	}
+	if p := tr.GetNode("n2").Payoff; p == nil || *p != 100 {
+		t.Errorf("payoff = %v, want 100", p)
+	}
+
+	p := 0.25
+	if err := SetProbability(tr, "n1", "n2", &p); err != nil {
+		t.Fatalf("SetProbability: %v", err)
+	}
+	bad := 1.5
+	if err := SetProbability(tr, "n1", "n2", &bad); err == nil {
+		t.Error("expected error for probability above 1")
+	}
+	if nan := math.NaN(); SetProbability(tr, "n1", "n2", &nan) == nil {
+		t.Error("expected error for NaN probability")
+	}
+	if err := SetProbability(tr, "n2", "n1", &p); err == nil {
+		t.Error("expected error for missing edge")
+	}
+	if err := EditEdgeLabel(tr, "n1", "n2", "bad"); err != nil {
+		t.Fatalf("EditEdgeLabel: %v", err)
+	}
+	if got := tr.GetEdge("n1", "n2").Label; got != "bad" {
+		t.Errorf("label = %q, want bad", got)
+	}
+}
+
+func TestSetConditionAndOutcome(t *testing.T) {
+	tr := model.NewTree("test")
+	AddNode(tr, model.Decision, "Adult?")
+	AddNode(tr, model.Action, "Allow")
+	ConnectNodes(tr, "n1", "n2", "yes")
+
+	if err := SetCondition(tr, "n1", "age >= 18"); err != nil {
+		t.Fatalf("SetCondition: %v", err)
+	}
+	if got := tr.GetNode("n1").Condition; got != "age >= 18" {
+		t.Errorf("condition = %q", got)
+	}
+	if err := SetCondition(tr, "n1", "age >="); err == nil {
+		t.Error("expected error for unparseable condition")
+	}
+	if got := tr.GetNode("n1").Condition; got != "age >= 18" {
+		t.Errorf("bad condition should not be stored, got %q", got)
+	}
+	if err := SetCondition(tr, "n9", "x"); err == nil {
+		t.Error("expected error for missing node")
+	}
+
+	if err := SetOutcome(tr, "n1", "n2", "true"); err != nil {
+		t.Fatalf("SetOutcome: %v", err)
+	}
+	if e := tr.GetEdge("n1", "n2"); e.Outcome != "true" || e.OutcomeText() != "true" {
+		t.Errorf("outcome = %q", e.Outcome)
+	}
+	SetOutcome(tr, "n1", "n2", "")
+	if got := tr.GetEdge("n1", "n2").OutcomeText(); got != "yes" {
+		t.Errorf("OutcomeText should fall back to label, got %q", got)
+	}
+}